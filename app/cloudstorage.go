@@ -0,0 +1,143 @@
+package index
+
+import (
+	"appengine"
+	"appengine/file"
+	"appengine/urlfetch"
+	"bytes"
+	"fmt"
+	"github.com/hajimehoshi/kakeibo/blob"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+const (
+	storageScope = "https://www.googleapis.com/auth/devstorage.read_write"
+	storageAPI   = "https://www.googleapis.com/storage/v1/b/"
+	uploadAPI    = "https://www.googleapis.com/upload/storage/v1/b/"
+)
+
+// cloudStorageStore is a blob.Store on Cloud Storage. The contents are stored
+// as objects in the application's default bucket.
+type cloudStorageStore struct {
+	context appengine.Context
+}
+
+func objectName(userID string, hash string) string {
+	return "attachments/" + userID + "/" + hash
+}
+
+// do sends a request to the Cloud Storage JSON API. path is the path after
+// the bucket's name.
+func (s *cloudStorageStore) do(
+	method string,
+	api string,
+	path string,
+	body []byte) (*http.Response, error) {
+	bucket, err := file.DefaultBucketName(s.context)
+	if err != nil {
+		return nil, err
+	}
+	token, _, err := appengine.AccessToken(s.context, storageScope)
+	if err != nil {
+		return nil, err
+	}
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	u := api + url.PathEscape(bucket) + path
+	req, err := http.NewRequest(method, u, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	return urlfetch.Client(s.context).Do(req)
+}
+
+// objectPath returns the path of the object of the hash after the bucket's
+// name.
+func objectPath(userID string, hash string) string {
+	return "/o/" + url.PathEscape(objectName(userID, hash))
+}
+
+func statusError(res *http.Response) error {
+	return fmt.Errorf("index: Cloud Storage status is not OK: %d",
+		res.StatusCode)
+}
+
+func (s *cloudStorageStore) Has(userID string, hash string) (bool, error) {
+	res, err := s.do("GET", storageAPI, objectPath(userID, hash), nil)
+	if err != nil {
+		return false, err
+	}
+	res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, statusError(res)
+	}
+}
+
+func (s *cloudStorageStore) Put(
+	userID string,
+	hash string,
+	content []byte) error {
+	ok, err := s.Has(userID, hash)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return nil
+	}
+	path := "/o?uploadType=media&name=" +
+		url.QueryEscape(objectName(userID, hash))
+	res, err := s.do("POST", uploadAPI, path, content)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return statusError(res)
+	}
+	return nil
+}
+
+func (s *cloudStorageStore) Get(userID string, hash string) ([]byte, error) {
+	path := objectPath(userID, hash) + "?alt=media"
+	res, err := s.do("GET", storageAPI, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+		return ioutil.ReadAll(res.Body)
+	case http.StatusNotFound:
+		return nil, blob.ErrNotFound
+	default:
+		return nil, statusError(res)
+	}
+}
+
+func (s *cloudStorageStore) Delete(userID string, hash string) error {
+	res, err := s.do("DELETE", storageAPI, objectPath(userID, hash), nil)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return statusError(res)
+	}
+}
@@ -4,45 +4,47 @@ import (
 	"appengine"
 	"appengine/datastore"
 	"errors"
-	"fmt"
 	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/storage"
 	"github.com/hajimehoshi/kakeibo/uuid"
 	"reflect"
 	"time"
 )
 
-const (
-	kindItems = "Items"
-)
-
-var (
-	rootKeyStringID = reflect.TypeOf((*models.ItemData)(nil)).Elem().Name()
-)
-
+// ItemDatastore is a storage.Storage on App Engine's datastore.
 type ItemDatastore struct {
 	context appengine.Context
 	userID  string
+	t       *models.SyncedType
 	rootKey *datastore.Key
 }
 
-func NewItemDatastore(context appengine.Context, userID string) *ItemDatastore {
+func NewItemDatastore(
+	context appengine.Context,
+	userID string,
+	typeName string) (*ItemDatastore, error) {
+	t, err := models.LookupSyncedType(typeName)
+	if err != nil {
+		return nil, err
+	}
 	rootKey := datastore.NewKey(
 		context,
-		kindItems,
-		rootKeyStringID,
+		t.Kind,
+		t.Name,
 		0,
 		nil)
 	return &ItemDatastore{
 		context: context,
 		userID:  userID,
+		t:       t,
 		rootKey: rootKey,
-	}
+	}, nil
 }
 
 func (d *ItemDatastore) datastoreKey(id uuid.UUID) *datastore.Key {
 	return datastore.NewKey(
 		d.context,
-		kindItems,
+		d.t.Kind,
 		id.String(),
 		0,
 		d.rootKey)
@@ -52,43 +54,53 @@ func (d *ItemDatastore) datastoreKey(id uuid.UUID) *datastore.Key {
 
 func (d *ItemDatastore) Put(
 	lastUpdated time.Time,
-	reqItems []*models.ItemData) (now time.Time, err error) {
+	reqItems []interface{}) (
+	now time.Time,
+	rejected []interface{},
+	err error) {
 	now = time.Now().UTC()
 	if now.Before(lastUpdated) {
-		err = errors.New("ItemDatastore.Put: last-updated is too new")
+		err = storage.ErrTooNew
 		return
 	}
 	f := func(c appengine.Context) error {
-		itemsToPut := []*models.ItemData{}
+		// The transaction might be retried.
+		rejected = []interface{}{}
+		itemsToPut := []interface{}{}
 		for _, item := range reqItems {
-			id := item.Meta.ID
-			var existingData models.ItemData
+			if reflect.TypeOf(item) != reflect.PtrTo(d.t.Type) {
+				return errors.New("ItemDatastore.Put: invalid type")
+			}
+			if !d.t.Validate(item) {
+				return errors.New("ItemDatastore.Put: invalid item")
+			}
+			meta := models.MetaOf(item)
+			id := meta.ID
+			existingData := d.t.New()
 			key := d.datastoreKey(id)
-			err := datastore.Get(c, key, &existingData)
+			var existingMeta *models.Meta
+			err := datastore.Get(c, key, existingData)
 			switch err {
 			case nil:
-				if d.userID != existingData.Meta.UserID {
-					e := fmt.Sprintf(
-						"ItemDatastorePut: "+
-							"invalid UUID: %s",
-						id.String())
-					return errors.New(e)
-				}
-				if lastUpdated.Before(
-					existingData.Meta.LastUpdated) {
-					continue
-				}
+				existingMeta = models.MetaOf(existingData)
 			case datastore.ErrNoSuchEntity:
 			default:
 				return err
 			}
-			item.Meta.LastUpdated = now
-			item.Meta.UserID = d.userID
+			ok, err := storage.Accept(d.userID, meta, existingMeta)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				rejected = append(rejected, existingData)
+				continue
+			}
+			storage.Stamp(d.userID, now, meta, existingMeta)
 			itemsToPut = append(itemsToPut, item)
 		}
 		keys := make([]*datastore.Key, len(itemsToPut))
 		for i, item := range itemsToPut {
-			key := d.datastoreKey(item.Meta.ID)
+			key := d.datastoreKey(models.MetaOf(item).ID)
 			keys[i] = key
 		}
 		_, err := datastore.PutMulti(c, keys, itemsToPut)
@@ -99,12 +111,63 @@ func (d *ItemDatastore) Put(
 }
 
 func (d *ItemDatastore) Get(
-	lastUpdated time.Time) (items []*models.ItemData, err error) {
-	q := datastore.NewQuery(kindItems).
+	lastUpdated time.Time,
+	cursor string,
+	limit int) (items []interface{}, next string, err error) {
+	q := datastore.NewQuery(d.t.Kind).
 		Ancestor(d.rootKey).
 		Filter("Meta.LastUpdated >", lastUpdated).
-		Filter("Meta.UserID =", d.userID)
-	items = []*models.ItemData{}
-	_, err = q.GetAll(d.context, &items)
-	return
+		Filter("Meta.UserID =", d.userID).
+		Order("Meta.LastUpdated")
+	if cursor != "" {
+		var c datastore.Cursor
+		if c, err = datastore.DecodeCursor(cursor); err != nil {
+			return
+		}
+		q = q.Start(c)
+	}
+	if 0 < limit {
+		// Get one more value to know whether the next page exists.
+		q = q.Limit(limit + 1)
+	}
+	items = []interface{}{}
+	it := q.Run(d.context)
+	for {
+		if 0 < limit && len(items) == limit {
+			var c datastore.Cursor
+			if c, err = it.Cursor(); err != nil {
+				return
+			}
+			if _, err = it.Next(d.t.New()); err == datastore.Done {
+				err = nil
+				return
+			}
+			if err != nil {
+				return
+			}
+			next = c.String()
+			return
+		}
+		v := d.t.New()
+		_, err = it.Next(v)
+		if err == datastore.Done {
+			err = nil
+			return
+		}
+		if err != nil {
+			return
+		}
+		items = append(items, v)
+	}
+}
+
+// datastoreBackend is a storage.Backend on App Engine's datastore.
+type datastoreBackend struct {
+	context appengine.Context
+}
+
+func (b *datastoreBackend) Open(
+	userID string,
+	typeName string) (storage.Storage, error) {
+	return NewItemDatastore(b.context, userID, typeName)
 }
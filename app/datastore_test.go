@@ -0,0 +1,26 @@
+package index
+
+import (
+	"appengine/aetest"
+	"github.com/hajimehoshi/kakeibo/storage"
+	"github.com/hajimehoshi/kakeibo/storage/storagetest"
+	"testing"
+)
+
+func TestItemDatastore(t *testing.T) {
+	contexts := []aetest.Context{}
+	defer func() {
+		for _, c := range contexts {
+			c.Close()
+		}
+	}()
+	storagetest.Run(t, func(t *testing.T) storage.Backend {
+		// Each context has its own empty datastore.
+		c, err := aetest.NewContext(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contexts = append(contexts, c)
+		return &datastoreBackend{c}
+	})
+}
@@ -3,12 +3,10 @@ package index
 import (
 	"appengine"
 	"appengine/user"
-	"encoding/json"
-	"errors"
-	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/server"
 	"html/template"
-	"io/ioutil"
 	"net/http"
+	"strings"
 )
 
 // TODO: Use memcache?
@@ -17,6 +15,19 @@ var tmpl *template.Template
 
 func init() {
 	http.HandleFunc("/sync", filterUsers(handleSync))
+	http.HandleFunc("/ledgers", filterUsers(handleLedgers))
+	http.HandleFunc("/attachments/", filterUsers(handleAttachment))
+	// The REST API is authenticated by API tokens instead of logging in.
+	http.HandleFunc(server.APIItemsPath, handleAPIItems)
+	http.HandleFunc(server.APIItemsPath+"/", handleAPIItems)
+	http.HandleFunc(
+		"/api/reports/monthly",
+		filterUsers(handleMonthlyReport))
+	http.HandleFunc(
+		"/api/reports/categories",
+		filterUsers(handleCategoryReport))
+	http.HandleFunc("/admin/backup", filterAdmins(handleBackup))
+	http.HandleFunc("/admin/restore", filterAdmins(handleRestore))
 	http.HandleFunc("/", filterUsers(handleIndex))
 
 	var err error
@@ -38,80 +49,102 @@ func handleIndex(w http.ResponseWriter, r *http.Request) {
 	url, _ := user.LogoutURL(c, "/")
 	tmpl.Execute(w, map[string]interface{}{
 		"UserEmail":         u.Email,
+		"UserID":            u.ID,
 		"IsDevelopmentMode": appengine.IsDevAppServer(),
 		"LogoutURL":         url,
 	})
 }
 
-func parseRequest(r *http.Request) (
-	req *models.SyncRequest,
-	reqItems []*models.ItemData,
-	err error) {
-	body, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		return
-	}
+func handleSync(w http.ResponseWriter, r *http.Request) {
+	c := appengine.NewContext(r)
+	u := user.Current(c)
+	b := &datastoreBackend{c}
+	server.HandleSync(w, r, b, &cloudStorageStore{c}, u.ID, u.Email)
+}
 
-	req = &models.SyncRequest{}
-	if err = json.Unmarshal(body, &req); err != nil {
-		return
-	}
-	reqItems = []*models.ItemData{}
-	for _, v := range req.Values {
-		v, ok := v.(*models.ItemData)
-		if !ok {
-			err = errors.New("invalid data")
-			return
-		}
-		if !v.IsValid() {
-			err = errors.New("invalid item")
-			return
-		}
-		reqItems = append(reqItems, v)
-	}
-	return
+func handleLedgers(w http.ResponseWriter, r *http.Request) {
+	c := appengine.NewContext(r)
+	u := user.Current(c)
+	server.HandleLedgers(w, r, &datastoreBackend{c}, u.Email)
 }
 
-func handleSync(w http.ResponseWriter, r *http.Request) {
-	req, reqItems, err := parseRequest(r)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+func handleAttachment(w http.ResponseWriter, r *http.Request) {
+	c := appengine.NewContext(r)
+	u := user.Current(c)
+	hash := strings.TrimPrefix(r.URL.Path, "/attachments/")
+	b := &datastoreBackend{c}
+	blobs := &cloudStorageStore{c}
+	switch r.Method {
+	case "PUT":
+		server.HandleUpload(w, r, b, blobs, u.ID, u.Email, hash)
+	case "GET":
+		server.HandleDownload(w, r, b, blobs, u.ID, u.Email, hash)
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 	}
+}
 
+func handleAPIItems(w http.ResponseWriter, r *http.Request) {
 	c := appengine.NewContext(r)
-	u := user.Current(c)
+	server.HandleAPIItems(w, r, &datastoreBackend{c}, &cloudStorageStore{c})
+}
 
-	d := NewItemDatastore(c, u.ID)
-	now, err := d.Put(req.LastUpdated, reqItems)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+func handleMonthlyReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	resItems, err := d.Get(req.LastUpdated)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	c := appengine.NewContext(r)
+	u := user.Current(c)
+	server.HandleMonthlyReport(w, r, &datastoreBackend{c}, u.ID, u.Email)
+}
+
+func handleCategoryReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	c := appengine.NewContext(r)
+	u := user.Current(c)
+	server.HandleCategoryReport(w, r, &datastoreBackend{c}, u.ID, u.Email)
+}
 
-	values := make([]interface{}, len(resItems))
-	for i, v := range resItems {
-		values[i] = v
+func filterAdmins(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := appengine.NewContext(r)
+		if user.Current(c) == nil || !user.IsAdmin(c) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		f(w, r)
 	}
+}
 
-	res := &models.SyncResponse{
-		Type:        req.Type,
-		LastUpdated: now,
-		Values:      values,
+// targetUserID returns the ID of the user specified by the 'user' parameter,
+// or the current user's ID.
+func targetUserID(r *http.Request, c appengine.Context) string {
+	if id := r.URL.Query().Get("user"); id != "" {
+		return id
 	}
-	resBytes, err := json.Marshal(res)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	return user.Current(c).ID
+}
+
+func handleBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	_, err = w.Write(resBytes)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	c := appengine.NewContext(r)
+	id := targetUserID(r, c)
+	server.HandleBackup(w, r, &datastoreBackend{c}, id)
+}
+
+func handleRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	c := appengine.NewContext(r)
+	id := targetUserID(r, c)
+	server.HandleRestore(w, r, &datastoreBackend{c}, id)
 }
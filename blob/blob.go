@@ -0,0 +1,80 @@
+// Package blob provides server-side stores of attachments' contents.
+package blob
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrNotFound = errors.New("blob: not found")
+
+// Store stores contents of each user by their hashes. As a hash identifies
+// its content, a content is stored only once however many times it is put.
+type Store interface {
+	// Has reports whether the content of the hash is stored.
+	Has(userID string, hash string) (bool, error)
+
+	// Put stores the content of the hash. Put does nothing if the content
+	// is already stored. The caller must verify the hash.
+	Put(userID string, hash string, content []byte) error
+
+	// Get returns the content of the hash, or ErrNotFound if the content
+	// is not stored.
+	Get(userID string, hash string) ([]byte, error)
+
+	// Delete deletes the content of the hash. Delete does nothing if the
+	// content is not stored.
+	Delete(userID string, hash string) error
+}
+
+type memoryKey struct {
+	userID string
+	hash   string
+}
+
+// Memory is a Store which keeps contents in memory.
+type Memory struct {
+	m        sync.Mutex
+	contents map[memoryKey][]byte
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		contents: map[memoryKey][]byte{},
+	}
+}
+
+func (m *Memory) Has(userID string, hash string) (bool, error) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	_, ok := m.contents[memoryKey{userID, hash}]
+	return ok, nil
+}
+
+func (m *Memory) Put(userID string, hash string, content []byte) error {
+	m.m.Lock()
+	defer m.m.Unlock()
+	key := memoryKey{userID, hash}
+	if _, ok := m.contents[key]; ok {
+		return nil
+	}
+	m.contents[key] = append([]byte(nil), content...)
+	return nil
+}
+
+func (m *Memory) Get(userID string, hash string) ([]byte, error) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	c, ok := m.contents[memoryKey{userID, hash}]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), c...), nil
+}
+
+func (m *Memory) Delete(userID string, hash string) error {
+	m.m.Lock()
+	defer m.m.Unlock()
+	delete(m.contents, memoryKey{userID, hash})
+	return nil
+}
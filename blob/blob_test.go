@@ -0,0 +1,84 @@
+package blob_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	. "github.com/hajimehoshi/kakeibo/blob"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func hashOf(content []byte) string {
+	h := sha256.Sum256(content)
+	return hex.EncodeToString(h[:])
+}
+
+func testStore(t *testing.T, s Store) {
+	content := []byte("receipt")
+	hash := hashOf(content)
+	const (
+		user1 = "foo@example.com"
+		user2 = "bar@example.com"
+	)
+
+	if _, err := s.Get(user1, hash); err != ErrNotFound {
+		t.Errorf("expected %+v got %+v", ErrNotFound, err)
+	}
+	// Putting the same content twice stores it once.
+	for i := 0; i < 2; i++ {
+		if err := s.Put(user1, hash, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ok, err := s.Has(user1, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Errorf("expected true got false")
+	}
+	got, err := s.Get(user1, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, got) {
+		t.Errorf("expected %q got %q", content, got)
+	}
+
+	// Contents are not shared between users.
+	ok, err = s.Has(user2, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Errorf("expected false got true")
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := s.Delete(user1, hash); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Get(user1, hash); err != ErrNotFound {
+		t.Errorf("expected %+v got %+v", ErrNotFound, err)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	testStore(t, NewMemory())
+}
+
+func TestDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kakeibo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	testStore(t, OpenDir(dir))
+
+	if err := OpenDir(dir).Put("foo", "../../etc", nil); err == nil {
+		t.Errorf("expected an error for an invalid hash")
+	}
+}
@@ -0,0 +1,111 @@
+package blob
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/hajimehoshi/kakeibo/models"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Dir is a Store which writes each content to a file in a directory, for
+// self-hosting.
+type Dir struct {
+	root string
+}
+
+// OpenDir opens the directory at root. The directory is created at the first
+// put if it doesn't exist.
+func OpenDir(root string) *Dir {
+	return &Dir{root}
+}
+
+// path returns the path of the content's file. User IDs are hashed as they
+// can be email addresses, which are not always valid file names.
+func (d *Dir) path(userID string, hash string) (string, error) {
+	// The hash is checked so that it never points outside the directory.
+	if !models.IsValidHash(hash) {
+		return "", errors.New("blob: invalid hash")
+	}
+	u := sha256.Sum256([]byte(userID))
+	return filepath.Join(d.root, hex.EncodeToString(u[:]), hash), nil
+}
+
+func (d *Dir) Has(userID string, hash string) (bool, error) {
+	path, err := d.path(userID, hash)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (d *Dir) Put(userID string, hash string, content []byte) error {
+	ok, err := d.Has(userID, hash)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return nil
+	}
+	path, _ := d.path(userID, hash)
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename it so that a content is never
+	// read partially.
+	tmp, err := ioutil.TempFile(dir, ".kakeibo")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+func (d *Dir) Get(userID string, hash string) ([]byte, error) {
+	path, err := d.path(userID, hash)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (d *Dir) Delete(userID string, hash string) error {
+	path, err := d.path(userID, hash)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
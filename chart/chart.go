@@ -0,0 +1,265 @@
+// Package chart renders simple charts as SVG images.
+package chart
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strconv"
+)
+
+const (
+	// Width and Height are the size of the charts in pixels.
+	Width  = 480
+	Height = 240
+
+	fontSize = 10
+	// The plot areas of the bar and line charts.
+	plotLeft   = 56
+	plotTop    = 24
+	plotWidth  = Width - plotLeft - 16
+	plotHeight = Height - plotTop - 24
+)
+
+// palette is the colors of the bars, the slices and the lines in order.
+var palette = []string{
+	"#b36633",
+	"#3366b3",
+	"#66b333",
+	"#b33366",
+	"#33b3a6",
+	"#8033b3",
+	"#b3a633",
+	"#999999",
+}
+
+func color(i int) string {
+	return palette[i%len(palette)]
+}
+
+// number formats a coordinate or a value without unnecessary digits.
+func number(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+type writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (w *writer) printf(format string, args ...interface{}) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = fmt.Fprintf(w.w, format, args...)
+}
+
+func (w *writer) begin(title string) {
+	w.printf(
+		"<svg xmlns=\"http://www.w3.org/2000/svg\" "+
+			"width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" "+
+			"font-family=\"sans-serif\" font-size=\"%d\">\n",
+		Width, Height, Width, Height, fontSize)
+	w.printf("<title>%s</title>\n", html.EscapeString(title))
+}
+
+func (w *writer) end() error {
+	w.printf("</svg>\n")
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+func (w *writer) text(x, y float64, anchor string, str string) {
+	w.printf(
+		"<text x=\"%s\" y=\"%s\" text-anchor=\"%s\">%s</text>\n",
+		number(x), number(y), anchor, html.EscapeString(str))
+}
+
+// axes draws the axes of the plot area with the label of the maximum value.
+func (w *writer) axes(max float64) {
+	bottom := float64(plotTop + plotHeight)
+	w.printf(
+		"<path d=\"M%d %d V%s H%d\" "+
+			"fill=\"none\" stroke=\"#999999\"/>\n",
+		plotLeft, plotTop, number(bottom), plotLeft+plotWidth)
+	w.text(plotLeft-4, plotTop+fontSize/2, "end", number(max))
+	w.text(plotLeft-4, bottom, "end", "0")
+}
+
+// maxOf returns the maximum value, or 0 if all the values are negative.
+func maxOf(values []float64) float64 {
+	max := 0.0
+	for _, v := range values {
+		if max < v {
+			max = v
+		}
+	}
+	return max
+}
+
+// scale returns the height in the plot area for the value. Negative values are
+// drawn as zero.
+func scale(v, max float64) float64 {
+	if v <= 0 || max <= 0 {
+		return 0
+	}
+	return v / max * plotHeight
+}
+
+// Bar is a bar of a bar chart.
+type Bar struct {
+	Label string
+	Value float64
+}
+
+// WriteBars writes a bar chart.
+func WriteBars(w io.Writer, title string, bars []Bar) error {
+	sw := &writer{w: bufio.NewWriter(w)}
+	sw.begin(title)
+	values := make([]float64, len(bars))
+	for i, b := range bars {
+		values[i] = b.Value
+	}
+	max := maxOf(values)
+	sw.axes(max)
+	bottom := float64(plotTop + plotHeight)
+	for i, b := range bars {
+		slot := float64(plotWidth) / float64(len(bars))
+		x := plotLeft + slot*float64(i)
+		h := scale(b.Value, max)
+		sw.printf(
+			"<rect x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\" "+
+				"fill=\"%s\"><title>%s: %s</title></rect>\n",
+			number(x+slot*0.1),
+			number(bottom-h),
+			number(slot*0.8),
+			number(h),
+			color(0),
+			html.EscapeString(b.Label),
+			number(b.Value))
+		sw.text(x+slot/2, bottom+fontSize+4, "middle", b.Label)
+	}
+	return sw.end()
+}
+
+// Slice is a slice of a donut chart.
+type Slice struct {
+	Label string
+	Value float64
+}
+
+// WriteDonut writes a donut chart with the legend. Slices whose values are
+// not positive are not drawn.
+func WriteDonut(w io.Writer, title string, slices []Slice) error {
+	const (
+		cx     = Height / 2
+		cy     = Height / 2
+		r      = 70
+		stroke = 40
+	)
+	sw := &writer{w: bufio.NewWriter(w)}
+	sw.begin(title)
+	total := 0.0
+	for _, s := range slices {
+		if 0 < s.Value {
+			total += s.Value
+		}
+	}
+	circumference := 2 * math.Pi * r
+	sw.printf(
+		"<circle cx=\"%d\" cy=\"%d\" r=\"%d\" fill=\"none\" "+
+			"stroke=\"#eeeeee\" stroke-width=\"%d\"/>\n",
+		cx, cy, r, stroke)
+	offset := 0.0
+	n := 0
+	for _, s := range slices {
+		if s.Value <= 0 {
+			continue
+		}
+		length := circumference * s.Value / total
+		// Slices start at the top and go clockwise.
+		sw.printf(
+			"<circle cx=\"%d\" cy=\"%d\" r=\"%d\" fill=\"none\" "+
+				"stroke=\"%s\" stroke-width=\"%d\" "+
+				"stroke-dasharray=\"%s %s\" "+
+				"stroke-dashoffset=\"%s\" "+
+				"transform=\"rotate(-90 %d %d)\">"+
+				"<title>%s: %s</title></circle>\n",
+			cx, cy, r,
+			color(n), stroke,
+			number(length), number(circumference-length),
+			number(-offset),
+			cx, cy,
+			html.EscapeString(s.Label), number(s.Value))
+		x := float64(Height + 16)
+		y := float64(plotTop + n*(fontSize+8))
+		sw.printf(
+			"<rect x=\"%s\" y=\"%s\" width=\"%d\" height=\"%d\" "+
+				"fill=\"%s\"/>\n",
+			number(x), number(y), fontSize, fontSize, color(n))
+		label := fmt.Sprintf("%s (%.0f%%)", s.Label, s.Value/total*100)
+		sw.text(x+fontSize+6, y+fontSize-1, "start", label)
+		offset += length
+		n++
+	}
+	return sw.end()
+}
+
+// Series is a line of a line chart. Values[i] is the value at the i-th point
+// of the x axis.
+type Series struct {
+	Label  string
+	Values []float64
+}
+
+// WriteLines writes a line chart with the legend. points is the number of the
+// points of the x axis, and labels are the labels of the first and the last
+// points.
+func WriteLines(
+	w io.Writer,
+	title string,
+	points int,
+	labels [2]string,
+	series []Series) error {
+	sw := &writer{w: bufio.NewWriter(w)}
+	sw.begin(title)
+	values := []float64{}
+	for _, s := range series {
+		values = append(values, s.Values...)
+	}
+	max := maxOf(values)
+	sw.axes(max)
+	bottom := float64(plotTop + plotHeight)
+	step := float64(plotWidth)
+	if 1 < points {
+		step = float64(plotWidth) / float64(points-1)
+	}
+	sw.text(plotLeft, bottom+fontSize+4, "start", labels[0])
+	sw.text(plotLeft+plotWidth, bottom+fontSize+4, "end", labels[1])
+	for n, s := range series {
+		sw.printf("<polyline fill=\"none\" stroke=\"%s\" "+
+			"stroke-width=\"2\" points=\"", color(n))
+		for i, v := range s.Values {
+			if 0 < i {
+				sw.printf(" ")
+			}
+			x := plotLeft + step*float64(i)
+			y := bottom - scale(v, max)
+			sw.printf("%s,%s", number(x), number(y))
+		}
+		sw.printf("\"><title>%s</title></polyline>\n",
+			html.EscapeString(s.Label))
+		x := float64(plotLeft + 8 + n*96)
+		sw.printf(
+			"<rect x=\"%s\" y=\"%d\" width=\"%d\" height=\"%d\" "+
+				"fill=\"%s\"/>\n",
+			number(x), plotTop-fontSize-6,
+			fontSize, fontSize, color(n))
+		sw.text(x+fontSize+4, plotTop-7, "start", s.Label)
+	}
+	return sw.end()
+}
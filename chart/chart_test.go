@@ -0,0 +1,77 @@
+package chart_test
+
+import (
+	"bytes"
+	"flag"
+	. "github.com/hajimehoshi/kakeibo/chart"
+	"io/ioutil"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func testGolden(
+	t *testing.T,
+	path string,
+	write func(buf *bytes.Buffer) error) {
+	buf := &bytes.Buffer{}
+	if err := write(buf); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	if *update {
+		err := ioutil.WriteFile(path, buf.Bytes(), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected, buf.Bytes()) {
+		t.Errorf("%s: expected %s got %s", path, expected, buf.Bytes())
+	}
+}
+
+func TestWriteBars(t *testing.T) {
+	bars := []Bar{
+		{"Jan", 1200},
+		{"Feb", 800.5},
+		{"Mar", 0},
+		{"Apr", -100},
+		{"<May>", 1500},
+	}
+	testGolden(t, "testdata/bars.svg", func(buf *bytes.Buffer) error {
+		return WriteBars(buf, "Monthly expenses", bars)
+	})
+}
+
+func TestWriteDonut(t *testing.T) {
+	slices := []Slice{
+		{"Food", 500},
+		{"Housing", 300},
+		{"Empty", 0},
+		{"Fun & games", 200},
+	}
+	testGolden(t, "testdata/donut.svg", func(buf *bytes.Buffer) error {
+		return WriteDonut(buf, "Categories", slices)
+	})
+}
+
+func TestWriteDonutEmpty(t *testing.T) {
+	path := "testdata/donut_empty.svg"
+	testGolden(t, path, func(buf *bytes.Buffer) error {
+		return WriteDonut(buf, "Categories", nil)
+	})
+}
+
+func TestWriteLines(t *testing.T) {
+	series := []Series{
+		{"2026-10", []float64{100, 100, 350, 400}},
+		{"2026-09", []float64{0, 200, 200, 300, 300, 500}},
+	}
+	labels := [2]string{"1", "6"}
+	testGolden(t, "testdata/lines.svg", func(buf *bytes.Buffer) error {
+		return WriteLines(buf, "Cumulative expenses", 6, labels, series)
+	})
+}
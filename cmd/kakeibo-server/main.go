@@ -0,0 +1,147 @@
+// kakeibo-server is a standalone Kakeibo server for self-hosting.
+//
+// Usage:
+//
+//	kakeibo-server -users=users.txt -storage=file -file=kakeibo.json
+//
+// The users file lists permitted users' email addresses, each followed by a
+// password hash for the basic authentication. Run 'kakeibo-server -hash' to
+// generate a password hash. The contents of attachments are written to the
+// directory specified by -blobs.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"github.com/hajimehoshi/kakeibo/blob"
+	"github.com/hajimehoshi/kakeibo/server"
+	"github.com/hajimehoshi/kakeibo/storage"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+var (
+	flagAddr = flag.String(
+		"addr",
+		":8080",
+		"address to listen on")
+	flagRoot = flag.String(
+		"root",
+		"app",
+		"directory which contains templates and static files")
+	flagStorage = flag.String(
+		"storage",
+		"file",
+		"storage backend: 'memory' or 'file'")
+	flagFile = flag.String(
+		"file",
+		"kakeibo.json",
+		"data file for the 'file' storage")
+	flagBlobs = flag.String(
+		"blobs",
+		"blobs",
+		"directory of attachments' contents for the 'file' storage")
+	flagAuth = flag.String(
+		"auth",
+		"basic",
+		"authentication: 'basic' or 'header'")
+	flagHeader = flag.String(
+		"header",
+		"X-Forwarded-Email",
+		"request header which has the user's email "+
+			"for the 'header' authentication")
+	flagUsers = flag.String(
+		"users",
+		"users.txt",
+		"users file")
+	flagAdmins = flag.String(
+		"admins",
+		"",
+		"comma-separated email addresses of the administrators who can "+
+			"use /admin/backup and /admin/restore")
+	flagHash = flag.Bool(
+		"hash",
+		false,
+		"read a password from stdin and print its hash")
+	flagCert = flag.String(
+		"cert",
+		"",
+		"TLS certificate file")
+	flagKey = flag.String(
+		"key",
+		"",
+		"TLS key file")
+)
+
+func newBackend() (storage.Backend, error) {
+	switch *flagStorage {
+	case "memory":
+		return storage.NewMemory(), nil
+	case "file":
+		return storage.OpenFile(*flagFile)
+	}
+	return nil, fmt.Errorf("unknown storage: %s", *flagStorage)
+}
+
+func newBlobStore() blob.Store {
+	if *flagStorage == "memory" {
+		return blob.NewMemory()
+	}
+	return blob.OpenDir(*flagBlobs)
+}
+
+func printHash() error {
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	hash, err := server.HashPassword(strings.TrimRight(line, "\r\n"))
+	if err != nil {
+		return err
+	}
+	fmt.Println(hash)
+	return nil
+}
+
+func main() {
+	flag.Parse()
+
+	if *flagHash {
+		if err := printHash(); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	users, err := server.LoadUsers(*flagUsers)
+	if err != nil {
+		log.Fatal(err)
+	}
+	auth, err := server.NewAuth(*flagAuth, users, *flagHeader)
+	if err != nil {
+		log.Fatal(err)
+	}
+	backend, err := newBackend()
+	if err != nil {
+		log.Fatal(err)
+	}
+	s, err := server.New(backend, newBlobStore(), auth, *flagRoot)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *flagAdmins != "" {
+		s.SetAdmins(strings.Split(*flagAdmins, ","))
+	}
+
+	log.Printf("Listening on %s", *flagAddr)
+	if *flagCert != "" || *flagKey != "" {
+		err = http.ListenAndServeTLS(*flagAddr, *flagCert, *flagKey, s)
+	} else {
+		err = http.ListenAndServe(*flagAddr, s)
+	}
+	log.Fatal(err)
+}
@@ -0,0 +1,92 @@
+// Package currency handles ISO 4217 currency codes.
+package currency
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Code is an ISO 4217 currency code like 'JPY'.
+type Code string
+
+// Default is the currency of items which don't have their currencies. Items
+// were always recorded in yen before currencies existed.
+const Default Code = "JPY"
+
+// minorUnits is the number of the digits after the decimal point for each
+// currency. Currencies not listed here have 2 digits.
+var minorUnits = map[Code]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0,
+	"XAF": 0, "XOF": 0, "XPF": 0,
+
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+
+	"CLF": 4, "UYW": 4,
+}
+
+// codes is sorted alphabetically.
+var codes = []Code{
+	"AED", "AFN", "ALL", "AMD", "ANG", "AOA", "ARS", "AUD", "AWG", "AZN",
+	"BAM", "BBD", "BDT", "BGN", "BHD", "BIF", "BMD", "BND", "BOB", "BRL",
+	"BSD", "BTN", "BWP", "BYN", "BZD", "CAD", "CDF", "CHF", "CLF", "CLP",
+	"CNY", "COP", "CRC", "CUP", "CVE", "CZK", "DJF", "DKK", "DOP", "DZD",
+	"EGP", "ERN", "ETB", "EUR", "FJD", "FKP", "GBP", "GEL", "GHS", "GIP",
+	"GMD", "GNF", "GTQ", "GYD", "HKD", "HNL", "HTG", "HUF", "IDR", "ILS",
+	"INR", "IQD", "IRR", "ISK", "JMD", "JOD", "JPY", "KES", "KGS", "KHR",
+	"KMF", "KPW", "KRW", "KWD", "KYD", "KZT", "LAK", "LBP", "LKR", "LRD",
+	"LSL", "LYD", "MAD", "MDL", "MGA", "MKD", "MMK", "MNT", "MOP", "MRU",
+	"MUR", "MVR", "MWK", "MXN", "MYR", "MZN", "NAD", "NGN", "NIO", "NOK",
+	"NPR", "NZD", "OMR", "PAB", "PEN", "PGK", "PHP", "PKR", "PLN", "PYG",
+	"QAR", "RON", "RSD", "RUB", "RWF", "SAR", "SBD", "SCR", "SDG", "SEK",
+	"SGD", "SHP", "SLE", "SOS", "SRD", "SSP", "STN", "SVC", "SYP", "SZL",
+	"THB", "TJS", "TMT", "TND", "TOP", "TRY", "TTD", "TWD", "TZS", "UAH",
+	"UGX", "USD", "UYI", "UYU", "UYW", "UZS", "VES", "VND", "VUV", "WST",
+	"XAF", "XCD", "XOF", "XPF", "YER", "ZAR", "ZMW", "ZWL",
+}
+
+var validCodes = map[Code]struct{}{}
+
+func init() {
+	for _, c := range codes {
+		validCodes[c] = struct{}{}
+	}
+}
+
+// Codes returns the supported currency codes in alphabetical order.
+func Codes() []Code {
+	result := make([]Code, len(codes))
+	copy(result, codes)
+	return result
+}
+
+func (c Code) IsValid() bool {
+	_, ok := validCodes[c]
+	return ok
+}
+
+func (c Code) String() string {
+	return string(c)
+}
+
+// MinorUnits returns the number of the digits after the decimal point, like 0
+// for JPY and 2 for USD.
+func (c Code) MinorUnits() int {
+	if n, ok := minorUnits[c]; ok {
+		return n
+	}
+	return 2
+}
+
+// ParseRate parses an exchange rate like '150.25'. The rate must be positive.
+func ParseRate(str string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(str))
+	if !ok {
+		return nil, fmt.Errorf("currency: invalid rate: %q", str)
+	}
+	if r.Sign() <= 0 {
+		return nil, fmt.Errorf("currency: rate must be positive: %q", str)
+	}
+	return r, nil
+}
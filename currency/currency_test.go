@@ -0,0 +1,14 @@
+package currency_test
+
+import (
+	. "github.com/hajimehoshi/kakeibo/currency"
+	"testing"
+)
+
+func TestParseRate(t *testing.T) {
+	for _, str := range []string{"", "0", "-1", "abc"} {
+		if _, err := ParseRate(str); err == nil {
+			t.Errorf("expected an error for %q", str)
+		}
+	}
+}
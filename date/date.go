@@ -34,11 +34,15 @@ func ParseISO8601(value string) (Date, error) {
 
 func (d Date) time() time.Time {
 	u := (int64(d) - unixEpochDays) * secondsPerDay
-	return time.Unix(int64(u), 0)
+	return time.Unix(int64(u), 0).UTC()
 }
 
+// AddDate returns the date adding the given number of years, months and days.
+// Like time.Time.AddDate, AddDate normalizes its result: adding a month to
+// 2015-01-31 yields 2015-03-03.
 func (d Date) AddDate(years, months, days int) Date {
-	return Date(d.time().AddDate(years, months, days).Unix())
+	t := d.time().AddDate(years, months, days)
+	return New(t.Year(), t.Month(), t.Day())
 }
 
 func (d Date) String() string {
@@ -69,3 +73,7 @@ func (d Date) Month() time.Month {
 func (d Date) Day() int {
 	return d.time().Day()
 }
+
+func (d Date) Weekday() time.Weekday {
+	return d.time().Weekday()
+}
@@ -3,6 +3,7 @@ package date_test
 import (
 	. "github.com/hajimehoshi/kakeibo/date"
 	"testing"
+	"time"
 )
 
 func TestZero(t *testing.T) {
@@ -57,3 +58,33 @@ func TestString(t *testing.T) {
 		}
 	}
 }
+
+func TestAddDate(t *testing.T) {
+	tests := []struct {
+		Date     Date
+		Years    int
+		Months   int
+		Days     int
+		Expected Date
+	}{
+		{New(2015, 1, 5), 0, 0, 1, New(2015, 1, 6)},
+		{New(2015, 1, 31), 0, 1, 0, New(2015, 3, 3)},
+		{New(2015, 2, 1), 0, 1, -1, New(2015, 2, 28)},
+		{New(2016, 2, 29), 1, 0, 0, New(2017, 3, 1)},
+		{New(1969, 12, 31), 0, 0, 1, New(1970, 1, 1)},
+		{New(2015, 1, 1), 0, 0, -1, New(2014, 12, 31)},
+	}
+	for _, test := range tests {
+		got := test.Date.AddDate(test.Years, test.Months, test.Days)
+		if test.Expected != got {
+			t.Errorf("expected %+v got %+v", test.Expected, got)
+		}
+	}
+}
+
+func TestWeekday(t *testing.T) {
+	d := New(2015, 1, 5)
+	if d.Weekday() != time.Monday {
+		t.Errorf("expected %+v got %+v", time.Monday, d.Weekday())
+	}
+}
@@ -0,0 +1,178 @@
+// +build js
+
+package idb
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gopherjs/gopherjs/js"
+	"github.com/hajimehoshi/kakeibo/models"
+)
+
+func (i *IDB) putBlob(hash string, blob js.Object, uploaded bool) error {
+	ch := make(chan error)
+	tr := i.db.Call("transaction", blobStore, "readwrite")
+	s := tr.Call("objectStore", blobStore)
+	record := js.Global.Get("Object").New()
+	record.Set("Hash", hash)
+	record.Set("Blob", blob)
+	record.Set("Uploaded", uploaded)
+	req := s.Call("put", record)
+	req.Set("onsuccess", func() {
+		close(ch)
+	})
+	req.Set("onerror", func(e js.Object) {
+		go func() {
+			ch <- toError(e.Get("target"))
+			close(ch)
+		}()
+	})
+	return <-ch
+}
+
+// SaveBlob stores the content of an attachment by its hash. The content is
+// uploaded to the server on the next sync.
+func (i *IDB) SaveBlob(hash string, blob js.Object) error {
+	i.syncNeeded = true
+	return i.putBlob(hash, blob, false)
+}
+
+// LoadBlob returns the content of the hash, or nil if the content is not
+// stored on this client.
+func (i *IDB) LoadBlob(hash string) (js.Object, error) {
+	ch := make(chan error)
+	tr := i.db.Call("transaction", blobStore, "readonly")
+	s := tr.Call("objectStore", blobStore)
+	req := s.Call("get", hash)
+	var result js.Object
+	req.Set("onsuccess", func(e js.Object) {
+		r := e.Get("target").Get("result")
+		if !r.IsUndefined() && !r.IsNull() {
+			result = r.Get("Blob")
+		}
+		close(ch)
+	})
+	req.Set("onerror", func(e js.Object) {
+		go func() {
+			ch <- toError(e.Get("target"))
+			close(ch)
+		}()
+	})
+	if err := <-ch; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type pendingBlob struct {
+	hash string
+	blob js.Object
+}
+
+// pendingBlobs returns the contents which are not uploaded yet.
+func (i *IDB) pendingBlobs() ([]pendingBlob, error) {
+	ch := make(chan error)
+	tr := i.db.Call("transaction", blobStore, "readonly")
+	s := tr.Call("objectStore", blobStore)
+	req := s.Call("openCursor")
+	result := []pendingBlob{}
+	req.Set("onsuccess", func(e js.Object) {
+		cursor := e.Get("target").Get("result")
+		if cursor.IsNull() {
+			close(ch)
+			return
+		}
+		r := cursor.Get("value")
+		if !r.Get("Uploaded").Bool() {
+			b := pendingBlob{r.Get("Hash").Str(), r.Get("Blob")}
+			result = append(result, b)
+		}
+		cursor.Call("continue")
+	})
+	req.Set("onerror", func(e js.Object) {
+		go func() {
+			ch <- toError(e.Get("target"))
+			close(ch)
+		}()
+	})
+	if err := <-ch; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (i *IDB) uploadBlob(hash string, blob js.Object) error {
+	ch := make(chan error)
+	req := js.Global.Get("XMLHttpRequest").New()
+	url := "/attachments/" + hash + LedgerQuery(i.ledger)
+	req.Call("open", "PUT", url, true)
+	req.Set("onload", func(e js.Object) {
+		close(ch)
+	})
+	req.Set("onerror", func(e js.Object) {
+		go func() {
+			ch <- toError(e)
+			close(ch)
+		}()
+	})
+	req.Call("send", blob)
+	if err := <-ch; err != nil {
+		return err
+	}
+	// 200 means the server already has the content.
+	if s := req.Get("status").Int(); s != 200 && s != 201 {
+		e := fmt.Sprintf("idb: status is not OK: %d", s)
+		return errors.New(e)
+	}
+	return nil
+}
+
+// uploadBlobs uploads the contents which are not uploaded yet.
+func (i *IDB) uploadBlobs() error {
+	blobs, err := i.pendingBlobs()
+	if err != nil {
+		return err
+	}
+	for _, b := range blobs {
+		if err := i.uploadBlob(b.hash, b.blob); err != nil {
+			return err
+		}
+		if err := i.putBlob(b.hash, b.blob, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneBlobs deletes the contents of the deleted attachments which no other
+// attachments refer to.
+func (i *IDB) pruneBlobs() error {
+	for _, m := range i.models {
+		if m.Type().Name() != "Attachment" {
+			continue
+		}
+		values, err := i.getAll(m)
+		if err != nil {
+			return err
+		}
+		deleted := map[string]struct{}{}
+		for _, v := range values {
+			a := v.(*models.Attachment)
+			if a.Meta.IsDeleted {
+				deleted[a.Hash] = struct{}{}
+			}
+		}
+		for _, v := range values {
+			a := v.(*models.Attachment)
+			if !a.Meta.IsDeleted {
+				delete(deleted, a.Hash)
+			}
+		}
+		for hash := range deleted {
+			if err := i.delete(blobStore, hash); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
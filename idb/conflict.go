@@ -0,0 +1,159 @@
+// +build js
+
+package idb
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/hajimehoshi/kakeibo/models"
+	"reflect"
+	"time"
+)
+
+// ConflictStrategy is a way to resolve a conflict between a value edited on
+// this client and the value stored on the server.
+type ConflictStrategy int
+
+const (
+	// ConflictServerWins discards the edit on this client.
+	ConflictServerWins ConflictStrategy = iota
+	// ConflictClientWins overwrites the value on the server by the edit on
+	// this client.
+	ConflictClientWins
+	// ConflictMerge merges the edits field by field. If both edited the
+	// same field, the server's value is taken.
+	ConflictMerge
+)
+
+// ConflictHandler is implemented by a Model which is notified of conflicts.
+type ConflictHandler interface {
+	OnConflicted(conflicts []models.Conflict)
+}
+
+func (i *IDB) SetConflictStrategy(strategy ConflictStrategy) {
+	i.conflictStrategy = strategy
+}
+
+// saveBase saves the stored value as the base of an edit if the stored value
+// is synced.
+func (i *IDB) saveBase(value interface{}) error {
+	t := reflect.TypeOf(value).Elem()
+	id := models.MetaOf(value).ID.String()
+	j, err := i.get(t.Name(), id)
+	if err != nil {
+		return err
+	}
+	if j == "" {
+		return nil
+	}
+	st, err := models.LookupSyncedType(t.Name())
+	if err != nil {
+		return err
+	}
+	stored, err := st.Decode(json.RawMessage(j))
+	if err != nil {
+		return err
+	}
+	// The stored value is already edited and the base is already saved.
+	if models.MetaOf(stored).LastUpdated.IsZero() {
+		return nil
+	}
+	return i.putTo(baseStore, stored)
+}
+
+func (i *IDB) base(st *models.SyncedType, id string) (interface{}, error) {
+	j, err := i.get(baseStore, id)
+	if err != nil {
+		return nil, err
+	}
+	if j == "" {
+		return nil, nil
+	}
+	return st.Decode(json.RawMessage(j))
+}
+
+func (i *IDB) resolve(
+	st *models.SyncedType,
+	local interface{},
+	server interface{}) (interface{}, error) {
+	id := models.MetaOf(server).ID.String()
+	var result interface{}
+	switch i.conflictStrategy {
+	case ConflictServerWins:
+		result = server
+	case ConflictClientWins:
+		// Send the local value again as an edit of the server's revision.
+		models.MetaOf(local).Revision = models.MetaOf(server).Revision
+		result = local
+	case ConflictMerge:
+		base, err := i.base(st, id)
+		if err != nil {
+			return nil, err
+		}
+		if base == nil {
+			result = server
+			break
+		}
+		result, _ = models.Merge(base, local, server)
+	default:
+		return nil, errors.New("idb: invalid conflict strategy")
+	}
+
+	if reflect.DeepEqual(result, server) {
+		if err := i.delete(baseStore, id); err != nil {
+			return nil, err
+		}
+		if err := i.put(server); err != nil {
+			return nil, err
+		}
+		return server, nil
+	}
+
+	// The result is based on the server's value and needs to be sent again.
+	models.MetaOf(result).LastUpdated = time.Time{}
+	if err := i.putTo(baseStore, server); err != nil {
+		return nil, err
+	}
+	if err := i.put(result); err != nil {
+		return nil, err
+	}
+	i.syncNeeded = true
+	return result, nil
+}
+
+func (i *IDB) resolveConflicts(
+	m Model,
+	sent []interface{},
+	rejected []interface{}) ([]models.Conflict, error) {
+	st, err := models.LookupSyncedType(m.Type().Name())
+	if err != nil {
+		return nil, err
+	}
+	locals := map[string]interface{}{}
+	for _, v := range sent {
+		locals[models.MetaOf(v).ID.String()] = v
+	}
+	conflicts := []models.Conflict{}
+	for _, server := range rejected {
+		if reflect.TypeOf(server) != reflect.PtrTo(m.Type()) {
+			return nil, errors.New("idb: invalid response")
+		}
+		local, ok := locals[models.MetaOf(server).ID.String()]
+		if !ok {
+			return nil, errors.New("idb: invalid response")
+		}
+		// Copy local since resolve might modify it.
+		localCopy := reflect.New(m.Type())
+		localCopy.Elem().Set(reflect.ValueOf(local).Elem())
+		result, err := i.resolve(st, localCopy.Interface(), server)
+		if err != nil {
+			return nil, err
+		}
+		conflicts = append(conflicts, models.Conflict{
+			Local:  local,
+			Server: server,
+			Result: result,
+		})
+	}
+	return conflicts, nil
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"github.com/gopherjs/gopherjs/js"
 	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
 	"reflect"
 	"time"
 )
@@ -16,6 +17,12 @@ import (
 
 const (
 	lastUpdatedIndex = "LastUpdated"
+	// baseStore is the object store of the synced values which unsynced
+	// edits are based on.
+	baseStore = "Base"
+	// blobStore is the object store of the contents of attachments keyed by
+	// their hashes. The contents are stored as Blob objects, not as JSON.
+	blobStore = "Blobs"
 )
 
 type Model interface {
@@ -24,10 +31,17 @@ type Model interface {
 }
 
 type IDB struct {
-	name        string
-	db          js.Object
-	lastUpdated time.Time
-	syncNeeded  bool
+	name string
+	// ledger is the shared ledger whose values are synced, or empty for
+	// the user's own values.
+	ledger uuid.UUID
+	db     js.Object
+	models []Model
+	// lastUpdated is the last-updated time of the server for each model
+	// type.
+	lastUpdated      map[string]time.Time
+	syncNeeded       bool
+	conflictStrategy ConflictStrategy
 }
 
 func toError(e js.Object) error {
@@ -37,17 +51,8 @@ func toError(e js.Object) error {
 	return errors.New(fmt.Sprintf("idb: %s: %s", name, msg))
 }
 
-func DeleteDBIfUserChanged(name string) error {
+func deleteDB(name string) error {
 	ch := make(chan error)
-
-	ls := js.Global.Get("localStorage")
-	last := ls.Call("getItem", "last_user_email").Str()
-	current := js.Global.Call("userEmail").Str()
-	if last == current {
-		close(ch)
-		return nil
-	}
-
 	req := js.Global.Get("indexedDB").Call("deleteDatabase", name)
 	req.Set("onsuccess", func(e js.Object) {
 		close(ch)
@@ -58,27 +63,61 @@ func DeleteDBIfUserChanged(name string) error {
 			close(ch)
 		}()
 	})
-	ls.Call("setItem", "last_user_email", current)
+	return <-ch
+}
 
-	if err := <-ch; err != nil {
-		return err
+// DeleteDBIfUserChanged deletes the database and the databases of the shared
+// ledgers if another user used them, and resets the current ledger.
+func DeleteDBIfUserChanged(name string) error {
+	ls := js.Global.Get("localStorage")
+	last := ls.Call("getItem", "last_user_email").Str()
+	current := js.Global.Call("userEmail").Str()
+	if last == current {
+		return nil
 	}
+
+	names := append(ledgerDBNames(), name)
+	for _, n := range names {
+		if err := deleteDB(n); err != nil {
+			return err
+		}
+	}
+	ls.Call("removeItem", ledgerDBsKey)
+	ls.Call("removeItem", currentLedgerKey)
+	ls.Call("setItem", "last_user_email", current)
 	return nil
 }
 
-func New(name string) *IDB {
+// New returns a database whose values are synced with the ledger. An empty
+// ledger means the user's own values. The values of a shared ledger are stored
+// in another database.
+func New(name string, ledger uuid.UUID) *IDB {
+	if ledger != "" {
+		name += "-" + ledger.String()
+		addLedgerDBName(name)
+	}
 	return &IDB{
-		name:       name,
-		syncNeeded: true,
+		name:        name,
+		ledger:      ledger,
+		lastUpdated: map[string]time.Time{},
+		syncNeeded:  true,
 	}
 }
 
 func (i *IDB) Save(value interface{}) error {
 	i.syncNeeded = true
+	if err := i.saveBase(value); err != nil {
+		return err
+	}
 	return i.put(value)
 }
 
 func (i *IDB) put(v interface{}) error {
+	t := reflect.TypeOf(v).Elem()
+	return i.putTo(t.Name(), v)
+}
+
+func (i *IDB) putTo(store string, v interface{}) error {
 	json, err := json.Marshal(v)
 	if err != nil {
 		return err
@@ -87,9 +126,8 @@ func (i *IDB) put(v interface{}) error {
 	ch := make(chan error)
 	j := js.Global.Get("JSON").Call("parse", string(json))
 	db := i.db
-	t := reflect.TypeOf(v).Elem()
-	tr := db.Call("transaction", t.Name(), "readwrite")
-	s := tr.Call("objectStore", t.Name())
+	tr := db.Call("transaction", store, "readwrite")
+	s := tr.Call("objectStore", store)
 	req := s.Call("put", j)
 	req.Set("onsuccess", func() {
 		close(ch)
@@ -108,11 +146,65 @@ func (i *IDB) put(v interface{}) error {
 	return nil
 }
 
+// get returns the JSON string of the record in the store, or an empty string
+// if the record doesn't exist.
+func (i *IDB) get(store string, key string) (string, error) {
+	ch := make(chan error)
+	db := i.db
+	tr := db.Call("transaction", store, "readonly")
+	s := tr.Call("objectStore", store)
+	req := s.Call("get", key)
+	result := ""
+	req.Set("onsuccess", func(e js.Object) {
+		r := e.Get("target").Get("result")
+		if !r.IsUndefined() && !r.IsNull() {
+			result = jsonStringify(r)
+		}
+		close(ch)
+	})
+	req.Set("onerror", func(e js.Object) {
+		go func() {
+			ch <- toError(e.Get("target"))
+			close(ch)
+		}()
+	})
+
+	if err := <-ch; err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+func (i *IDB) delete(store string, key string) error {
+	ch := make(chan error)
+	db := i.db
+	tr := db.Call("transaction", store, "readwrite")
+	s := tr.Call("objectStore", store)
+	req := s.Call("delete", key)
+	req.Set("onsuccess", func() {
+		close(ch)
+	})
+	req.Set("onerror", func(e js.Object) {
+		go func() {
+			ch <- toError(e.Get("target"))
+			close(ch)
+		}()
+	})
+
+	return <-ch
+}
+
 func jsonStringify(v interface{}) string {
 	return js.Global.Get("JSON").Call("stringify", v).Str()
 }
 
-func (i *IDB) loadAll(m Model) error {
+// getAll returns all the values of the model including deleted ones.
+func (i *IDB) getAll(m Model) ([]interface{}, error) {
+	st, err := models.LookupSyncedType(m.Type().Name())
+	if err != nil {
+		return nil, err
+	}
+
 	ch := make(chan error)
 	db := i.db
 	t := m.Type()
@@ -123,18 +215,12 @@ func (i *IDB) loadAll(m Model) error {
 	req.Set("onsuccess", func(e js.Object) {
 		cursor := e.Get("target").Get("result")
 		if cursor.IsNull() {
-			m.OnLoaded(values)
 			close(ch)
 			return
 		}
-		value := cursor.Get("value")
-		if value.Get("Meta").Get("IsDeleted").Bool() {
-			cursor.Call("continue")
-			return
-		}
-		v := reflect.New(t).Interface()
-		j := jsonStringify(value)
-		if err := json.Unmarshal([]byte(j), &v); err != nil {
+		j := jsonStringify(cursor.Get("value"))
+		v, err := st.Decode(json.RawMessage(j))
+		if err != nil {
 			go func() {
 				ch <- err
 				close(ch)
@@ -152,8 +238,19 @@ func (i *IDB) loadAll(m Model) error {
 	})
 
 	if err := <-ch; err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (i *IDB) loadAll(m Model) error {
+	all, err := i.getAll(m)
+	if err != nil {
 		return err
 	}
+	// Deleted values are also passed as syncs do. Items need them not to
+	// materialize deleted occurrences of recurring items again.
+	m.OnLoaded(all)
 	return nil
 }
 
@@ -161,6 +258,22 @@ func (i *IDB) SyncIfNeeded(models []Model) error {
 	if !i.syncNeeded {
 		return nil
 	}
+	// Edits made while syncing, like the results of conflicts to be sent
+	// again, set syncNeeded again and are sent on the next sync.
+	i.syncNeeded = false
+	if err := i.syncAll(models); err != nil {
+		i.syncNeeded = true
+		return err
+	}
+	return nil
+}
+
+func (i *IDB) syncAll(models []Model) error {
+	// Contents are uploaded before their attachments are synced so that
+	// other clients can download them.
+	if err := i.uploadBlobs(); err != nil {
+		return err
+	}
 	for _, m := range models {
 		if err := i.initLastUpdated(m); err != nil {
 			return err
@@ -173,18 +286,23 @@ func (i *IDB) SyncIfNeeded(models []Model) error {
 			return err
 		}
 	}
-	i.syncNeeded = false
-	return nil
+	return i.pruneBlobs()
 }
 
 func (i *IDB) Init(models []Model) error {
+	i.models = models
 	ch := make(chan error)
 
-	const version = 1
+	// Increment the version whenever a new object store is added.
+	const version = 10
 	req := js.Global.Get("indexedDB").Call("open", i.name, version)
 	req.Set("onupgradeneeded", func(e js.Object) {
 		db := e.Get("target").Get("result")
 		for _, m := range models {
+			names := db.Get("objectStoreNames")
+			if names.Call("contains", m.Type().Name()).Bool() {
+				continue
+			}
 			store := db.Call(
 				"createObjectStore",
 				m.Type().Name(),
@@ -201,6 +319,25 @@ func (i *IDB) Init(models []Model) error {
 				})
 			// TODO: create index for other columns
 		}
+		names := db.Get("objectStoreNames")
+		if !names.Call("contains", baseStore).Bool() {
+			db.Call(
+				"createObjectStore",
+				baseStore,
+				map[string]interface{}{
+					"keyPath":       "Meta.ID",
+					"autoIncrement": false,
+				})
+		}
+		if !names.Call("contains", blobStore).Bool() {
+			db.Call(
+				"createObjectStore",
+				blobStore,
+				map[string]interface{}{
+					"keyPath":       "Hash",
+					"autoIncrement": false,
+				})
+		}
 	})
 	req.Set("onsuccess", func(e js.Object) {
 		i.db = e.Get("target").Get("result")
@@ -231,7 +368,7 @@ func (i *IDB) Init(models []Model) error {
 }
 
 func (i *IDB) initLastUpdated(m Model) error {
-	if !i.lastUpdated.IsZero() {
+	if _, ok := i.lastUpdated[m.Type().Name()]; ok {
 		return nil
 	}
 
@@ -257,10 +394,8 @@ func (i *IDB) initLastUpdated(m Model) error {
 				}()
 				return
 			}
-			if i.lastUpdated.Before(maxLastUpdated) {
-				i.lastUpdated = maxLastUpdated
-			}
 		}
+		i.lastUpdated[t.Name()] = maxLastUpdated
 		close(ch)
 	})
 	req.Set("onerror", func(e js.Object) {
@@ -274,6 +409,11 @@ func (i *IDB) initLastUpdated(m Model) error {
 }
 
 func (i *IDB) getUnsyncedItems(m Model) ([]interface{}, error) {
+	st, err := models.LookupSyncedType(m.Type().Name())
+	if err != nil {
+		return nil, err
+	}
+
 	ch := make(chan error)
 
 	// A record whose LastUpdated is zero time means a record which is not
@@ -295,8 +435,8 @@ func (i *IDB) getUnsyncedItems(m Model) ([]interface{}, error) {
 		}
 		j := cursor.Get("value")
 		jStr := jsonStringify(j)
-		value := reflect.New(t).Interface()
-		if err := json.Unmarshal([]byte(jStr), &value); err != nil {
+		value, err := st.Decode(json.RawMessage(jStr))
+		if err != nil {
 			go func() {
 				ch <- err
 				close(ch) // ?
@@ -320,7 +460,47 @@ func (i *IDB) getUnsyncedItems(m Model) ([]interface{}, error) {
 	return values, nil
 }
 
+// syncPageSize is the maximum number of values sent or received at once.
+const syncPageSize = 100
+
+// sync sends the values and receives the values updated on the server page by
+// page until the client is caught up.
 func (i *IDB) sync(m Model, values []interface{}) error {
+	name := m.Type().Name()
+	lastUpdated := i.lastUpdated[name]
+	cursor := ""
+	filter := NewSyncFilter()
+	for {
+		n := len(values)
+		if syncPageSize < n {
+			n = syncPageSize
+		}
+		res, err := i.syncPage(
+			m,
+			filter,
+			lastUpdated,
+			values[:n],
+			cursor)
+		if err != nil {
+			return err
+		}
+		values = values[n:]
+		cursor = res.Cursor
+		if len(values) == 0 && cursor == "" {
+			i.lastUpdated[name] = res.LastUpdated
+			return nil
+		}
+	}
+}
+
+// syncPage sends one request and applies the response to IndexedDB and the
+// model. filter is shared by the pages of the sync.
+func (i *IDB) syncPage(
+	m Model,
+	filter *SyncFilter,
+	lastUpdated time.Time,
+	values []interface{},
+	cursor string) (*models.SyncResponse, error) {
 	ch := make(chan error)
 	req := js.Global.Get("XMLHttpRequest").New()
 	req.Call("open", "POST", "/sync", true)
@@ -336,37 +516,158 @@ func (i *IDB) sync(m Model, values []interface{}) error {
 
 	request := models.SyncRequest{
 		Type:        m.Type().Name(),
-		LastUpdated: i.lastUpdated,
+		Ledger:      i.ledger,
+		LastUpdated: lastUpdated,
 		Values:      values,
+		Cursor:      cursor,
+		Limit:       syncPageSize,
 	}
 	str, _ := json.Marshal(request)
 	req.Call("send", str)
 
 	if err := <-ch; err != nil {
-		return err
+		return nil, err
 	}
 
-	if s := req.Get("status").Int(); s != 200 {
-		return errors.New(fmt.Sprintf("idb: status is not OK: %d", s))
+	switch s := req.Get("status").Int(); s {
+	case 200:
+	case 403:
+		return nil, ErrForbidden
+	default:
+		e := fmt.Sprintf("idb: status is not OK: %d", s)
+		return nil, errors.New(e)
 	}
 	text := req.Get("responseText").Str()
-	res := models.SyncResponse{}
-	if err := json.Unmarshal([]byte(text), &res); err != nil {
-		return err
+	res := &models.SyncResponse{}
+	if err := json.Unmarshal([]byte(text), res); err != nil {
+		return nil, err
+	}
+	if res.Type != request.Type {
+		return nil, errors.New("idb: invalid response type")
 	}
-	vals := []interface{}{}
 	for _, v := range res.Values {
-		v, ok := v.(*models.ItemData)
-		if !ok {
-			return errors.New("idb: invalid response")
+		if reflect.TypeOf(v) != reflect.PtrTo(m.Type()) {
+			return nil, errors.New("idb: invalid response")
 		}
+	}
+	// Rejected values are resolved below.
+	hasUnsentEdit := func(id uuid.UUID) (bool, error) {
+		return i.hasUnsentEdit(m, id)
+	}
+	received, err := filter.Filter(values, res, hasUnsentEdit)
+	if err != nil {
+		return nil, err
+	}
+	vals := []interface{}{}
+	for _, v := range received {
+		id := models.MetaOf(v).ID.String()
 		if err := i.put(v); err != nil {
-			return err
+			return nil, err
+		}
+		if err := i.delete(baseStore, id); err != nil {
+			return nil, err
 		}
 		vals = append(vals, v)
 	}
-	i.lastUpdated = res.LastUpdated
+	conflicts, err := i.resolveConflicts(m, values, res.Rejected)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range conflicts {
+		vals = append(vals, c.Result)
+	}
 	m.OnLoaded(vals)
+	if h, ok := m.(ConflictHandler); ok && 0 < len(conflicts) {
+		h.OnConflicted(conflicts)
+	}
 
-	return nil
+	return res, nil
+}
+
+// hasUnsentEdit reports whether the value of the ID stored on this client is
+// edited and not sent yet.
+func (i *IDB) hasUnsentEdit(m Model, id uuid.UUID) (bool, error) {
+	name := m.Type().Name()
+	j, err := i.get(name, id.String())
+	if err != nil {
+		return false, err
+	}
+	if j == "" {
+		return false, nil
+	}
+	st, err := models.LookupSyncedType(name)
+	if err != nil {
+		return false, err
+	}
+	v, err := st.Decode(json.RawMessage(j))
+	if err != nil {
+		return false, err
+	}
+	return models.MetaOf(v).LastUpdated.IsZero(), nil
+}
+
+// Backup returns the backup of all the values including deleted ones and
+// edits not synced yet.
+func (i *IDB) Backup() (*models.Backup, error) {
+	b := models.NewBackup(time.Now().UTC())
+	for _, m := range i.models {
+		values, err := i.getAll(m)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 0 {
+			continue
+		}
+		b.Values[m.Type().Name()] = values
+	}
+	return b, nil
+}
+
+// Restore saves the values in the backup which models.ShouldRestore accepts
+// as unsynced edits, and returns the number of the restored values. Restore
+// modifies the Meta of the values in the backup.
+func (i *IDB) Restore(b *models.Backup) (int, error) {
+	restored := 0
+	for _, m := range i.models {
+		name := m.Type().Name()
+		st, err := models.LookupSyncedType(name)
+		if err != nil {
+			return restored, err
+		}
+		vals := []interface{}{}
+		for _, v := range b.Values[name] {
+			meta := models.MetaOf(v)
+			str, err := i.get(name, meta.ID.String())
+			if err != nil {
+				return restored, err
+			}
+			var current *models.Meta
+			if str != "" {
+				c, err := st.Decode(json.RawMessage(str))
+				if err != nil {
+					return restored, err
+				}
+				current = models.MetaOf(c)
+			}
+			if !models.ShouldRestore(meta, current) {
+				continue
+			}
+			// The restored value overwrites the current value on the
+			// server on the next sync.
+			meta.Revision = 0
+			if current != nil {
+				meta.Revision = current.Revision
+			}
+			meta.LastUpdated = time.Time{}
+			if err := i.Save(v); err != nil {
+				return restored, err
+			}
+			vals = append(vals, v)
+		}
+		if 0 < len(vals) {
+			m.OnLoaded(vals)
+		}
+		restored += len(vals)
+	}
+	return restored, nil
 }
@@ -0,0 +1,150 @@
+// +build js
+
+package idb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gopherjs/gopherjs/js"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"net/url"
+)
+
+const (
+	// currentLedgerKey is the key in localStorage of the ledger which is
+	// shown. The user's own values are shown if it is absent.
+	currentLedgerKey = "current_ledger"
+	// ledgerDBsKey is the key in localStorage of the names of the databases
+	// of shared ledgers in JSON.
+	ledgerDBsKey = "ledger_dbs"
+)
+
+// ErrForbidden is returned when the server rejects a sync of a shared ledger
+// because the user is not a member with the permission.
+var ErrForbidden = errors.New("idb: forbidden")
+
+// CurrentLedger returns the ledger which the user switched to, or an empty
+// UUID for the user's own values.
+func CurrentLedger() uuid.UUID {
+	v := js.Global.Get("localStorage").Call("getItem", currentLedgerKey)
+	if v.IsNull() {
+		return ""
+	}
+	id, err := uuid.ParseString(v.Str())
+	if err != nil {
+		return ""
+	}
+	return id
+}
+
+// LedgerQuery returns the query string which specifies the ledger in requests
+// to the server.
+func LedgerQuery(ledger uuid.UUID) string {
+	if ledger == "" {
+		return ""
+	}
+	return "?ledger=" + url.QueryEscape(ledger.String())
+}
+
+func ledgerDBNames() []string {
+	v := js.Global.Get("localStorage").Call("getItem", ledgerDBsKey)
+	if v.IsNull() {
+		return nil
+	}
+	names := []string{}
+	if err := json.Unmarshal([]byte(v.Str()), &names); err != nil {
+		return nil
+	}
+	return names
+}
+
+// addLedgerDBName records the name of a database of a shared ledger so that
+// the database is deleted when another user logs in.
+func addLedgerDBName(name string) {
+	names := ledgerDBNames()
+	for _, n := range names {
+		if n == name {
+			return
+		}
+	}
+	b, err := json.Marshal(append(names, name))
+	if err != nil {
+		return
+	}
+	js.Global.Get("localStorage").Call("setItem", ledgerDBsKey, string(b))
+}
+
+// request sends a request to the server and returns the response body.
+func request(method string, path string, body string) (string, error) {
+	ch := make(chan error)
+	req := js.Global.Get("XMLHttpRequest").New()
+	req.Call("open", method, path, true)
+	req.Set("onload", func(e js.Object) {
+		close(ch)
+	})
+	req.Set("onerror", func(e js.Object) {
+		go func() {
+			ch <- toError(e)
+			close(ch)
+		}()
+	})
+	req.Call("send", body)
+	if err := <-ch; err != nil {
+		return "", err
+	}
+	if s := req.Get("status").Int(); s != 200 && s != 201 {
+		e := fmt.Sprintf("idb: status is not OK: %d", s)
+		return "", errors.New(e)
+	}
+	return req.Get("responseText").Str(), nil
+}
+
+// Ledger returns the ledger whose values are synced, or an empty UUID for the
+// user's own values.
+func (i *IDB) Ledger() uuid.UUID {
+	return i.ledger
+}
+
+// Ledgers returns the ledgers which the user is a member of.
+func (i *IDB) Ledgers() ([]models.LedgerSummary, error) {
+	text, err := request("GET", "/ledgers", "")
+	if err != nil {
+		return nil, err
+	}
+	ledgers := []models.LedgerSummary{}
+	if err := json.Unmarshal([]byte(text), &ledgers); err != nil {
+		return nil, err
+	}
+	return ledgers, nil
+}
+
+// CreateLedger creates a ledger whose owner is the user.
+func (i *IDB) CreateLedger(name string) (*models.LedgerSummary, error) {
+	body, err := json.Marshal(struct{ Name string }{name})
+	if err != nil {
+		return nil, err
+	}
+	text, err := request("POST", "/ledgers", string(body))
+	if err != nil {
+		return nil, err
+	}
+	ledger := &models.LedgerSummary{}
+	if err := json.Unmarshal([]byte(text), ledger); err != nil {
+		return nil, err
+	}
+	return ledger, nil
+}
+
+// SwitchLedger shows the ledger, or the user's own values if ledger is empty,
+// by reloading the page.
+func (i *IDB) SwitchLedger(ledger uuid.UUID) {
+	ls := js.Global.Get("localStorage")
+	if ledger == "" {
+		ls.Call("removeItem", currentLedgerKey)
+	} else {
+		ls.Call("setItem", currentLedgerKey, ledger.String())
+	}
+	js.Global.Get("location").Call("reload")
+}
@@ -0,0 +1,68 @@
+package idb
+
+import (
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+)
+
+// SyncFilter decides which values received in the pages of a sync are stored
+// on the client. A filter is used for all the pages of one sync of a type.
+type SyncFilter struct {
+	// rejected is the IDs of the values rejected in any page of the sync.
+	// The rejected values are resolved as conflicts, and the server's
+	// values received in later pages must not overwrite the results.
+	rejected map[uuid.UUID]struct{}
+	// accepted is the IDs of the values sent and accepted in any page of
+	// the sync. Their server's values can be received in later pages, and
+	// are stored though the local values are not marked as synced yet.
+	accepted map[uuid.UUID]struct{}
+}
+
+func NewSyncFilter() *SyncFilter {
+	return &SyncFilter{
+		rejected: map[uuid.UUID]struct{}{},
+		accepted: map[uuid.UUID]struct{}{},
+	}
+}
+
+// Filter returns the values of the response which are stored on the client.
+// sent is the values sent in the page. hasUnsentEdit reports whether the
+// value of the ID stored on the client has an edit which is not sent yet,
+// like a value waiting in a later page. Such values are not overwritten, and
+// conflicts with them are resolved when they are sent.
+func (f *SyncFilter) Filter(
+	sent []interface{},
+	res *models.SyncResponse,
+	hasUnsentEdit func(id uuid.UUID) (bool, error)) ([]interface{}, error) {
+	for _, v := range res.Rejected {
+		id := models.MetaOf(v).ID
+		f.rejected[id] = struct{}{}
+		delete(f.accepted, id)
+	}
+	for _, v := range sent {
+		id := models.MetaOf(v).ID
+		if _, ok := f.rejected[id]; !ok {
+			f.accepted[id] = struct{}{}
+		}
+	}
+	result := []interface{}{}
+	for _, v := range res.Values {
+		id := models.MetaOf(v).ID
+		if _, ok := f.rejected[id]; ok {
+			continue
+		}
+		// The value sent in this or an earlier page is stored as the
+		// server's value.
+		if _, ok := f.accepted[id]; !ok {
+			unsent, err := hasUnsentEdit(id)
+			if err != nil {
+				return nil, err
+			}
+			if unsent {
+				continue
+			}
+		}
+		result = append(result, v)
+	}
+	return result, nil
+}
@@ -0,0 +1,108 @@
+package idb_test
+
+import (
+	. "github.com/hajimehoshi/kakeibo/idb"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"reflect"
+	"testing"
+)
+
+func newItem(subject string) *models.ItemData {
+	return &models.ItemData{
+		Meta:    models.Meta{ID: uuid.Generate()},
+		Subject: subject,
+	}
+}
+
+func serverCopy(item *models.ItemData, revision int) *models.ItemData {
+	c := *item
+	c.Meta.Revision = revision
+	return &c
+}
+
+func subjects(values []interface{}) []string {
+	result := []string{}
+	for _, v := range values {
+		result = append(result, v.(*models.ItemData).Subject)
+	}
+	return result
+}
+
+func TestSyncFilterAcrossPages(t *testing.T) {
+	a := newItem("a")
+	b := newItem("b")
+	c := newItem("c")
+	d := newItem("d")
+	x := newItem("x")
+	y := newItem("y")
+	// a, b and d are sent in the first page, and c is sent in the second
+	// page. x is edited on this client while syncing.
+	unsent := map[uuid.UUID]bool{
+		a.Meta.ID: true,
+		b.Meta.ID: true,
+		c.Meta.ID: true,
+		d.Meta.ID: true,
+		x.Meta.ID: true,
+	}
+	hasUnsentEdit := func(id uuid.UUID) (bool, error) {
+		return unsent[id], nil
+	}
+
+	f := NewSyncFilter()
+	res := &models.SyncResponse{
+		Values: []interface{}{
+			serverCopy(a, 3),
+			serverCopy(b, 1),
+			serverCopy(c, 2),
+			serverCopy(x, 2),
+		},
+		Rejected: []interface{}{serverCopy(a, 3)},
+	}
+	got, err := f.Filter([]interface{}{a, b, d}, res, hasUnsentEdit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a is resolved as a conflict, and c and x are not sent yet.
+	if s := subjects(got); len(s) != 1 || s[0] != "b" {
+		t.Errorf("expected [b] got %+v", s)
+	}
+	// b is stored as the server's value. The rejection of a is remembered
+	// even if its local value doesn't look edited. The server's value of d
+	// is not received yet, so d is not marked as synced yet.
+	delete(unsent, a.Meta.ID)
+	delete(unsent, b.Meta.ID)
+
+	res = &models.SyncResponse{
+		Values: []interface{}{
+			serverCopy(a, 3),
+			serverCopy(c, 3),
+			serverCopy(d, 1),
+			serverCopy(x, 2),
+			serverCopy(y, 1),
+		},
+	}
+	got, err = f.Filter([]interface{}{c}, res, hasUnsentEdit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// d is accepted in the first page and its server's value is received
+	// in this page.
+	expected := []string{"c", "d", "y"}
+	if s := subjects(got); !reflect.DeepEqual(s, expected) {
+		t.Errorf("expected %+v got %+v", expected, s)
+	}
+
+	// A new sync doesn't remember the rejections of the previous one.
+	f = NewSyncFilter()
+	res = &models.SyncResponse{
+		Values: []interface{}{serverCopy(a, 4)},
+	}
+	got, err = f.Filter(nil, res, hasUnsentEdit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := subjects(got); len(s) != 1 || s[0] != "a" {
+		t.Errorf("expected [a] got %+v", s)
+	}
+}
@@ -0,0 +1,137 @@
+package items
+
+import (
+	"errors"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/money"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"reflect"
+	"sort"
+	"time"
+)
+
+type AccountsView interface {
+	PrintAccounts(accounts []models.Account)
+}
+
+type Accounts struct {
+	accounts map[uuid.UUID]*models.Account
+	view     AccountsView
+	storage  Storage
+	// onChanged is called when accounts are added or removed.
+	onChanged func()
+}
+
+func NewAccounts(view AccountsView, storage Storage) *Accounts {
+	return &Accounts{
+		accounts: map[uuid.UUID]*models.Account{},
+		view:     view,
+		storage:  storage,
+	}
+}
+
+func (a *Accounts) Type() reflect.Type {
+	return reflect.TypeOf((*models.Account)(nil)).Elem()
+}
+
+func (a *Accounts) OnLoaded(vals []interface{}) {
+	for _, v := range vals {
+		d, ok := v.(*models.Account)
+		if !ok {
+			print("invalid data")
+			return
+		}
+		id := d.Meta.ID
+		if account, ok := a.accounts[id]; ok {
+			*account = *d
+			continue
+		}
+		a.accounts[id] = d
+	}
+	a.changed()
+}
+
+func (a *Accounts) changed() {
+	if a.view != nil {
+		a.view.PrintAccounts(a.sorted())
+	}
+	if a.onChanged != nil {
+		a.onChanged()
+	}
+}
+
+func (a *Accounts) get(id uuid.UUID) *models.Account {
+	if account, ok := a.accounts[id]; ok && !account.Meta.IsDeleted {
+		return account
+	}
+	return nil
+}
+
+type sortAccountsByName []models.Account
+
+func (s sortAccountsByName) Len() int {
+	return len(s)
+}
+
+func (s sortAccountsByName) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s sortAccountsByName) Less(i, j int) bool {
+	return s[i].Name < s[j].Name
+}
+
+// sorted returns the existing accounts sorted by their names.
+func (a *Accounts) sorted() []models.Account {
+	accounts := []models.Account{}
+	for _, account := range a.accounts {
+		if account.Meta.IsDeleted {
+			continue
+		}
+		accounts = append(accounts, *account)
+	}
+	sort.Sort(sortAccountsByName(accounts))
+	return accounts
+}
+
+func (a *Accounts) save(account *models.Account) error {
+	if !account.IsValid() {
+		return errors.New("Accounts.save: invalid data")
+	}
+	account.Meta.LastUpdated = time.Time{}
+	if a.storage == nil {
+		return nil
+	}
+	err := a.storage.Save(account) //gopherjs:blocking
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (a *Accounts) Create(name string, openingBalance money.Amount) error {
+	account := &models.Account{
+		Meta:           models.Meta{ID: uuid.Generate()},
+		Name:           name,
+		OpeningBalance: openingBalance,
+	}
+	if err := a.save(account); err != nil {
+		return err
+	}
+	a.accounts[account.Meta.ID] = account
+	a.changed()
+	return nil
+}
+
+func (a *Accounts) Destroy(id uuid.UUID) error {
+	account := a.get(id)
+	if account == nil {
+		return errors.New("Accounts.Destroy: account not found")
+	}
+	account.Destroy()
+	if err := a.save(account); err != nil {
+		return err
+	}
+	a.changed()
+	return nil
+}
@@ -0,0 +1,146 @@
+package items
+
+import (
+	"errors"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"reflect"
+	"sort"
+	"time"
+)
+
+type APITokensView interface {
+	PrintAPITokens(tokens []models.APIToken)
+}
+
+// APITokens is the tokens of the user for the REST API.
+type APITokens struct {
+	tokens  map[uuid.UUID]*models.APIToken
+	view    APITokensView
+	storage Storage
+	// userID is the ID of the user on the server, which is a part of the
+	// tokens.
+	userID string
+}
+
+func NewAPITokens(
+	view APITokensView,
+	storage Storage,
+	userID string) *APITokens {
+	return &APITokens{
+		tokens:  map[uuid.UUID]*models.APIToken{},
+		view:    view,
+		storage: storage,
+		userID:  userID,
+	}
+}
+
+func (a *APITokens) Type() reflect.Type {
+	return reflect.TypeOf((*models.APIToken)(nil)).Elem()
+}
+
+func (a *APITokens) OnLoaded(vals []interface{}) {
+	for _, v := range vals {
+		d, ok := v.(*models.APIToken)
+		if !ok {
+			print("invalid data")
+			return
+		}
+		id := d.Meta.ID
+		if token, ok := a.tokens[id]; ok {
+			*token = *d
+			continue
+		}
+		a.tokens[id] = d
+	}
+	a.changed()
+}
+
+func (a *APITokens) changed() {
+	if a.view != nil {
+		a.view.PrintAPITokens(a.sorted())
+	}
+}
+
+func (a *APITokens) get(id uuid.UUID) *models.APIToken {
+	if token, ok := a.tokens[id]; ok && !token.Meta.IsDeleted {
+		return token
+	}
+	return nil
+}
+
+type sortAPITokensByName []models.APIToken
+
+func (s sortAPITokensByName) Len() int {
+	return len(s)
+}
+
+func (s sortAPITokensByName) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s sortAPITokensByName) Less(i, j int) bool {
+	return s[i].Name < s[j].Name
+}
+
+// sorted returns the tokens which are not revoked sorted by their names.
+func (a *APITokens) sorted() []models.APIToken {
+	tokens := []models.APIToken{}
+	for _, token := range a.tokens {
+		if token.Meta.IsDeleted {
+			continue
+		}
+		tokens = append(tokens, *token)
+	}
+	sort.Sort(sortAPITokensByName(tokens))
+	return tokens
+}
+
+func (a *APITokens) save(token *models.APIToken) error {
+	if !token.IsValid() {
+		return errors.New("APITokens.save: invalid data")
+	}
+	token.Meta.LastUpdated = time.Time{}
+	if a.storage == nil {
+		return nil
+	}
+	err := a.storage.Save(token) //gopherjs:blocking
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Create creates a token and returns it. The token can't be shown again as
+// only its hash is saved. The token works after it is synced to the server.
+func (a *APITokens) Create(name string) (string, error) {
+	token, hash, err := models.NewAPIToken(a.userID)
+	if err != nil {
+		return "", err
+	}
+	t := &models.APIToken{
+		Meta: models.Meta{ID: uuid.Generate()},
+		Name: name,
+		Hash: hash,
+	}
+	if err := a.save(t); err != nil {
+		return "", err
+	}
+	a.tokens[t.Meta.ID] = t
+	a.changed()
+	return token, nil
+}
+
+// Destroy revokes the token.
+func (a *APITokens) Destroy(id uuid.UUID) error {
+	token := a.get(id)
+	if token == nil {
+		return errors.New("APITokens.Destroy: token not found")
+	}
+	token.Destroy()
+	if err := a.save(token); err != nil {
+		return err
+	}
+	a.changed()
+	return nil
+}
@@ -0,0 +1,152 @@
+package items
+
+import (
+	"errors"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"reflect"
+	"sort"
+	"time"
+)
+
+type Attachments struct {
+	attachments map[uuid.UUID]*models.Attachment
+	storage     Storage
+	// onChanged is called when attachments are added or removed.
+	onChanged func()
+}
+
+func NewAttachments(storage Storage) *Attachments {
+	return &Attachments{
+		attachments: map[uuid.UUID]*models.Attachment{},
+		storage:     storage,
+	}
+}
+
+func (a *Attachments) Type() reflect.Type {
+	return reflect.TypeOf((*models.Attachment)(nil)).Elem()
+}
+
+func (a *Attachments) OnLoaded(vals []interface{}) {
+	for _, v := range vals {
+		d, ok := v.(*models.Attachment)
+		if !ok {
+			print("invalid data")
+			return
+		}
+		id := d.Meta.ID
+		if attachment, ok := a.attachments[id]; ok {
+			*attachment = *d
+			continue
+		}
+		a.attachments[id] = d
+	}
+	a.changed()
+}
+
+func (a *Attachments) changed() {
+	if a.onChanged != nil {
+		a.onChanged()
+	}
+}
+
+func (a *Attachments) save(attachment *models.Attachment) error {
+	if !attachment.IsValid() {
+		return errors.New("Attachments.save: invalid data")
+	}
+	attachment.Meta.LastUpdated = time.Time{}
+	if a.storage == nil {
+		return nil
+	}
+	err := a.storage.Save(attachment) //gopherjs:blocking
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// create creates an attachment of the item. The content must be stored by its
+// hash beforehand.
+func (a *Attachments) create(
+	itemID uuid.UUID,
+	name string,
+	contentType string,
+	size int64,
+	hash string) error {
+	attachment := &models.Attachment{
+		Meta:        models.Meta{ID: uuid.Generate()},
+		ItemID:      itemID,
+		Name:        name,
+		ContentType: contentType,
+		Size:        size,
+		Hash:        hash,
+	}
+	if err := a.save(attachment); err != nil {
+		return err
+	}
+	a.attachments[attachment.Meta.ID] = attachment
+	a.changed()
+	return nil
+}
+
+func (a *Attachments) Destroy(id uuid.UUID) error {
+	attachment, ok := a.attachments[id]
+	if !ok || attachment.Meta.IsDeleted {
+		return errors.New("Attachments.Destroy: attachment not found")
+	}
+	attachment.Destroy()
+	if err := a.save(attachment); err != nil {
+		return err
+	}
+	a.changed()
+	return nil
+}
+
+// destroyItem destroys the attachments of the item, which is destroyed.
+func (a *Attachments) destroyItem(itemID uuid.UUID) error {
+	destroyed := false
+	for _, attachment := range a.attachments {
+		if attachment.Meta.IsDeleted || attachment.ItemID != itemID {
+			continue
+		}
+		attachment.Destroy()
+		if err := a.save(attachment); err != nil {
+			return err
+		}
+		destroyed = true
+	}
+	if destroyed {
+		a.changed()
+	}
+	return nil
+}
+
+type sortAttachments []models.Attachment
+
+func (s sortAttachments) Len() int {
+	return len(s)
+}
+
+func (s sortAttachments) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s sortAttachments) Less(i, j int) bool {
+	if s[i].Name != s[j].Name {
+		return s[i].Name < s[j].Name
+	}
+	return s[i].Meta.ID < s[j].Meta.ID
+}
+
+// forItem returns the attachments of the item which are not deleted.
+func (a *Attachments) forItem(itemID uuid.UUID) []models.Attachment {
+	result := []models.Attachment{}
+	for _, attachment := range a.attachments {
+		if attachment.Meta.IsDeleted || attachment.ItemID != itemID {
+			continue
+		}
+		result = append(result, *attachment)
+	}
+	sort.Sort(sortAttachments(result))
+	return result
+}
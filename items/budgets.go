@@ -0,0 +1,140 @@
+package items
+
+import (
+	"errors"
+	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/money"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"reflect"
+	"time"
+)
+
+type Budgets struct {
+	budgets map[uuid.UUID]*models.Budget
+	storage Storage
+	// onChanged is called when budgets are added or removed.
+	onChanged func()
+}
+
+func NewBudgets(storage Storage) *Budgets {
+	return &Budgets{
+		budgets: map[uuid.UUID]*models.Budget{},
+		storage: storage,
+	}
+}
+
+func (b *Budgets) Type() reflect.Type {
+	return reflect.TypeOf((*models.Budget)(nil)).Elem()
+}
+
+func (b *Budgets) OnLoaded(vals []interface{}) {
+	for _, v := range vals {
+		d, ok := v.(*models.Budget)
+		if !ok {
+			print("invalid data")
+			return
+		}
+		id := d.Meta.ID
+		if budget, ok := b.budgets[id]; ok {
+			*budget = *d
+			continue
+		}
+		b.budgets[id] = d
+	}
+	b.changed()
+}
+
+func (b *Budgets) changed() {
+	if b.onChanged != nil {
+		b.onChanged()
+	}
+}
+
+func (b *Budgets) save(budget *models.Budget) error {
+	if !budget.IsValid() {
+		return errors.New("Budgets.save: invalid data")
+	}
+	budget.Meta.LastUpdated = time.Time{}
+	if b.storage == nil {
+		return nil
+	}
+	err := b.storage.Save(budget) //gopherjs:blocking
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Create creates a budget. Either categoryID or subjectPattern must be
+// specified. month is zero for a recurring budget.
+func (b *Budgets) Create(
+	categoryID uuid.UUID,
+	subjectPattern string,
+	month date.Date,
+	limit money.Amount) error {
+	if month != 0 {
+		month = date.New(month.Year(), month.Month(), 1)
+	}
+	budget := &models.Budget{
+		Meta:           models.Meta{ID: uuid.Generate()},
+		CategoryID:     categoryID,
+		SubjectPattern: subjectPattern,
+		Month:          month,
+		Limit:          limit,
+	}
+	if err := b.save(budget); err != nil {
+		return err
+	}
+	b.budgets[budget.Meta.ID] = budget
+	b.changed()
+	return nil
+}
+
+func (b *Budgets) Destroy(id uuid.UUID) error {
+	budget, ok := b.budgets[id]
+	if !ok || budget.Meta.IsDeleted {
+		return errors.New("Budgets.Destroy: budget not found")
+	}
+	budget.Destroy()
+	if err := b.save(budget); err != nil {
+		return err
+	}
+	b.changed()
+	return nil
+}
+
+// budgetTarget returns a key which identifies what the budget is for.
+func budgetTarget(budget *models.Budget) string {
+	if budget.CategoryID != "" {
+		return "category:" + string(budget.CategoryID)
+	}
+	return "subject:" + budget.SubjectPattern
+}
+
+// forMonth returns the budgets for the month of ym. A budget for the specific
+// month overrides the recurring budget for the same target.
+func (b *Budgets) forMonth(ym date.Date) []*models.Budget {
+	specific := map[string]struct{}{}
+	for _, budget := range b.budgets {
+		if budget.Meta.IsDeleted || budget.IsRecurring() {
+			continue
+		}
+		if budget.AppliesTo(ym) {
+			specific[budgetTarget(budget)] = struct{}{}
+		}
+	}
+	result := []*models.Budget{}
+	for _, budget := range b.budgets {
+		if budget.Meta.IsDeleted || !budget.AppliesTo(ym) {
+			continue
+		}
+		if budget.IsRecurring() {
+			if _, ok := specific[budgetTarget(budget)]; ok {
+				continue
+			}
+		}
+		result = append(result, budget)
+	}
+	return result
+}
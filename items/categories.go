@@ -0,0 +1,191 @@
+package items
+
+import (
+	"errors"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const categoryPathSeparator = " > "
+
+// CategoryPath is a category and its full name like 'Food > Dining out'.
+type CategoryPath struct {
+	ID   uuid.UUID
+	Path string
+}
+
+type CategoriesView interface {
+	PrintCategories(categories []CategoryPath)
+}
+
+type Categories struct {
+	categories map[uuid.UUID]*models.Category
+	view       CategoriesView
+	storage    Storage
+	// onChanged is called when categories are added, renamed or removed.
+	onChanged func()
+}
+
+func NewCategories(view CategoriesView, storage Storage) *Categories {
+	return &Categories{
+		categories: map[uuid.UUID]*models.Category{},
+		view:       view,
+		storage:    storage,
+	}
+}
+
+func (c *Categories) Type() reflect.Type {
+	return reflect.TypeOf((*models.Category)(nil)).Elem()
+}
+
+func (c *Categories) OnLoaded(vals []interface{}) {
+	for _, v := range vals {
+		d, ok := v.(*models.Category)
+		if !ok {
+			print("invalid data")
+			return
+		}
+		id := d.Meta.ID
+		if category, ok := c.categories[id]; ok {
+			*category = *d
+			continue
+		}
+		c.categories[id] = d
+	}
+	c.changed()
+}
+
+func (c *Categories) changed() {
+	if c.view != nil {
+		c.view.PrintCategories(c.paths())
+	}
+	if c.onChanged != nil {
+		c.onChanged()
+	}
+}
+
+func (c *Categories) get(id uuid.UUID) *models.Category {
+	if category, ok := c.categories[id]; ok && !category.Meta.IsDeleted {
+		return category
+	}
+	return nil
+}
+
+// ancestors returns the IDs of the category and its ancestors, from the
+// category itself to the root. Unknown or deleted categories are ignored.
+func (c *Categories) ancestors(id uuid.UUID) []uuid.UUID {
+	ids := []uuid.UUID{}
+	visited := map[uuid.UUID]struct{}{}
+	for {
+		category := c.get(id)
+		if category == nil {
+			break
+		}
+		// Categories are edited on multiple devices, so a cycle might
+		// exist.
+		if _, ok := visited[id]; ok {
+			break
+		}
+		visited[id] = struct{}{}
+		ids = append(ids, id)
+		id = category.ParentID
+	}
+	return ids
+}
+
+// Path returns the full name of the category like 'Food > Dining out'. Path
+// returns an empty string if the category doesn't exist.
+func (c *Categories) Path(id uuid.UUID) string {
+	ids := c.ancestors(id)
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[len(ids)-1-i] = c.categories[id].Name
+	}
+	return strings.Join(names, categoryPathSeparator)
+}
+
+type sortCategoryPaths []CategoryPath
+
+func (s sortCategoryPaths) Len() int {
+	return len(s)
+}
+
+func (s sortCategoryPaths) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s sortCategoryPaths) Less(i, j int) bool {
+	return s[i].Path < s[j].Path
+}
+
+func (c *Categories) paths() []CategoryPath {
+	paths := []CategoryPath{}
+	for id, category := range c.categories {
+		if category.Meta.IsDeleted {
+			continue
+		}
+		paths = append(paths, CategoryPath{id, c.Path(id)})
+	}
+	sort.Sort(sortCategoryPaths(paths))
+	return paths
+}
+
+func (c *Categories) save(category *models.Category) error {
+	if !category.IsValid() {
+		return errors.New("Categories.save: invalid data")
+	}
+	category.Meta.LastUpdated = time.Time{}
+	if c.storage == nil {
+		return nil
+	}
+	err := c.storage.Save(category) //gopherjs:blocking
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Categories) Create(name string, parentID uuid.UUID) error {
+	if parentID != "" && c.get(parentID) == nil {
+		return errors.New("Categories.Create: parent not found")
+	}
+	category := &models.Category{
+		Meta:     models.Meta{ID: uuid.Generate()},
+		ParentID: parentID,
+		Name:     name,
+	}
+	if err := c.save(category); err != nil {
+		return err
+	}
+	c.categories[category.Meta.ID] = category
+	c.changed()
+	return nil
+}
+
+// Destroy removes the category. The children of the category are moved to
+// the category's parent.
+func (c *Categories) Destroy(id uuid.UUID) error {
+	category := c.get(id)
+	if category == nil {
+		return errors.New("Categories.Destroy: category not found")
+	}
+	for _, child := range c.categories {
+		if child.Meta.IsDeleted || child.ParentID != id {
+			continue
+		}
+		child.ParentID = category.ParentID
+		if err := c.save(child); err != nil {
+			return err
+		}
+	}
+	category.Destroy()
+	if err := c.save(category); err != nil {
+		return err
+	}
+	c.changed()
+	return nil
+}
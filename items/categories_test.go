@@ -0,0 +1,173 @@
+package items_test
+
+import (
+	. "github.com/hajimehoshi/kakeibo/items"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"reflect"
+	"testing"
+)
+
+type categoriesView struct {
+	paths []CategoryPath
+}
+
+func (v *categoriesView) PrintCategories(categories []CategoryPath) {
+	v.paths = categories
+}
+
+// categoryStorage keeps the last saved value of each category.
+type categoryStorage map[uuid.UUID]models.Category
+
+func (s categoryStorage) Save(v interface{}) error {
+	c := v.(*models.Category)
+	s[c.Meta.ID] = *c
+	return nil
+}
+
+// pathNames returns the paths of the printed categories.
+func pathNames(view *categoriesView) []string {
+	result := []string{}
+	for _, p := range view.paths {
+		result = append(result, p.Path)
+	}
+	return result
+}
+
+// idOf returns the ID of the printed category of the path.
+func idOf(t *testing.T, view *categoriesView, path string) uuid.UUID {
+	for _, p := range view.paths {
+		if p.Path == path {
+			return p.ID
+		}
+	}
+	t.Fatalf("category not found: %s", path)
+	return ""
+}
+
+func TestCategoriesPath(t *testing.T) {
+	view := &categoriesView{}
+	c := NewCategories(view, categoryStorage{})
+	names := []string{"Food", "Dining out", "Lunch", "Weekday"}
+	parentID := uuid.UUID("")
+	path := ""
+	for _, name := range names {
+		if err := c.Create(name, parentID); err != nil {
+			t.Fatal(err)
+		}
+		if path != "" {
+			path += " > "
+		}
+		path += name
+		parentID = idOf(t, view, path)
+	}
+	if err := c.Create("Rent", ""); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"Food",
+		"Food > Dining out",
+		"Food > Dining out > Lunch",
+		"Food > Dining out > Lunch > Weekday",
+		"Rent",
+	}
+	if got := pathNames(view); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected %+v got %+v", expected, got)
+	}
+	id := idOf(t, view, "Food > Dining out > Lunch > Weekday")
+	if got := c.Path(id); got != expected[3] {
+		t.Errorf("expected %s got %s", expected[3], got)
+	}
+	if got := c.Path(uuid.Generate()); got != "" {
+		t.Errorf("expected an empty path got %s", got)
+	}
+	if err := c.Create("Snack", uuid.Generate()); err == nil {
+		t.Errorf("expected an error for an unknown parent")
+	}
+}
+
+func TestCategoriesCycle(t *testing.T) {
+	// Categories edited on different clients can form a cycle.
+	newCategory := func(name string) *models.Category {
+		return &models.Category{
+			Meta: models.Meta{ID: uuid.Generate()},
+			Name: name,
+		}
+	}
+	a := newCategory("A")
+	b := newCategory("B")
+	d := newCategory("C")
+	a.ParentID = b.Meta.ID
+	b.ParentID = d.Meta.ID
+	d.ParentID = a.Meta.ID
+	view := &categoriesView{}
+	c := NewCategories(view, nil)
+	c.OnLoaded([]interface{}{a, b, d})
+	expected := []string{"A > C > B", "B > A > C", "C > B > A"}
+	if got := pathNames(view); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected %+v got %+v", expected, got)
+	}
+	// A category can't be its own parent.
+	a.ParentID = a.Meta.ID
+	if a.IsValid() {
+		t.Errorf("expected invalid got valid: %+v", a)
+	}
+}
+
+func TestCategoriesDestroy(t *testing.T) {
+	view := &categoriesView{}
+	s := categoryStorage{}
+	c := NewCategories(view, s)
+	if err := c.Create("Food", ""); err != nil {
+		t.Fatal(err)
+	}
+	food := idOf(t, view, "Food")
+	if err := c.Create("Dining out", food); err != nil {
+		t.Fatal(err)
+	}
+	diningOut := idOf(t, view, "Food > Dining out")
+	for _, name := range []string{"Lunch", "Dinner"} {
+		if err := c.Create(name, diningOut); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lunch := idOf(t, view, "Food > Dining out > Lunch")
+	if err := c.Create("Weekday", lunch); err != nil {
+		t.Fatal(err)
+	}
+
+	// The children are moved to the parent.
+	if err := c.Destroy(diningOut); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"Food",
+		"Food > Dinner",
+		"Food > Lunch",
+		"Food > Lunch > Weekday",
+	}
+	if got := pathNames(view); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected %+v got %+v", expected, got)
+	}
+	if got := s[lunch].ParentID; got != food {
+		t.Errorf("expected %s got %s", food, got)
+	}
+	if !s[diningOut].Meta.IsDeleted {
+		t.Errorf("expected deleted got %+v", s[diningOut])
+	}
+	if got := c.Path(diningOut); got != "" {
+		t.Errorf("expected an empty path got %s", got)
+	}
+
+	// The children of a root become roots.
+	if err := c.Destroy(food); err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{"Dinner", "Lunch", "Lunch > Weekday"}
+	if got := pathNames(view); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected %+v got %+v", expected, got)
+	}
+	if err := c.Destroy(food); err == nil {
+		t.Errorf("expected an error destroying a deleted category")
+	}
+}
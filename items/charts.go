@@ -0,0 +1,165 @@
+package items
+
+import (
+	"bytes"
+	"github.com/hajimehoshi/kakeibo/chart"
+	"github.com/hajimehoshi/kakeibo/currency"
+	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/money"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"math"
+	"strconv"
+)
+
+// DailyExpenses is the expenses of each day of a month in the base currency.
+type DailyExpenses struct {
+	// Month is the first day of the month.
+	Month date.Date
+	// Expenses[i] is the expenses on the (i+1)-th day of the month.
+	// Expenses might be shorter than the month, like when the month is the
+	// current month.
+	Expenses []money.Amount
+}
+
+// majorUnits returns the amount in the major unit of the currency, like
+// dollars for USD.
+func majorUnits(amount money.Amount, code currency.Code) float64 {
+	return float64(amount) / math.Pow10(code.MinorUnits())
+}
+
+// MonthlyExpenseChart returns an SVG bar chart of the expenses of the months.
+func MonthlyExpenseChart(
+	months []MonthTotals,
+	code currency.Code) (string, error) {
+	bars := make([]chart.Bar, len(months))
+	for k, m := range months {
+		bars[k] = chart.Bar{
+			Label: m.Month.Month().String()[:3],
+			Value: majorUnits(m.Totals.Expense, code),
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := chart.WriteBars(buf, "Expenses by month", bars); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// CategoryChart returns an SVG donut chart of the expenses of the categories.
+// totals should not include both a category and its subcategories.
+func CategoryChart(
+	totals []CategoryTotals,
+	code currency.Code) (string, error) {
+	slices := []chart.Slice{}
+	for _, t := range totals {
+		label := t.Path
+		if t.ID == "" {
+			label = "(Uncategorized)"
+		}
+		slices = append(slices, chart.Slice{
+			Label: label,
+			Value: majorUnits(t.Totals.Expense, code),
+		})
+	}
+	buf := &bytes.Buffer{}
+	err := chart.WriteDonut(buf, "Expenses by category", slices)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// CumulativeChart returns an SVG line chart of the cumulative expenses of the
+// months by day.
+func CumulativeChart(
+	months []DailyExpenses,
+	code currency.Code) (string, error) {
+	const days = 31
+	series := make([]chart.Series, len(months))
+	for k, m := range months {
+		values := make([]float64, len(m.Expenses))
+		sum := 0.0
+		for d, e := range m.Expenses {
+			sum += majorUnits(e, code)
+			values[d] = sum
+		}
+		label := m.Month.String()[:len("2006-01")]
+		series[k] = chart.Series{Label: label, Values: values}
+	}
+	labels := [2]string{"1", strconv.Itoa(days)}
+	buf := &bytes.Buffer{}
+	title := "Cumulative expenses"
+	err := chart.WriteLines(buf, title, days, labels, series)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// dailyExpenses returns the expenses of each day of the month of ym until
+// today.
+func (i *Items) dailyExpenses(ym date.Date) DailyExpenses {
+	today := date.Today()
+	end := ym.AddDate(0, 1, 0)
+	if today < end {
+		end = today.AddDate(0, 0, 1)
+	}
+	n := 0
+	if ym < end {
+		n = int(end - ym)
+	}
+	totals := make([]Totals, n)
+	for _, id := range i.itemsInMonth(ym) {
+		item := i.get(id)
+		d := item.Date.Day() - 1
+		if n <= d {
+			continue
+		}
+		i.addToTotals(&totals[d], item, item.Amount)
+	}
+	result := DailyExpenses{ym, make([]money.Amount, n)}
+	for d, t := range totals {
+		result.Expenses[d] = t.Expense
+	}
+	return result
+}
+
+// printMonthlyExpenseChart prints the chart of the expenses of the months.
+func (i *Items) printMonthlyExpenseChart(months []MonthTotals) {
+	svg, err := MonthlyExpenseChart(months, i.settings.BaseCurrency())
+	if err != nil {
+		svg = ""
+	}
+	i.view.PrintMonthlyExpenseChart(svg)
+}
+
+// printCategoryChart prints the chart of the expenses of the top-level
+// categories of the items.
+func (i *Items) printCategoryChart(ids []uuid.UUID) {
+	totals := []CategoryTotals{}
+	for _, t := range i.categoryTotals(ids) {
+		if t.ID != "" && len(i.categories.ancestors(t.ID)) != 1 {
+			continue
+		}
+		totals = append(totals, t)
+	}
+	svg, err := CategoryChart(totals, i.settings.BaseCurrency())
+	if err != nil {
+		svg = ""
+	}
+	i.view.PrintCategoryChart(svg)
+}
+
+// printCumulativeChart prints the chart of the cumulative expenses of the month
+// of ym and the previous month.
+func (i *Items) printCumulativeChart(ym date.Date) {
+	months := []DailyExpenses{
+		i.dailyExpenses(ym),
+		i.dailyExpenses(ym.AddDate(0, -1, 0)),
+	}
+	svg, err := CumulativeChart(months, i.settings.BaseCurrency())
+	if err != nil {
+		svg = ""
+	}
+	i.view.PrintCumulativeChart(svg)
+}
@@ -0,0 +1,37 @@
+package items_test
+
+import (
+	"flag"
+	"github.com/hajimehoshi/kakeibo/date"
+	. "github.com/hajimehoshi/kakeibo/items"
+	"github.com/hajimehoshi/kakeibo/money"
+	"io/ioutil"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestCumulativeChart(t *testing.T) {
+	months := []DailyExpenses{
+		{date.New(2015, 2, 1), []money.Amount{1000, 0, 2550}},
+		{date.New(2015, 1, 1), []money.Amount{0, 500, 500, 0, 1250}},
+	}
+	got, err := CumulativeChart(months, "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const path = "testdata/cumulative.svg"
+	if *update {
+		err := ioutil.WriteFile(path, []byte(got), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(expected) != got {
+		t.Errorf("%s: expected %s got %s", path, expected, got)
+	}
+}
@@ -0,0 +1,323 @@
+package items
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/hajimehoshi/kakeibo/currency"
+	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/money"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"io"
+	"strings"
+	"time"
+)
+
+// CSVSign is how a CSV file distinguishes expenses from incomes.
+type CSVSign int
+
+const (
+	// CSVSignNegativeExpense means negative amounts are expenses and
+	// positive amounts are incomes, like bank statements.
+	CSVSignNegativeExpense CSVSign = iota
+	// CSVSignPositiveExpense means positive amounts are expenses and
+	// negative amounts are incomes, like credit card statements.
+	CSVSignPositiveExpense
+	// CSVSignSeparateColumns means the amount column has withdrawals and
+	// the deposit column has deposits, like many Japanese banks.
+	CSVSignSeparateColumns
+)
+
+// CSVMapping is how the columns of a CSV file are mapped to items. Columns
+// are 0-based.
+type CSVMapping struct {
+	// Encoding is the character encoding of the file like 'shift_jis'. An
+	// empty Encoding means UTF-8.
+	Encoding string
+	// Comma is the field delimiter. A zero Comma means ','.
+	Comma rune
+	// HeaderRows is the number of rows to skip.
+	HeaderRows int
+	DateColumn int
+	// DateLayout is the layout of dates in the manner of time.Parse like
+	// '2006/01/02'.
+	DateLayout    string
+	SubjectColumn int
+	AmountColumn  int
+	Sign          CSVSign
+	// DepositColumn is used only for CSVSignSeparateColumns.
+	DepositColumn int
+	// AccountID is the account of the imported items. AccountID can be
+	// empty.
+	AccountID uuid.UUID
+	// Currency is the currency of the amounts. An empty Currency means
+	// currency.Default.
+	Currency currency.Code
+	// Locale is the decimal and group separators of the amounts like
+	// '1.234,50'. A zero Locale means money.DefaultLocale.
+	Locale money.Locale
+}
+
+func (m *CSVMapping) currency() currency.Code {
+	if m.Currency == "" {
+		return currency.Default
+	}
+	return m.Currency
+}
+
+func (m *CSVMapping) locale() money.Locale {
+	if m.Locale == (money.Locale{}) {
+		return money.DefaultLocale
+	}
+	return m.Locale
+}
+
+// ImportRow is a row of a CSV file to import.
+type ImportRow struct {
+	// Line is the 1-based line number in the file.
+	Line int
+	Item models.ItemData
+	// Duplicate is true when the item already exists, or when an existing
+	// item has the same date, amount, subject, direction and currency.
+	Duplicate bool
+	// Err is not nil when the row can't be imported.
+	Err error
+}
+
+func isUTF8(encoding string) bool {
+	switch strings.ToLower(encoding) {
+	case "", "utf-8", "utf8":
+		return true
+	}
+	return false
+}
+
+// parseCSVAmount parses an amount like '-1,234' or '¥1,234' formatted for the
+// locale to the minor unit of the currency. An empty string is 0.
+func parseCSVAmount(
+	str string,
+	code currency.Code,
+	locale money.Locale) (money.Amount, error) {
+	str = strings.TrimSpace(str)
+	for _, s := range []string{"¥", "￥", "円", "$", "€", "£", " "} {
+		str = strings.Replace(str, s, "", -1)
+	}
+	if str == "" {
+		return 0, nil
+	}
+	return money.ParseLocale(str, code, locale)
+}
+
+func csvColumn(record []string, column int) (string, error) {
+	if column < 0 || len(record) <= column {
+		return "", fmt.Errorf("items: column %d doesn't exist", column)
+	}
+	return strings.TrimSpace(record[column]), nil
+}
+
+func (m *CSVMapping) item(record []string) (*models.ItemData, error) {
+	str, err := csvColumn(record, m.DateColumn)
+	if err != nil {
+		return nil, err
+	}
+	t, err := time.Parse(m.DateLayout, str)
+	if err != nil {
+		return nil, err
+	}
+	subject, err := csvColumn(record, m.SubjectColumn)
+	if err != nil {
+		return nil, err
+	}
+	str, err = csvColumn(record, m.AmountColumn)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := parseCSVAmount(str, m.currency(), m.locale())
+	if err != nil {
+		return nil, err
+	}
+	switch m.Sign {
+	case CSVSignNegativeExpense:
+	case CSVSignPositiveExpense:
+		amount = -amount
+	case CSVSignSeparateColumns:
+		str, err := csvColumn(record, m.DepositColumn)
+		if err != nil {
+			return nil, err
+		}
+		deposit, err := parseCSVAmount(str, m.currency(), m.locale())
+		if err != nil {
+			return nil, err
+		}
+		amount, err = deposit.Sub(amount)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("items: invalid sign convention")
+	}
+	item := &models.ItemData{
+		Meta:      models.Meta{ID: uuid.Generate()},
+		Date:      date.New(t.Year(), t.Month(), t.Day()),
+		Subject:   subject,
+		Currency:  m.currency(),
+		Direction: models.DirectionIncome,
+		AccountID: m.AccountID,
+	}
+	if amount < 0 {
+		item.Direction = models.DirectionExpense
+		amount = -amount
+	}
+	item.Amount = amount
+	if !item.IsValid() {
+		return nil, errors.New("items: invalid item")
+	}
+	return item, nil
+}
+
+// ParseCSV parses a CSV file decoded to text by the mapping. A row which
+// can't be parsed is returned with its Err. Duplicates are not detected.
+func ParseCSV(text string, mapping CSVMapping) ([]ImportRow, error) {
+	text = strings.TrimPrefix(text, "\ufeff")
+	r := csv.NewReader(strings.NewReader(text))
+	if mapping.Comma != 0 {
+		r.Comma = mapping.Comma
+	}
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	rows := []ImportRow{}
+	for line := 1; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line <= mapping.HeaderRows {
+			continue
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		row := ImportRow{Line: line}
+		item, err := mapping.item(record)
+		if err != nil {
+			row.Err = err
+		} else {
+			row.Item = *item
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+type importKey struct {
+	date      date.Date
+	amount    money.Amount
+	subject   string
+	direction models.Direction
+	currency  currency.Code
+}
+
+func newImportKey(item *models.ItemData) importKey {
+	return importKey{
+		date:      item.Date,
+		amount:    item.Amount,
+		subject:   item.Subject,
+		direction: item.Direction,
+		currency:  item.CurrencyCode(),
+	}
+}
+
+// markDuplicates marks rows whose items already exist, including deleted
+// ones, and rows which have the same date, amount, subject, direction and
+// currency as existing items. Each existing item matches one row at most.
+func (i *Items) markDuplicates(rows []ImportRow) {
+	counts := map[importKey]int{}
+	for _, id := range i.allIDs() {
+		item := i.get(id)
+		counts[newImportKey(item)]++
+	}
+	for n := range rows {
+		row := &rows[n]
+		if row.Err != nil {
+			continue
+		}
+		item := &row.Item
+		if _, ok := i.items[item.Meta.ID]; ok {
+			row.Duplicate = true
+			continue
+		}
+		key := newImportKey(item)
+		if counts[key] == 0 {
+			continue
+		}
+		counts[key]--
+		row.Duplicate = true
+	}
+}
+
+func (i *Items) decodeText(data []byte, encoding string) (string, error) {
+	if isUTF8(encoding) {
+		return string(data), nil
+	}
+	return i.view.DecodeText(data, encoding)
+}
+
+// PreviewCSV parses a CSV file and prints the rows to import. The rows are
+// imported by Import.
+func (i *Items) PreviewCSV(data []byte, mapping CSVMapping) error {
+	text, err := i.decodeText(data, mapping.Encoding)
+	if err != nil {
+		return err
+	}
+	rows, err := ParseCSV(text, mapping)
+	if err != nil {
+		return err
+	}
+	i.markDuplicates(rows)
+	i.importRows = rows
+	i.view.PrintImportPreview(rows)
+	return nil
+}
+
+// Import saves the rows previewed by PreviewCSV or PreviewOFX. Rows marked as
+// duplicates are skipped unless includeDuplicates is true. Rows whose items
+// already exist are always skipped.
+func (i *Items) Import(includeDuplicates bool) error {
+	if i.importRows == nil {
+		return errors.New("Items.Import: nothing to import")
+	}
+	for n, row := range i.importRows {
+		if row.Err != nil {
+			continue
+		}
+		if row.Duplicate && !includeDuplicates {
+			continue
+		}
+		item := row.Item
+		if _, ok := i.items[item.Meta.ID]; ok {
+			continue
+		}
+		if err := i.saveItem(&item); err != nil {
+			// Keep the rows not saved yet so that they can be
+			// imported again.
+			i.importRows = i.importRows[n:]
+			i.view.PrintImportPreview(i.importRows)
+			return err
+		}
+		i.items[item.Meta.ID] = &item
+	}
+	i.CancelImport()
+	i.printItems()
+	i.printYearMonths()
+	return nil
+}
+
+// CancelImport discards the rows to import.
+func (i *Items) CancelImport() {
+	i.importRows = nil
+	i.view.PrintImportPreview(nil)
+}
@@ -0,0 +1,232 @@
+package items_test
+
+import (
+	"github.com/hajimehoshi/kakeibo/currency"
+	"github.com/hajimehoshi/kakeibo/date"
+	. "github.com/hajimehoshi/kakeibo/items"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/money"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"testing"
+)
+
+type importResult struct {
+	Line      int
+	Date      date.Date
+	Subject   string
+	Amount    money.Amount
+	Direction models.Direction
+	Err       bool
+}
+
+func TestParseCSV(t *testing.T) {
+	german := money.Locale{Decimal: ",", Group: "."}
+	tests := []struct {
+		Text     string
+		Mapping  CSVMapping
+		Expected []importResult
+	}{
+		{
+			"\ufeffDate,Description,Amount\n" +
+				"2015-01-05,Coffee,-350\n" +
+				"2015-01-25,Salary,\"250,000\"\n",
+			CSVMapping{
+				HeaderRows:    1,
+				DateColumn:    0,
+				DateLayout:    "2006-01-02",
+				SubjectColumn: 1,
+				AmountColumn:  2,
+				Sign:          CSVSignNegativeExpense,
+			},
+			[]importResult{
+				{2, date.New(2015, 1, 5), "Coffee", 350, models.DirectionExpense, false},
+				{3, date.New(2015, 1, 25), "Salary", 250000, models.DirectionIncome, false},
+			},
+		},
+		{
+			"01/05/2015,Book,1200\n" +
+				"01/06/2015,Refund,-300\n",
+			CSVMapping{
+				DateColumn:    0,
+				DateLayout:    "01/02/2006",
+				SubjectColumn: 1,
+				AmountColumn:  2,
+				Sign:          CSVSignPositiveExpense,
+			},
+			[]importResult{
+				{1, date.New(2015, 1, 5), "Book", 1200, models.DirectionExpense, false},
+				{2, date.New(2015, 1, 6), "Refund", 300, models.DirectionIncome, false},
+			},
+		},
+		{
+			"日付,摘要,お引出し,お預入れ\n" +
+				"2015/01/05,ATM,\"10,000\",\n" +
+				"2015/01/25,給与,,\"¥250,000\"\n" +
+				"2015/13/01,Invalid,1,\n",
+			CSVMapping{
+				HeaderRows:    1,
+				DateColumn:    0,
+				DateLayout:    "2006/01/02",
+				SubjectColumn: 1,
+				AmountColumn:  2,
+				Sign:          CSVSignSeparateColumns,
+				DepositColumn: 3,
+			},
+			[]importResult{
+				{2, date.New(2015, 1, 5), "ATM", 10000, models.DirectionExpense, false},
+				{3, date.New(2015, 1, 25), "給与", 250000, models.DirectionIncome, false},
+				{Line: 4, Err: true},
+			},
+		},
+		{
+			"2015-01-05,Museum,-12.50\n" +
+				"2015-01-06,Taxi,\"-$1,034.1\"\n" +
+				"2015-01-07,Invalid,-1.005\n",
+			CSVMapping{
+				DateColumn:    0,
+				DateLayout:    "2006-01-02",
+				SubjectColumn: 1,
+				AmountColumn:  2,
+				Sign:          CSVSignNegativeExpense,
+				Currency:      "USD",
+			},
+			[]importResult{
+				{1, date.New(2015, 1, 5), "Museum", 1250, models.DirectionExpense, false},
+				{2, date.New(2015, 1, 6), "Taxi", 103410, models.DirectionExpense, false},
+				{Line: 3, Err: true},
+			},
+		},
+		{
+			"2015-01-05;Miete;-1.234,50\n" +
+				"2015-01-06;Kaffee;\"-3,5 €\"\n" +
+				"2015-01-07;Invalid;-12.34\n",
+			CSVMapping{
+				Comma:         ';',
+				DateColumn:    0,
+				DateLayout:    "2006-01-02",
+				SubjectColumn: 1,
+				AmountColumn:  2,
+				Sign:          CSVSignNegativeExpense,
+				Currency:      "EUR",
+				Locale:        german,
+			},
+			[]importResult{
+				{1, date.New(2015, 1, 5), "Miete", 123450, models.DirectionExpense, false},
+				{2, date.New(2015, 1, 6), "Kaffee", 350, models.DirectionExpense, false},
+				{Line: 3, Err: true},
+			},
+		},
+	}
+	for _, test := range tests {
+		rows, err := ParseCSV(test.Text, test.Mapping)
+		if err != nil {
+			t.Errorf("ParseCSV error: %v", err)
+			continue
+		}
+		got := []importResult{}
+		for _, row := range rows {
+			if row.Err != nil {
+				got = append(got, importResult{Line: row.Line, Err: true})
+				continue
+			}
+			item := row.Item
+			if !item.IsValid() {
+				t.Errorf("expected valid item got %+v", item)
+			}
+			got = append(got, importResult{
+				row.Line,
+				item.Date,
+				item.Subject,
+				item.Amount,
+				item.Direction,
+				false,
+			})
+		}
+		if len(got) != len(test.Expected) {
+			t.Errorf("expected %+v got %+v", test.Expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.Expected[i] {
+				t.Errorf("expected %+v got %+v", test.Expected[i], got[i])
+			}
+		}
+	}
+}
+
+// importView is an ItemsView which records the previewed rows.
+type importView struct {
+	itemsView
+	rows []ImportRow
+}
+
+func (v *importView) PrintImportPreview(rows []ImportRow) {
+	v.rows = rows
+}
+
+func TestPreviewCSVDuplicates(t *testing.T) {
+	const (
+		expense = models.DirectionExpense
+		income  = models.DirectionIncome
+	)
+	// The CSV has an expense of 350 in the currency of the mapping.
+	const text = "2015-01-05,Coffee,-350\n"
+	tests := []struct {
+		Name string
+		// Amount, Direction and Currency are of the existing item.
+		Amount    money.Amount
+		Direction models.Direction
+		Currency  currency.Code
+		Mapping   currency.Code
+		Duplicate bool
+	}{
+		{"same", 350, expense, "", "", true},
+		{"default currency", 350, expense, "", "JPY", true},
+		{"same currency", 35000, expense, "USD", "USD", true},
+		{"income", 350, income, "", "", false},
+		{"other currency", 350, expense, "USD", "", false},
+		{"other mapping currency", 35000, expense, "", "USD", false},
+	}
+	for _, test := range tests {
+		view := &importView{}
+		s := &memoryStorage{values: map[uuid.UUID]*models.ItemData{}}
+		i := New(view, s,
+			NewCategories(nil, nil),
+			NewAccounts(nil, nil),
+			NewBudgets(nil),
+			NewRecurringItems(nil, nil),
+			NewExchangeRates(nil, nil),
+			NewSettings(nil, nil, ""),
+			NewAttachments(nil),
+			NewLedger(nil, nil, "", "foo@example.com"))
+		i.OnLoaded([]interface{}{
+			&models.ItemData{
+				Meta:      models.Meta{ID: uuid.Generate()},
+				Date:      date.New(2015, 1, 5),
+				Subject:   "Coffee",
+				Amount:    test.Amount,
+				Currency:  test.Currency,
+				Direction: test.Direction,
+			},
+		})
+		mapping := CSVMapping{
+			DateLayout:    "2006-01-02",
+			SubjectColumn: 1,
+			AmountColumn:  2,
+			Sign:          CSVSignNegativeExpense,
+			Currency:      test.Mapping,
+		}
+		if err := i.PreviewCSV([]byte(text), mapping); err != nil {
+			t.Fatal(err)
+		}
+		if len(view.rows) != 1 {
+			t.Errorf("%s: expected 1 row got %d",
+				test.Name, len(view.rows))
+			continue
+		}
+		if row := view.rows[0]; row.Duplicate != test.Duplicate {
+			t.Errorf("%s: expected %+v got %+v",
+				test.Name, test.Duplicate, row.Duplicate)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package items
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/hajimehoshi/kakeibo/currency"
+	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"io"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+type ExchangeRatesView interface {
+	PrintExchangeRates(rates []models.ExchangeRate)
+}
+
+type ExchangeRates struct {
+	rates   map[uuid.UUID]*models.ExchangeRate
+	view    ExchangeRatesView
+	storage Storage
+	// onChanged is called when exchange rates are added or removed.
+	onChanged func()
+}
+
+func NewExchangeRates(
+	view ExchangeRatesView,
+	storage Storage) *ExchangeRates {
+	return &ExchangeRates{
+		rates:   map[uuid.UUID]*models.ExchangeRate{},
+		view:    view,
+		storage: storage,
+	}
+}
+
+func (e *ExchangeRates) Type() reflect.Type {
+	return reflect.TypeOf((*models.ExchangeRate)(nil)).Elem()
+}
+
+func (e *ExchangeRates) OnLoaded(vals []interface{}) {
+	for _, v := range vals {
+		d, ok := v.(*models.ExchangeRate)
+		if !ok {
+			print("invalid data")
+			return
+		}
+		id := d.Meta.ID
+		if rate, ok := e.rates[id]; ok {
+			*rate = *d
+			continue
+		}
+		e.rates[id] = d
+	}
+	e.changed()
+}
+
+func (e *ExchangeRates) changed() {
+	if e.view != nil {
+		e.view.PrintExchangeRates(e.sorted())
+	}
+	if e.onChanged != nil {
+		e.onChanged()
+	}
+}
+
+type sortExchangeRates []models.ExchangeRate
+
+func (s sortExchangeRates) Len() int {
+	return len(s)
+}
+
+func (s sortExchangeRates) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s sortExchangeRates) Less(i, j int) bool {
+	if s[i].Date != s[j].Date {
+		return s[i].Date > s[j].Date
+	}
+	if s[i].From != s[j].From {
+		return s[i].From < s[j].From
+	}
+	if s[i].To != s[j].To {
+		return s[i].To < s[j].To
+	}
+	return s[i].Meta.ID < s[j].Meta.ID
+}
+
+// sorted returns the exchange rates which are not deleted, newest first.
+func (e *ExchangeRates) sorted() []models.ExchangeRate {
+	result := []models.ExchangeRate{}
+	for _, rate := range e.rates {
+		if rate.Meta.IsDeleted {
+			continue
+		}
+		result = append(result, *rate)
+	}
+	sort.Sort(sortExchangeRates(result))
+	return result
+}
+
+func (e *ExchangeRates) save(rate *models.ExchangeRate) error {
+	if !rate.IsValid() {
+		return errors.New("ExchangeRates.save: invalid data")
+	}
+	rate.Meta.LastUpdated = time.Time{}
+	if e.storage == nil {
+		return nil
+	}
+	err := e.storage.Save(rate) //gopherjs:blocking
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// find returns the exchange rate between the currencies on the date, or nil
+// if it doesn't exist.
+func (e *ExchangeRates) find(
+	d date.Date,
+	from currency.Code,
+	to currency.Code) *models.ExchangeRate {
+	for _, rate := range e.rates {
+		if rate.Meta.IsDeleted {
+			continue
+		}
+		if rate.Date == d && rate.From == from && rate.To == to {
+			return rate
+		}
+	}
+	return nil
+}
+
+// put creates an exchange rate, or updates the rate of the existing exchange
+// rate between the same currencies on the same date.
+func (e *ExchangeRates) put(rate models.ExchangeRate) error {
+	if existing := e.find(rate.Date, rate.From, rate.To); existing != nil {
+		if existing.Rate == rate.Rate {
+			return nil
+		}
+		updated := *existing
+		updated.Rate = rate.Rate
+		if err := e.save(&updated); err != nil {
+			return err
+		}
+		*existing = updated
+		return nil
+	}
+	rate.Meta = models.Meta{ID: uuid.Generate()}
+	if err := e.save(&rate); err != nil {
+		return err
+	}
+	e.rates[rate.Meta.ID] = &rate
+	return nil
+}
+
+// Create creates an exchange rate. rate is the value of one from in to like
+// '150.25' for USD to JPY. An exchange rate between the same currencies on the
+// same date is overwritten.
+func (e *ExchangeRates) Create(
+	d date.Date,
+	from currency.Code,
+	to currency.Code,
+	rate string) error {
+	r := models.ExchangeRate{
+		Date: d,
+		From: from,
+		To:   to,
+		Rate: strings.TrimSpace(rate),
+	}
+	if err := e.put(r); err != nil {
+		return err
+	}
+	e.changed()
+	return nil
+}
+
+func (e *ExchangeRates) Destroy(id uuid.UUID) error {
+	rate, ok := e.rates[id]
+	if !ok || rate.Meta.IsDeleted {
+		return errors.New("ExchangeRates.Destroy: exchange rate not found")
+	}
+	rate.Destroy()
+	if err := e.save(rate); err != nil {
+		return err
+	}
+	e.changed()
+	return nil
+}
+
+// ParseExchangeRatesCSV parses a CSV file of exchange rates. Each row has the
+// date in ISO 8601, the source currency, the target currency and the rate like
+// '2015-01-05,USD,JPY,120.5'. The first row is skipped if it is a header.
+func ParseExchangeRatesCSV(text string) ([]models.ExchangeRate, error) {
+	text = strings.TrimPrefix(text, "\ufeff")
+	r := csv.NewReader(strings.NewReader(text))
+	r.FieldsPerRecord = -1
+	rates := []models.ExchangeRate{}
+	for line := 1; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		if len(record) < 4 {
+			e := fmt.Sprintf("items: line %d: too few columns", line)
+			return nil, errors.New(e)
+		}
+		d, err := date.ParseISO8601(strings.TrimSpace(record[0]))
+		if err != nil {
+			if line == 1 {
+				// Header
+				continue
+			}
+			return nil, fmt.Errorf("items: line %d: %v", line, err)
+		}
+		rate := models.ExchangeRate{
+			// The ID is valid only for validation.
+			Meta: models.Meta{ID: uuid.Generate()},
+			Date: d,
+			From: currency.Code(strings.TrimSpace(record[1])),
+			To:   currency.Code(strings.TrimSpace(record[2])),
+			Rate: strings.TrimSpace(record[3]),
+		}
+		if !rate.IsValid() {
+			e := fmt.Sprintf("items: line %d: invalid exchange rate", line)
+			return nil, errors.New(e)
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+// ImportCSV imports a CSV file of exchange rates, and returns the number of
+// the rates. See ParseExchangeRatesCSV for the format. Existing exchange rates
+// between the same currencies on the same dates are overwritten, so importing
+// the same file again doesn't duplicate them.
+func (e *ExchangeRates) ImportCSV(data []byte) (int, error) {
+	rates, err := ParseExchangeRatesCSV(string(data))
+	if err != nil {
+		return 0, err
+	}
+	defer e.changed()
+	for n, rate := range rates {
+		if err := e.put(rate); err != nil {
+			return n, err
+		}
+	}
+	return len(rates), nil
+}
+
+// Rate returns the ratio to convert an amount from one currency to another on
+// the date. The latest exchange rate on or before the date is used, or the
+// earliest one after the date if there is none. An exchange rate of the
+// opposite direction is used inversely. Rate returns false if there is no
+// exchange rate between the currencies.
+func (e *ExchangeRates) Rate(
+	from currency.Code,
+	to currency.Code,
+	d date.Date) (*big.Rat, bool) {
+	if from == to {
+		return big.NewRat(1, 1), true
+	}
+	var before, after *models.ExchangeRate
+	better := func(a, b *models.ExchangeRate, later bool) bool {
+		if b == nil {
+			return true
+		}
+		if a.Date != b.Date {
+			return (a.Date > b.Date) == later
+		}
+		// Prefer the rate of the requested direction.
+		if (a.From == from) != (b.From == from) {
+			return a.From == from
+		}
+		return a.Meta.ID < b.Meta.ID
+	}
+	for _, rate := range e.rates {
+		if rate.Meta.IsDeleted {
+			continue
+		}
+		direct := rate.From == from && rate.To == to
+		inverse := rate.From == to && rate.To == from
+		if !direct && !inverse {
+			continue
+		}
+		if rate.Date <= d {
+			if better(rate, before, true) {
+				before = rate
+			}
+			continue
+		}
+		if better(rate, after, false) {
+			after = rate
+		}
+	}
+	rate := before
+	if rate == nil {
+		rate = after
+	}
+	if rate == nil {
+		return nil, false
+	}
+	if rate.From == from {
+		return rate.Ratio(), true
+	}
+	return rate.InverseRatio(), true
+}
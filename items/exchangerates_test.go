@@ -0,0 +1,98 @@
+package items_test
+
+import (
+	"github.com/hajimehoshi/kakeibo/currency"
+	"github.com/hajimehoshi/kakeibo/date"
+	. "github.com/hajimehoshi/kakeibo/items"
+	"math/big"
+	"testing"
+)
+
+func TestParseExchangeRatesCSV(t *testing.T) {
+	text := "Date,From,To,Rate\n" +
+		"2015-01-05,USD,JPY,120.5\n" +
+		"\n" +
+		"2015-01-06, EUR , JPY , 140 \n"
+	rates, err := ParseExchangeRatesCSV(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rates) != 2 {
+		t.Fatalf("expected 2 rates got %+v", rates)
+	}
+	r := rates[1]
+	if r.Date != date.New(2015, 1, 6) || r.From != "EUR" || r.To != "JPY" ||
+		r.Rate != "140" {
+		t.Errorf("unexpected rate: %+v", r)
+	}
+
+	for _, text := range []string{
+		"2015-01-05,USD,JPY\n",
+		"2015-01-05,USD,JPY,0\n",
+		"2015-01-05,USD,XXX,1\n",
+		"2015-01-05,USD,JPY,1\n2015/01/06,USD,JPY,1\n",
+	} {
+		if _, err := ParseExchangeRatesCSV(text); err == nil {
+			t.Errorf("expected an error for %q", text)
+		}
+	}
+}
+
+func TestExchangeRatesRate(t *testing.T) {
+	rates := NewExchangeRates(nil, nil)
+	creates := []struct {
+		Date date.Date
+		From currency.Code
+		To   currency.Code
+		Rate string
+	}{
+		{date.New(2015, 1, 5), "USD", "JPY", "120"},
+		{date.New(2015, 1, 10), "USD", "JPY", "125"},
+		{date.New(2015, 1, 10), "JPY", "USD", "0.01"},
+		{date.New(2015, 1, 7), "JPY", "EUR", "0.0070"},
+	}
+	for _, c := range creates {
+		if err := rates.Create(c.Date, c.From, c.To, c.Rate); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Creating a rate on the same date overwrites the existing one.
+	err := rates.Create(date.New(2015, 1, 5), "USD", "JPY", "121")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		From     currency.Code
+		To       currency.Code
+		Date     date.Date
+		Expected string
+		OK       bool
+	}{
+		{"USD", "JPY", date.New(2015, 1, 5), "121", true},
+		{"USD", "JPY", date.New(2015, 1, 9), "121", true},
+		// The rate of the requested direction is preferred.
+		{"USD", "JPY", date.New(2015, 1, 10), "125", true},
+		{"JPY", "USD", date.New(2015, 1, 10), "1/100", true},
+		{"JPY", "USD", date.New(2015, 1, 6), "1/121", true},
+		// The earliest rate is used for a date before any rates.
+		{"USD", "JPY", date.New(2015, 1, 1), "121", true},
+		{"EUR", "JPY", date.New(2015, 1, 7), "1000/7", true},
+		{"JPY", "JPY", date.New(2015, 1, 1), "1", true},
+		{"GBP", "JPY", date.New(2015, 1, 7), "", false},
+	}
+	for _, test := range tests {
+		got, ok := rates.Rate(test.From, test.To, test.Date)
+		if ok != test.OK {
+			t.Errorf("%+v: expected %+v got %+v", test, test.OK, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		expected, _ := new(big.Rat).SetString(test.Expected)
+		if got.Cmp(expected) != 0 {
+			t.Errorf("%+v: expected %+v got %+v", test, expected, got)
+		}
+	}
+}
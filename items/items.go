@@ -5,8 +5,11 @@ import (
 	"encoding/csv"
 	"errors"
 	"fmt"
+	"github.com/hajimehoshi/kakeibo/currency"
 	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/journal"
 	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/money"
 	"github.com/hajimehoshi/kakeibo/uuid"
 	"reflect"
 	"sort"
@@ -23,35 +26,224 @@ type ItemsView interface {
 	SetEditingItem(id uuid.UUID)
 	PrintTitle(title string)
 	PrintItems(ids []uuid.UUID)
-	PrintItemsAndTotal(ids []uuid.UUID, total int)
+	PrintItemsAndTotals(ids []uuid.UUID, totals Totals)
 	PrintItem(data models.ItemData)
+	// PrintAttachments prints the attachments of the item.
+	PrintAttachments(itemID uuid.UUID, attachments []models.Attachment)
 	PrintYearMonths([]date.Date)
+	// PrintTags prints all the tags of the items.
+	PrintTags(tags []string)
+	PrintCategoryTotals(totals []CategoryTotals)
+	// PrintMonthTotals prints the totals of each month, and the sums of the
+	// months' totals of the months and of the previous year. months is
+	// empty when the totals are not shown.
+	PrintMonthTotals(months []MonthTotals, total, previousTotal Totals)
+	// PrintMonthlyExpenseChart, PrintCategoryChart and PrintCumulativeChart
+	// print the charts as SVG images. svg is empty when the chart is not
+	// shown.
+	PrintMonthlyExpenseChart(svg string)
+	PrintCategoryChart(svg string)
+	PrintCumulativeChart(svg string)
+	PrintAccountBalances(balances []AccountBalance)
+	// PrintRunningBalances prints the balance of each item's account just
+	// after the item.
+	PrintRunningBalances(balances map[uuid.UUID]money.Amount)
+	// PrintConflicts notifies the user of items edited on both this client
+	// and another client.
+	PrintConflicts(conflicts []ItemConflict)
+	// PrintBudgets prints the progress of the budgets for the current month.
+	PrintBudgets(budgets []BudgetStatus)
+	// PrintBudgetWarnings notifies the user of budgets whose limits are
+	// exceeded.
+	PrintBudgetWarnings(budgets []BudgetStatus)
+	// PrintImportPreview prints the rows of a CSV file to import. rows is
+	// nil when there is nothing to import.
+	PrintImportPreview(rows []ImportRow)
+	// DecodeText decodes text in the given encoding other than UTF-8.
+	DecodeText(b []byte, encoding string) (string, error)
 	Download(b []byte, filename string)
 }
 
+// Totals is the sums of items' amounts in the base currency by their
+// directions. Transfers are counted in neither.
+type Totals struct {
+	Income  money.Amount
+	Expense money.Amount
+	// Unconverted is the number of the items which are not counted because
+	// there are no exchange rates for their currencies, or because the totals
+	// would overflow.
+	Unconverted int
+	// Counted is the number of the items counted in Income or Expense.
+	Counted int
+}
+
+func (t Totals) Net() money.Amount {
+	return t.Income - t.Expense
+}
+
+func (t *Totals) add(direction models.Direction, amount money.Amount) error {
+	var err error
+	switch direction {
+	case models.DirectionIncome:
+		t.Income, err = t.Income.Add(amount)
+	case models.DirectionExpense:
+		t.Expense, err = t.Expense.Add(amount)
+	}
+	if err != nil {
+		return err
+	}
+	t.Counted++
+	return nil
+}
+
+// CategoryTotals is the totals of a category including its descendants. The
+// ID and the Path are empty for uncategorized items.
+type CategoryTotals struct {
+	CategoryPath
+	Totals Totals
+}
+
+type sortCategoryTotals []CategoryTotals
+
+func (s sortCategoryTotals) Len() int {
+	return len(s)
+}
+
+func (s sortCategoryTotals) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s sortCategoryTotals) Less(i, j int) bool {
+	return s[i].Path < s[j].Path
+}
+
+// AccountBalance is the current balance of an account in the base currency.
+type AccountBalance struct {
+	ID      uuid.UUID
+	Name    string
+	Balance money.Amount
+}
+
+// BudgetStatus is the expenses of a month against a budget in the base
+// currency. Name is the category path or the subject pattern the budget
+// targets.
+type BudgetStatus struct {
+	ID    uuid.UUID
+	Name  string
+	Limit money.Amount
+	Spent money.Amount
+}
+
+// IsOver reports whether the expenses exceed the limit.
+func (b BudgetStatus) IsOver() bool {
+	return b.Limit < b.Spent
+}
+
+type sortBudgetStatuses []BudgetStatus
+
+func (s sortBudgetStatuses) Len() int {
+	return len(s)
+}
+
+func (s sortBudgetStatuses) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s sortBudgetStatuses) Less(i, j int) bool {
+	if s[i].Name != s[j].Name {
+		return s[i].Name < s[j].Name
+	}
+	return s[i].ID < s[j].ID
+}
+
+// ItemConflict is an item edited on both this client and another client.
+type ItemConflict struct {
+	Local  models.ItemData
+	Server models.ItemData
+	Result models.ItemData
+}
+
 type Mode int
 
 const (
 	ModeTop Mode = iota
 	ModeYearMonth
+	// ModeTag lists the items with a tag across months.
+	ModeTag
+	// ModeSearch lists the items matching a search query across months.
+	ModeSearch
+	// ModeYear shows the totals of each month of a year.
+	ModeYear
 )
 
 // TODO: Should this have 'mode'?
 type Items struct {
-	items       map[uuid.UUID]*models.ItemData
-	view        ItemsView
-	storage     Storage
-	mode        Mode
-	yearMonth   date.Date
+	items      map[uuid.UUID]*models.ItemData
+	view       ItemsView
+	storage    Storage
+	categories *Categories
+	accounts   *Accounts
+	budgets    *Budgets
+	recurring  *RecurringItems
+	rates      *ExchangeRates
+	settings   *Settings
+	mode       Mode
+	yearMonth  date.Date
+	// tag is the tag to list the items for ModeTag.
+	tag string
+	// query is the search query for ModeSearch, and queryString is its
+	// source.
+	query       *Query
+	queryString string
 	editingItem *models.ItemData
+	// editingIsNew is true when editingItem is not saved yet.
+	editingIsNew bool
+	// loaded is true after the items are loaded once. Recurring items are
+	// not materialized before that in order not to overwrite stored items.
+	loaded bool
+	// importRows is the rows of a CSV file previewed to import.
+	importRows []ImportRow
+	// attachments is the files attached to the items.
+	attachments *Attachments
+	// ledger is the ledger whose items are shown, or nil when the items are
+	// not edited.
+	ledger *Ledger
 }
 
-func New(view ItemsView, storage Storage) *Items {
+func New(
+	view ItemsView,
+	storage Storage,
+	categories *Categories,
+	accounts *Accounts,
+	budgets *Budgets,
+	recurring *RecurringItems,
+	rates *ExchangeRates,
+	settings *Settings,
+	attachments *Attachments,
+	ledger *Ledger) *Items {
 	items := &Items{
-		items:   map[uuid.UUID]*models.ItemData{},
-		view:    view,
-		storage: storage,
+		items:       map[uuid.UUID]*models.ItemData{},
+		view:        view,
+		storage:     storage,
+		categories:  categories,
+		accounts:    accounts,
+		budgets:     budgets,
+		recurring:   recurring,
+		rates:       rates,
+		settings:    settings,
+		attachments: attachments,
+		ledger:      ledger,
 	}
+	categories.onChanged = items.printItems
+	accounts.onChanged = items.printItems
+	budgets.onChanged = items.printItems
+	recurring.onChanged = items.onRecurringItemsChanged
+	rates.onChanged = items.printItems
+	settings.onChanged = items.onSettingsChanged
+	attachments.onChanged = items.onAttachmentsChanged
+	// The occurrences are materialized when the user turns out to be able
+	// to write the ledger's items.
+	ledger.onChanged = items.onRecurringItemsChanged
 	items.createEditingItem(date.Today())
 	return items
 }
@@ -75,16 +267,98 @@ func (i *Items) OnLoaded(vals []interface{}) {
 		}
 		i.items[id] = d
 	}
+	i.loaded = true
+	if err := i.materialize(); err != nil {
+		print(err.Error())
+	}
+	i.printYearMonths()
+	i.printTags()
+	i.printItems()
+}
+
+func (i *Items) onSettingsChanged() {
+	if i.editingIsNew {
+		i.editingItem.Currency = i.settings.BaseCurrency()
+		i.printItem(i.editingItem)
+	}
+	i.printItems()
+}
+
+func (i *Items) onAttachmentsChanged() {
+	i.printItem(i.editingItem)
+	i.printItems()
+}
+
+func (i *Items) onRecurringItemsChanged() {
+	if err := i.materialize(); err != nil {
+		print(err.Error())
+	}
 	i.printYearMonths()
+	i.printTags()
 	i.printItems()
 }
 
+// materialize creates the items of the recurring items' occurrences until
+// today. An occurrence whose item already exists is skipped. Deleted items are
+// kept in i.items as tombstones, so an occurrence deleted by the user is not
+// created again. As the item's ID is derived from the occurrence, clients
+// materializing the same occurrence create the same item.
+func (i *Items) materialize() error {
+	if !i.loaded {
+		return nil
+	}
+	// Viewers of a shared ledger can't write the items.
+	if i.ledger == nil || !i.ledger.CanWrite() {
+		return nil
+	}
+	today := date.Today()
+	for _, r := range i.recurring.sorted() {
+		for _, d := range r.Occurrences(today) {
+			id := r.OccurrenceID(d)
+			if _, ok := i.items[id]; ok {
+				continue
+			}
+			item := r.Item(d)
+			if err := i.saveItem(item); err != nil {
+				return err
+			}
+			i.items[id] = item
+		}
+	}
+	return nil
+}
+
+func (i *Items) OnConflicted(conflicts []models.Conflict) {
+	result := []ItemConflict{}
+	for _, c := range conflicts {
+		local, ok1 := c.Local.(*models.ItemData)
+		server, ok2 := c.Server.(*models.ItemData)
+		r, ok3 := c.Result.(*models.ItemData)
+		if !ok1 || !ok2 || !ok3 {
+			print("invalid data")
+			return
+		}
+		// An occurrence of a recurring item materialized on another
+		// client is not an edit by the user.
+		if local.RecurringID != "" && local.Meta.Revision == 0 {
+			continue
+		}
+		result = append(result, ItemConflict{*local, *server, *r})
+	}
+	if len(result) == 0 {
+		return
+	}
+	i.view.PrintConflicts(result)
+}
+
 func (i *Items) createEditingItem(date date.Date) error {
 	item := &models.ItemData{
-		Meta: models.Meta{ID: uuid.Generate()},
+		Meta:     models.Meta{ID: uuid.Generate()},
+		Currency: i.settings.BaseCurrency(),
 	}
 	item.Date = date
 	i.editingItem = item
+	i.editingIsNew = true
 	id := item.Meta.ID
 	i.items[id] = item
 	i.view.SetEditingItem(id)
@@ -99,10 +373,7 @@ func (i *Items) Print(id uuid.UUID) error {
 	if item == nil {
 		return errors.New("Items.Print: item not found")
 	}
-	if i.view == nil {
-		return nil
-	}
-	i.view.PrintItem(*item)
+	i.printItem(item)
 	return nil
 }
 
@@ -111,6 +382,8 @@ func (i *Items) printItem(item *models.ItemData) {
 		return
 	}
 	i.view.PrintItem(*item)
+	id := item.Meta.ID
+	i.view.PrintAttachments(id, i.attachments.forItem(id))
 }
 
 func (i *Items) saveItem(item *models.ItemData) error {
@@ -149,7 +422,7 @@ func (i *Items) UpdateSubject(id uuid.UUID, subject string) error {
 	return nil
 }
 
-func (i *Items) UpdateAmount(id uuid.UUID, amount int32) error {
+func (i *Items) UpdateAmount(id uuid.UUID, amount money.Amount) error {
 	item := i.get(id)
 	if item == nil {
 		return errors.New("Items.UpdateAmount: item not found")
@@ -159,6 +432,126 @@ func (i *Items) UpdateAmount(id uuid.UUID, amount int32) error {
 	return nil
 }
 
+func (i *Items) UpdateCurrency(id uuid.UUID, code currency.Code) error {
+	item := i.get(id)
+	if item == nil {
+		return errors.New("Items.UpdateCurrency: item not found")
+	}
+	if !code.IsValid() {
+		return errors.New("Items.UpdateCurrency: invalid currency")
+	}
+	item.Currency = code
+	i.printItem(item)
+	return nil
+}
+
+func (i *Items) UpdateDirection(id uuid.UUID, direction models.Direction) error {
+	item := i.get(id)
+	if item == nil {
+		return errors.New("Items.UpdateDirection: item not found")
+	}
+	item.Direction = direction
+	if direction != models.DirectionTransfer {
+		item.ToAccountID = ""
+	}
+	i.printItem(item)
+	return nil
+}
+
+func (i *Items) UpdateCategory(id uuid.UUID, categoryID uuid.UUID) error {
+	item := i.get(id)
+	if item == nil {
+		return errors.New("Items.UpdateCategory: item not found")
+	}
+	if categoryID != "" && len(item.Splits) != 0 {
+		return errors.New("Items.UpdateCategory: the item is split")
+	}
+	if categoryID != "" && i.categories.get(categoryID) == nil {
+		return errors.New("Items.UpdateCategory: category not found")
+	}
+	item.CategoryID = categoryID
+	i.printItem(item)
+	return nil
+}
+
+// UpdateSplits updates the splits of the item. An empty splits makes the item
+// not split. The category of the item is cleared when the item is split, as
+// the splits have their own categories.
+func (i *Items) UpdateSplits(id uuid.UUID, splits []models.Split) error {
+	item := i.get(id)
+	if item == nil {
+		return errors.New("Items.UpdateSplits: item not found")
+	}
+	for _, s := range splits {
+		if s.CategoryID != "" && i.categories.get(s.CategoryID) == nil {
+			return errors.New("Items.UpdateSplits: category not found")
+		}
+	}
+	if len(splits) == 0 {
+		item.Splits = nil
+	} else {
+		item.Splits = splits
+		item.CategoryID = ""
+	}
+	i.printItem(item)
+	return nil
+}
+
+// UpdateTags updates the tags of the item. See models.ParseTags to parse tags.
+func (i *Items) UpdateTags(id uuid.UUID, tags []string) error {
+	item := i.get(id)
+	if item == nil {
+		return errors.New("Items.UpdateTags: item not found")
+	}
+	for _, t := range tags {
+		if !models.IsValidTag(t) {
+			return errors.New("Items.UpdateTags: invalid tag: " + t)
+		}
+	}
+	if len(tags) == 0 {
+		tags = nil
+	}
+	item.Tags = tags
+	i.printItem(item)
+	return nil
+}
+
+func (i *Items) UpdateNote(id uuid.UUID, note string) error {
+	item := i.get(id)
+	if item == nil {
+		return errors.New("Items.UpdateNote: item not found")
+	}
+	item.Note = note
+	i.printItem(item)
+	return nil
+}
+
+func (i *Items) UpdateAccount(id uuid.UUID, accountID uuid.UUID) error {
+	item := i.get(id)
+	if item == nil {
+		return errors.New("Items.UpdateAccount: item not found")
+	}
+	if accountID != "" && i.accounts.get(accountID) == nil {
+		return errors.New("Items.UpdateAccount: account not found")
+	}
+	item.AccountID = accountID
+	i.printItem(item)
+	return nil
+}
+
+func (i *Items) UpdateToAccount(id uuid.UUID, accountID uuid.UUID) error {
+	item := i.get(id)
+	if item == nil {
+		return errors.New("Items.UpdateToAccount: item not found")
+	}
+	if accountID != "" && i.accounts.get(accountID) == nil {
+		return errors.New("Items.UpdateToAccount: account not found")
+	}
+	item.ToAccountID = accountID
+	i.printItem(item)
+	return nil
+}
+
 func (i *Items) Save(id uuid.UUID) error {
 	item := i.get(id)
 	if item == nil {
@@ -168,13 +561,40 @@ func (i *Items) Save(id uuid.UUID) error {
 		return err
 	}
 	if i.editingItem == item {
+		i.editingIsNew = false
 		i.createEditingItem(item.Date)
 	}
 	i.printItems()
 	i.printYearMonths()
+	i.printTags()
+	return nil
+}
+
+// isDraft reports whether the item is the editing item which is not saved yet.
+func (i *Items) isDraft(item *models.ItemData) bool {
+	return item == i.editingItem && i.editingIsNew
+}
+
+// Edit makes the existing item the editing item. For example, this is used to
+// edit an occurrence of a recurring item.
+func (i *Items) Edit(id uuid.UUID) error {
+	item := i.get(id)
+	if item == nil || item == i.editingItem {
+		return errors.New("Items.Edit: item not found")
+	}
+	if i.editingIsNew {
+		delete(i.items, i.editingItem.Meta.ID)
+	}
+	i.editingItem = item
+	i.editingIsNew = false
+	i.view.SetEditingItem(id)
+	i.printItem(item)
+	i.printItems()
 	return nil
 }
 
+// Destroy destroys the item. Destroying an occurrence of a recurring item
+// skips the occurrence.
 func (i *Items) Destroy(id uuid.UUID) error {
 	item := i.get(id)
 	if item == nil {
@@ -184,12 +604,36 @@ func (i *Items) Destroy(id uuid.UUID) error {
 	if err := i.saveItem(item); err != nil {
 		return err
 	}
+	// Attachments are deleted with the item. The server also deletes them
+	// when it receives the item's tombstone.
+	if err := i.attachments.destroyItem(id); err != nil {
+		return err
+	}
 	i.printItem(item)
+	if i.editingItem == item {
+		i.createEditingItem(date.Today())
+	}
 	i.printItems()
 	i.printYearMonths()
+	i.printTags()
 	return nil
 }
 
+// Attach attaches a file to the item. The content must be stored by its hash
+// beforehand. Files can't be attached to an item which is not saved yet.
+func (i *Items) Attach(
+	id uuid.UUID,
+	name string,
+	contentType string,
+	size int64,
+	hash string) error {
+	item := i.get(id)
+	if item == nil || i.isDraft(item) {
+		return errors.New("Items.Attach: item not found")
+	}
+	return i.attachments.create(id, name, contentType, size, hash)
+}
+
 func (i *Items) title() string {
 	switch i.mode {
 	case ModeTop:
@@ -197,6 +641,12 @@ func (i *Items) title() string {
 	case ModeYearMonth:
 		ym := i.yearMonth
 		return fmt.Sprintf("%04d-%02d", ym.Year(), ym.Month())
+	case ModeTag:
+		return "Tag: " + i.tag
+	case ModeSearch:
+		return "Search: " + i.queryString
+	case ModeYear:
+		return fmt.Sprintf("%04d", i.yearMonth.Year())
 	}
 	panic("not reach")
 }
@@ -208,13 +658,96 @@ func (i *Items) UpdateMode(mode Mode, ym date.Date) {
 	i.printItems()
 }
 
+// UpdateTagMode lists the items with the tag.
+func (i *Items) UpdateTagMode(tag string) {
+	i.mode = ModeTag
+	i.tag = tag
+	i.view.PrintTitle(i.title())
+	i.printItems()
+}
+
+// UpdateSearchMode lists the items matching the search query. See Query for
+// the syntax.
+func (i *Items) UpdateSearchMode(query string) error {
+	q, err := ParseQuery(query)
+	if err != nil {
+		return err
+	}
+	i.mode = ModeSearch
+	i.query = q
+	i.queryString = query
+	i.view.PrintTitle(i.title())
+	i.printItems()
+	return nil
+}
+
 func (i *Items) printItems() {
+	if i.mode != ModeTop && i.mode != ModeYear {
+		i.view.PrintMonthTotals([]MonthTotals{}, Totals{}, Totals{})
+		i.view.PrintMonthlyExpenseChart("")
+	}
+	if i.mode != ModeTop && i.mode != ModeYearMonth {
+		i.view.PrintCumulativeChart("")
+	}
 	switch i.mode {
 	case ModeTop:
-		i.printNoItems()
+		i.printDashboard()
+	case ModeYear:
+		i.printYear()
 	case ModeYearMonth:
 		i.printYearMonthItems()
+	case ModeTag:
+		i.printMatchingItems(func(item *models.ItemData) bool {
+			return item.HasTag(i.tag)
+		})
+	case ModeSearch:
+		i.printMatchingItems(func(item *models.ItemData) bool {
+			return i.query.Match(item, i.categoryNames)
+		})
+	}
+	_, balances := i.balances()
+	result := []AccountBalance{}
+	for _, account := range i.accounts.sorted() {
+		id := account.Meta.ID
+		b := AccountBalance{id, account.Name, balances[id]}
+		result = append(result, b)
 	}
+	i.view.PrintAccountBalances(result)
+}
+
+// balances returns the balance of each item's account just after the item, and
+// the current balance of each account.
+func (i *Items) balances() (running, current map[uuid.UUID]money.Amount) {
+	running = map[uuid.UUID]money.Amount{}
+	current = map[uuid.UUID]money.Amount{}
+	for id, account := range i.accounts.accounts {
+		if account.Meta.IsDeleted {
+			continue
+		}
+		current[id] = account.OpeningBalance
+	}
+	for _, id := range i.allIDs() {
+		item := i.get(id)
+		aids := []uuid.UUID{item.AccountID, item.ToAccountID}
+		for _, aid := range aids {
+			if _, ok := current[aid]; !ok {
+				continue
+			}
+			change, ok := i.toBase(item.BalanceChange(aid), item)
+			if !ok {
+				continue
+			}
+			b, err := current[aid].Add(change)
+			if err != nil {
+				continue
+			}
+			current[aid] = b
+		}
+		if b, ok := current[item.AccountID]; ok {
+			running[id] = b
+		}
+	}
+	return
 }
 
 type sortItemsByDate struct {
@@ -242,33 +775,210 @@ func (s sortItemsByDate) Less(i, j int) bool {
 	return i1.Amount < i2.Amount
 }
 
-func (i *Items) printNoItems() {
-	i.view.PrintItems([]uuid.UUID{})
+// itemsInMonth returns the IDs of the saved items in the month of ym sorted by
+// date.
+func (i *Items) itemsInMonth(ym date.Date) []uuid.UUID {
+	ids := []uuid.UUID{}
+	for _, id := range i.allIDs() {
+		d := i.get(id).Date
+		if d.Year() != ym.Year() || d.Month() != ym.Month() {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
 }
 
 func (i *Items) printYearMonthItems() {
-	ym := i.yearMonth
+	ids := i.itemsInMonth(i.yearMonth)
+	totals := Totals{}
+	for _, id := range ids {
+		item := i.get(id)
+		i.addToTotals(&totals, item, item.Amount)
+	}
+	i.view.PrintItemsAndTotals(ids, totals)
+	for _, id := range ids {
+		i.printItem(i.get(id))
+	}
+	i.view.PrintCategoryTotals(i.categoryTotals(ids))
+	i.printCategoryChart(ids)
+	i.printCumulativeChart(i.yearMonth)
+	running, _ := i.balances()
+	i.view.PrintRunningBalances(running)
+	i.printBudgets(i.yearMonth, ids)
+}
+
+// printBudgets prints the budgets for the month of ym against the items.
+func (i *Items) printBudgets(ym date.Date, ids []uuid.UUID) {
+	budgets := i.budgetStatuses(ym, ids)
+	over := []BudgetStatus{}
+	for _, b := range budgets {
+		if b.IsOver() {
+			over = append(over, b)
+		}
+	}
+	i.view.PrintBudgets(budgets)
+	i.view.PrintBudgetWarnings(over)
+}
+
+// printMatchingItems prints the items of all months which match. Budgets are
+// not printed as they are for months.
+func (i *Items) printMatchingItems(match func(item *models.ItemData) bool) {
 	ids := []uuid.UUID{}
-	total := 0
-	for _, item := range i.items {
-		if item.Meta.IsDeleted {
+	totals := Totals{}
+	for _, id := range i.allIDs() {
+		item := i.get(id)
+		if !match(item) {
 			continue
 		}
-		if item == i.editingItem {
-			continue
+		ids = append(ids, id)
+		i.addToTotals(&totals, item, item.Amount)
+	}
+	i.view.PrintItemsAndTotals(ids, totals)
+	for _, id := range ids {
+		i.printItem(i.get(id))
+	}
+	i.view.PrintCategoryTotals(i.categoryTotals(ids))
+	i.view.PrintCategoryChart("")
+	running, _ := i.balances()
+	i.view.PrintRunningBalances(running)
+	i.view.PrintBudgets([]BudgetStatus{})
+	i.view.PrintBudgetWarnings([]BudgetStatus{})
+}
+
+// budgetStatuses returns the expenses of the items against the budgets for the
+// month of ym.
+func (i *Items) budgetStatuses(ym date.Date, ids []uuid.UUID) []BudgetStatus {
+	result := []BudgetStatus{}
+	for _, budget := range i.budgets.forMonth(ym) {
+		name := budget.SubjectPattern
+		if budget.CategoryID != "" {
+			name = i.categories.Path(budget.CategoryID)
 		}
-		d := item.Date
-		if d.Year() != ym.Year() || d.Month() != ym.Month() {
-			continue
+		status := BudgetStatus{
+			ID:    budget.Meta.ID,
+			Name:  name,
+			Limit: budget.Limit,
 		}
-		ids = append(ids, item.Meta.ID)
-		total += int(item.Amount)
+		for _, id := range ids {
+			item := i.get(id)
+			if item.Direction != models.DirectionExpense {
+				continue
+			}
+			amount := i.budgetAmount(budget, item)
+			if amount == 0 {
+				continue
+			}
+			amount, ok := i.toBase(amount, item)
+			if !ok {
+				continue
+			}
+			spent, err := status.Spent.Add(amount)
+			if err != nil {
+				continue
+			}
+			status.Spent = spent
+		}
+		result = append(result, status)
 	}
-	s := sortItemsByDate{i, ids}
-	sort.Sort(s)
-	i.view.PrintItemsAndTotal(ids, total)
+	sort.Sort(sortBudgetStatuses(result))
+	return result
+}
+
+// budgetAmount returns the amount of the item which the budget targets in the
+// item's currency. Only the splits in the budget's category are counted for a
+// split item.
+func (i *Items) budgetAmount(
+	budget *models.Budget,
+	item *models.ItemData) money.Amount {
+	if budget.CategoryID == "" {
+		if budget.MatchesSubject(item.Subject) {
+			return item.Amount
+		}
+		return 0
+	}
+	result := money.Amount(0)
+	for _, part := range item.Parts() {
+		for _, cid := range i.categories.ancestors(part.CategoryID) {
+			if cid != budget.CategoryID {
+				continue
+			}
+			// A part which would overflow is not counted.
+			if r, err := result.Add(part.Amount); err == nil {
+				result = r
+			}
+			break
+		}
+	}
+	return result
+}
+
+func (i *Items) categoryTotals(ids []uuid.UUID) []CategoryTotals {
+	totals := map[uuid.UUID]*Totals{}
 	for _, id := range ids {
-		i.printItem(i.get(id))
+		item := i.get(id)
+		// Each split is counted in its own category.
+		for _, part := range item.Parts() {
+			cids := i.categories.ancestors(part.CategoryID)
+			if len(cids) == 0 {
+				// Uncategorized
+				cids = []uuid.UUID{""}
+			}
+			for _, cid := range cids {
+				if _, ok := totals[cid]; !ok {
+					totals[cid] = &Totals{}
+				}
+				i.addToTotals(totals[cid], item, part.Amount)
+			}
+		}
+	}
+	result := make([]CategoryTotals, 0, len(totals))
+	for cid, t := range totals {
+		path := CategoryPath{cid, i.categories.Path(cid)}
+		result = append(result, CategoryTotals{path, *t})
+	}
+	sort.Sort(sortCategoryTotals(result))
+	return result
+}
+
+// toBase converts an amount in the item's currency to the base currency by the
+// exchange rate on the item's date. toBase returns false if there is no
+// exchange rate.
+func (i *Items) toBase(
+	amount money.Amount,
+	item *models.ItemData) (money.Amount, bool) {
+	from := item.CurrencyCode()
+	to := i.settings.BaseCurrency()
+	if from == to {
+		return amount, true
+	}
+	rate, ok := i.rates.Rate(from, to, item.Date)
+	if !ok {
+		return 0, false
+	}
+	result, err := amount.Convert(from, to, rate)
+	if err != nil {
+		return 0, false
+	}
+	return result, true
+}
+
+// addToTotals adds the amount, which is the whole or a part of the item, to the
+// totals.
+func (i *Items) addToTotals(
+	totals *Totals,
+	item *models.ItemData,
+	amount money.Amount) {
+	if item.Direction == models.DirectionTransfer {
+		return
+	}
+	amount, ok := i.toBase(amount, item)
+	if !ok {
+		totals.Unconverted++
+		return
+	}
+	if err := totals.add(item.Direction, amount); err != nil {
+		totals.Unconverted++
 	}
 }
 
@@ -299,7 +1009,7 @@ func (i *Items) printYearMonths() {
 		if item.Meta.IsDeleted {
 			continue
 		}
-		if item == i.editingItem {
+		if i.isDraft(item) {
 			continue
 		}
 		d := item.Date
@@ -317,29 +1027,112 @@ func (i *Items) printYearMonths() {
 	i.view.PrintYearMonths(result)
 }
 
-func (i *Items) DownloadCSV() error {
-	// TODO: Refactoring
+func (i *Items) printTags() {
+	tags := map[string]struct{}{}
+	for _, id := range i.allIDs() {
+		for _, t := range i.get(id).Tags {
+			tags[t] = struct{}{}
+		}
+	}
+	result := make([]string, 0, len(tags))
+	for t := range tags {
+		result = append(result, t)
+	}
+	sort.Strings(result)
+	i.view.PrintTags(result)
+}
+
+// allIDs returns the IDs of all the saved items sorted by date.
+func (i *Items) allIDs() []uuid.UUID {
 	ids := []uuid.UUID{}
 	for _, item := range i.items {
 		if item.Meta.IsDeleted {
 			continue
 		}
-		if item == i.editingItem {
+		if i.isDraft(item) {
 			continue
 		}
 		ids = append(ids, item.Meta.ID)
 	}
 	s := sortItemsByDate{i, ids}
 	sort.Sort(s)
+	return ids
+}
 
+func (i *Items) DownloadCSV() error {
+	ids := i.allIDs()
 	buf := &bytes.Buffer{}
 	w := csv.NewWriter(buf)
 	for _, id := range ids {
 		item := i.get(id)
 		r := item.CSVRecord()
-		w.Write(r)
+		w.Write(append(r, i.categories.Path(item.CategoryID)))
+		// The splits are expanded under the item.
+		for n, r := range item.SplitCSVRecords() {
+			path := i.categories.Path(item.Splits[n].CategoryID)
+			w.Write(append(r, path))
+		}
 	}
 	w.Flush()
 	i.view.Download(buf.Bytes(), "kakeibo.csv")
 	return nil
 }
+
+// categoryNames returns the names of the category and its ancestors from the
+// root.
+func (i *Items) categoryNames(id uuid.UUID) []string {
+	ids := i.categories.ancestors(id)
+	names := make([]string, len(ids))
+	for n, id := range ids {
+		names[len(ids)-1-n] = i.categories.get(id).Name
+	}
+	return names
+}
+
+func (i *Items) accountName(id uuid.UUID) string {
+	account := i.accounts.get(id)
+	if account == nil {
+		return ""
+	}
+	return account.Name
+}
+
+func (i *Items) journalSplits(item *models.ItemData) []journal.Split {
+	if len(item.Splits) == 0 {
+		return nil
+	}
+	result := []journal.Split{}
+	for _, s := range item.Splits {
+		result = append(result, journal.Split{
+			Category: i.categoryNames(s.CategoryID),
+			Amount:   s.Amount,
+		})
+	}
+	return result
+}
+
+// DownloadJournal downloads the items as a plain-text accounting journal.
+func (i *Items) DownloadJournal(format journal.Format) error {
+	entries := []journal.Entry{}
+	for _, id := range i.allIDs() {
+		item := i.get(id)
+		entries = append(entries, journal.Entry{
+			Date:      item.Date,
+			Subject:   item.Subject,
+			Amount:    item.Amount,
+			Commodity: item.CurrencyCode(),
+			Direction: item.Direction,
+			Category:  i.categoryNames(item.CategoryID),
+			Account:   i.accountName(item.AccountID),
+			ToAccount: i.accountName(item.ToAccountID),
+			Splits:    i.journalSplits(item),
+		})
+	}
+	buf := &bytes.Buffer{}
+	err := journal.Write(buf, format, entries, i.settings.BaseCurrency())
+	if err != nil {
+		return err
+	}
+	i.view.Download(buf.Bytes(), "kakeibo"+format.Extension())
+	return nil
+}
@@ -0,0 +1,181 @@
+package items_test
+
+import (
+	"github.com/hajimehoshi/kakeibo/date"
+	. "github.com/hajimehoshi/kakeibo/items"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/money"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"testing"
+)
+
+// itemsView is an ItemsView which prints nothing.
+type itemsView struct{}
+
+func (v *itemsView) SetEditingItem(id uuid.UUID) {}
+
+func (v *itemsView) PrintTitle(title string) {}
+
+func (v *itemsView) PrintItems(ids []uuid.UUID) {}
+
+func (v *itemsView) PrintItemsAndTotals(ids []uuid.UUID, totals Totals) {}
+
+func (v *itemsView) PrintItem(data models.ItemData) {}
+
+func (v *itemsView) PrintAttachments(
+	itemID uuid.UUID,
+	attachments []models.Attachment) {
+}
+
+func (v *itemsView) PrintYearMonths([]date.Date) {}
+
+func (v *itemsView) PrintTags(tags []string) {}
+
+func (v *itemsView) PrintCategoryTotals(totals []CategoryTotals) {}
+
+func (v *itemsView) PrintMonthTotals(
+	months []MonthTotals,
+	total, previousTotal Totals) {
+}
+
+func (v *itemsView) PrintMonthlyExpenseChart(svg string) {}
+
+func (v *itemsView) PrintCategoryChart(svg string) {}
+
+func (v *itemsView) PrintCumulativeChart(svg string) {}
+
+func (v *itemsView) PrintAccountBalances(balances []AccountBalance) {}
+
+func (v *itemsView) PrintRunningBalances(
+	balances map[uuid.UUID]money.Amount) {
+}
+
+func (v *itemsView) PrintConflicts(conflicts []ItemConflict) {}
+
+func (v *itemsView) PrintBudgets(budgets []BudgetStatus) {}
+
+func (v *itemsView) PrintBudgetWarnings(budgets []BudgetStatus) {}
+
+func (v *itemsView) PrintImportPreview(rows []ImportRow) {}
+
+func (v *itemsView) DecodeText(b []byte, encoding string) (string, error) {
+	return string(b), nil
+}
+
+func (v *itemsView) Download(b []byte, filename string) {}
+
+// memoryStorage keeps the last saved value of each ID like the IndexedDB.
+type memoryStorage struct {
+	values map[uuid.UUID]*models.ItemData
+}
+
+func (s *memoryStorage) Save(v interface{}) error {
+	item := *v.(*models.ItemData)
+	s.values[item.Meta.ID] = &item
+	return nil
+}
+
+func (s *memoryStorage) all() []interface{} {
+	result := []interface{}{}
+	for _, v := range s.values {
+		item := *v
+		result = append(result, &item)
+	}
+	return result
+}
+
+func newItems(
+	s *memoryStorage,
+	ledger *Ledger,
+	recurring []*models.RecurringItem) *Items {
+	r := NewRecurringItems(nil, nil)
+	values := []interface{}{}
+	for _, v := range recurring {
+		values = append(values, v)
+	}
+	r.OnLoaded(values)
+	return New(&itemsView{}, s,
+		NewCategories(nil, nil),
+		NewAccounts(nil, nil),
+		NewBudgets(nil),
+		r,
+		NewExchangeRates(nil, nil),
+		NewSettings(nil, nil, ""),
+		NewAttachments(nil),
+		ledger)
+}
+
+func newRecurringItem() *models.RecurringItem {
+	return &models.RecurringItem{
+		Meta:       models.Meta{ID: uuid.Generate()},
+		Subject:    "Rent",
+		Amount:     80000,
+		Recurrence: models.RecurrenceMonthly,
+		Day:        1,
+		Start:      date.Today().AddDate(0, -3, 0),
+	}
+}
+
+func TestDeletedOccurrence(t *testing.T) {
+	const user = "foo@example.com"
+	today := date.Today()
+	r := newRecurringItem()
+	recurring := []*models.RecurringItem{r}
+	s := &memoryStorage{values: map[uuid.UUID]*models.ItemData{}}
+	i := newItems(s, NewLedger(nil, nil, "", user), recurring)
+	i.OnLoaded(nil)
+	occurrences := r.Occurrences(today)
+	n := len(occurrences)
+	if len(s.values) != n {
+		t.Fatalf("expected %d items got %d", n, len(s.values))
+	}
+	id := r.OccurrenceID(occurrences[0])
+	if err := i.Destroy(id); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reload the stored items including the tombstone.
+	i = newItems(s, NewLedger(nil, nil, "", user), recurring)
+	i.OnLoaded(s.all())
+	if item := s.values[id]; !item.Meta.IsDeleted {
+		t.Errorf("expected deleted got %+v", item)
+	}
+	if len(s.values) != n {
+		t.Errorf("expected %d items got %d", n, len(s.values))
+	}
+}
+
+func TestMaterializeReadOnly(t *testing.T) {
+	const user = "foo@example.com"
+	id := uuid.Generate()
+	r := newRecurringItem()
+	s := &memoryStorage{values: map[uuid.UUID]*models.ItemData{}}
+	l := NewLedger(nil, nil, id, user)
+	i := newItems(s, l, []*models.RecurringItem{r})
+	i.OnLoaded(nil)
+	if len(s.values) != 0 {
+		t.Errorf("expected no items before the ledger is loaded got %d",
+			len(s.values))
+	}
+	ledger := &models.Ledger{
+		Meta: models.Meta{ID: id},
+		Name: "Home",
+		Members: []models.Member{
+			{Email: "bar@example.com", Role: models.RoleOwner},
+			{Email: user, Role: models.RoleViewer},
+		},
+	}
+	l.OnLoaded([]interface{}{ledger})
+	if len(s.values) != 0 {
+		t.Errorf("expected no items for a viewer got %d", len(s.values))
+	}
+	editor := *ledger
+	editor.Members = []models.Member{
+		{Email: "bar@example.com", Role: models.RoleOwner},
+		{Email: user, Role: models.RoleEditor},
+	}
+	l.OnLoaded([]interface{}{&editor})
+	if n := len(r.Occurrences(date.Today())); len(s.values) != n {
+		t.Errorf("expected %d items got %d", n, len(s.values))
+	}
+}
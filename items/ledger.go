@@ -0,0 +1,161 @@
+package items
+
+import (
+	"errors"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"reflect"
+	"time"
+)
+
+type LedgerView interface {
+	// PrintLedger prints the ledger and the user's role in it. ledger is
+	// nil when the user's own values are shown.
+	PrintLedger(ledger *models.Ledger, role models.Role)
+}
+
+// Ledger is the shared ledger which is shown. Only its owners can edit its
+// members.
+type Ledger struct {
+	// id is the ID of the ledger, or empty for the user's own values.
+	id      uuid.UUID
+	ledger  *models.Ledger
+	view    LedgerView
+	storage Storage
+	// user is the user's email address.
+	user string
+	// onChanged is called when the ledger is loaded or edited.
+	onChanged func()
+}
+
+func NewLedger(
+	view LedgerView,
+	storage Storage,
+	id uuid.UUID,
+	user string) *Ledger {
+	return &Ledger{
+		id:      id,
+		view:    view,
+		storage: storage,
+		user:    user,
+	}
+}
+
+func (l *Ledger) Type() reflect.Type {
+	return reflect.TypeOf((*models.Ledger)(nil)).Elem()
+}
+
+func (l *Ledger) OnLoaded(vals []interface{}) {
+	for _, v := range vals {
+		d, ok := v.(*models.Ledger)
+		if !ok {
+			print("invalid data")
+			return
+		}
+		// Ledgers of other IDs are ignored.
+		if l.id == "" || d.Meta.ID != l.id {
+			continue
+		}
+		l.ledger = d
+	}
+	l.changed()
+}
+
+func (l *Ledger) changed() {
+	if l.onChanged != nil {
+		l.onChanged()
+	}
+	if l.view == nil {
+		return
+	}
+	if l.ledger == nil {
+		l.view.PrintLedger(nil, models.RoleViewer)
+		return
+	}
+	role, _ := l.ledger.RoleOf(l.user)
+	ledger := *l.ledger
+	l.view.PrintLedger(&ledger, role)
+}
+
+// CanWrite reports whether the user can write the values. The user can always
+// write their own values, but can't write a shared ledger's values before the
+// ledger is loaded.
+func (l *Ledger) CanWrite() bool {
+	if l.id == "" {
+		return true
+	}
+	if l.ledger == nil {
+		return false
+	}
+	role, _ := l.ledger.RoleOf(l.user)
+	return role.CanWrite()
+}
+
+func (l *Ledger) save(ledger *models.Ledger) error {
+	if !ledger.IsValid() {
+		return errors.New("Ledger.save: invalid data")
+	}
+	ledger.Meta.LastUpdated = time.Time{}
+	if l.storage == nil {
+		return nil
+	}
+	err := l.storage.Save(ledger) //gopherjs:blocking
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// update saves the ledger with the members.
+func (l *Ledger) update(members []models.Member) error {
+	if l.ledger == nil {
+		return errors.New("Ledger.update: ledger not found")
+	}
+	if role, _ := l.ledger.RoleOf(l.user); role != models.RoleOwner {
+		return errors.New("Ledger.update: not an owner")
+	}
+	ledger := *l.ledger
+	ledger.Members = members
+	if err := l.save(&ledger); err != nil {
+		return err
+	}
+	*l.ledger = ledger
+	l.changed()
+	return nil
+}
+
+// SetMember adds the member, or changes the member's role. The member can
+// access the ledger after it is synced to the server.
+func (l *Ledger) SetMember(email string, role models.Role) error {
+	if l.ledger == nil {
+		return errors.New("Ledger.SetMember: ledger not found")
+	}
+	members := []models.Member{}
+	found := false
+	for _, m := range l.ledger.Members {
+		if m.Email == email {
+			m.Role = role
+			found = true
+		}
+		members = append(members, m)
+	}
+	if !found {
+		m := models.Member{Email: email, Role: role}
+		members = append(members, m)
+	}
+	return l.update(members)
+}
+
+// RemoveMember removes the member. The last owner can't be removed.
+func (l *Ledger) RemoveMember(email string) error {
+	if l.ledger == nil {
+		return errors.New("Ledger.RemoveMember: ledger not found")
+	}
+	members := []models.Member{}
+	for _, m := range l.ledger.Members {
+		if m.Email != email {
+			members = append(members, m)
+		}
+	}
+	return l.update(members)
+}
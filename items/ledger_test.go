@@ -0,0 +1,94 @@
+package items_test
+
+import (
+	. "github.com/hajimehoshi/kakeibo/items"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"reflect"
+	"testing"
+)
+
+type ledgerView struct {
+	ledger *models.Ledger
+	role   models.Role
+}
+
+func (v *ledgerView) PrintLedger(ledger *models.Ledger, role models.Role) {
+	v.ledger = ledger
+	v.role = role
+}
+
+func TestLedgerMembers(t *testing.T) {
+	const owner = "foo@example.com"
+	const member = "bar@example.com"
+	id := uuid.Generate()
+	view := &ledgerView{}
+	l := NewLedger(view, nil, id, owner)
+	if err := l.SetMember(member, models.RoleViewer); err == nil {
+		t.Errorf("expected an error before the ledger is loaded")
+	}
+	l.OnLoaded([]interface{}{
+		&models.Ledger{
+			Meta: models.Meta{ID: uuid.Generate()},
+			Name: "Other",
+			Members: []models.Member{
+				{Email: member, Role: models.RoleOwner},
+			},
+		},
+		&models.Ledger{
+			Meta: models.Meta{ID: id},
+			Name: "Home",
+			Members: []models.Member{
+				{Email: owner, Role: models.RoleOwner},
+			},
+		},
+	})
+	if view.ledger == nil || view.ledger.Name != "Home" ||
+		view.role != models.RoleOwner {
+		t.Fatalf("unexpected ledger: %+v %+v", view.ledger, view.role)
+	}
+
+	if err := l.SetMember(member, models.RoleViewer); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetMember(member, models.RoleEditor); err != nil {
+		t.Fatal(err)
+	}
+	expected := []models.Member{
+		{Email: owner, Role: models.RoleOwner},
+		{Email: member, Role: models.RoleEditor},
+	}
+	if !reflect.DeepEqual(view.ledger.Members, expected) {
+		t.Errorf("expected %+v got %+v", expected, view.ledger.Members)
+	}
+	// The last owner can't be removed.
+	if err := l.RemoveMember(owner); err == nil {
+		t.Errorf("expected an error removing the last owner")
+	}
+	if err := l.RemoveMember(member); err != nil {
+		t.Fatal(err)
+	}
+	expected = expected[:1]
+	if !reflect.DeepEqual(view.ledger.Members, expected) {
+		t.Errorf("expected %+v got %+v", expected, view.ledger.Members)
+	}
+
+	// Only the owners can edit the members.
+	l = NewLedger(view, nil, id, member)
+	l.OnLoaded([]interface{}{
+		&models.Ledger{
+			Meta: models.Meta{ID: id},
+			Name: "Home",
+			Members: []models.Member{
+				{Email: owner, Role: models.RoleOwner},
+				{Email: member, Role: models.RoleEditor},
+			},
+		},
+	})
+	if view.role != models.RoleEditor {
+		t.Errorf("expected %+v got %+v", models.RoleEditor, view.role)
+	}
+	if err := l.SetMember(member, models.RoleOwner); err == nil {
+		t.Errorf("expected an error editing the members as an editor")
+	}
+}
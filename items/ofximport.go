@@ -0,0 +1,130 @@
+package items
+
+import (
+	"errors"
+	"github.com/hajimehoshi/kakeibo/currency"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/money"
+	"github.com/hajimehoshi/kakeibo/ofx"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"strings"
+)
+
+// ofxNamespace is the base of the IDs of items imported from OFX files.
+const ofxNamespace = uuid.UUID("4f1e0a43-5f53-4c6b-9a3e-6b1f0d7c2e58")
+
+// OFXItemID returns the ID of the item imported from the transaction. The ID
+// is determined by the account and FITID so that importing the same
+// transaction twice doesn't create another item.
+func OFXItemID(s *ofx.Statement, t *ofx.Transaction) uuid.UUID {
+	name := strings.Join([]string{s.BankID, s.AccountID, t.FITID}, "/")
+	return uuid.Derive(ofxNamespace, name)
+}
+
+// statementCurrency returns the currency of the statement. A statement
+// without CURDEF is in currency.Default.
+func statementCurrency(s *ofx.Statement) (currency.Code, error) {
+	if s.Currency == "" {
+		return currency.Default, nil
+	}
+	code := currency.Code(strings.ToUpper(s.Currency))
+	if !code.IsValid() {
+		return "", errors.New("items: unsupported currency: " + s.Currency)
+	}
+	return code, nil
+}
+
+// parseOFXAmount parses an amount like '-1234.00' to the minor unit of the
+// currency. Trailing zeros after the decimal point are ignored, but an amount
+// with more non-zero digits than the currency has is an error.
+func parseOFXAmount(
+	str string,
+	code currency.Code) (money.Amount, error) {
+	str = strings.Replace(str, ",", ".", -1)
+	if i := strings.Index(str, "."); i != -1 {
+		str = strings.TrimRight(str, "0")
+		str = strings.TrimSuffix(str, ".")
+	}
+	return money.Parse(str, code)
+}
+
+func ofxItem(
+	s *ofx.Statement,
+	t *ofx.Transaction,
+	accountID uuid.UUID) (*models.ItemData, error) {
+	code, err := statementCurrency(s)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := parseOFXAmount(t.Amount, code)
+	if err != nil {
+		return nil, err
+	}
+	item := &models.ItemData{
+		Meta:      models.Meta{ID: OFXItemID(s, t)},
+		Date:      t.DatePosted,
+		Subject:   t.Name,
+		Currency:  code,
+		Direction: models.DirectionIncome,
+		AccountID: accountID,
+	}
+	if item.Subject == "" {
+		item.Subject = t.Memo
+	}
+	if item.Subject == "" {
+		item.Subject = t.Type
+	}
+	if amount < 0 {
+		item.Direction = models.DirectionExpense
+		amount = -amount
+	}
+	item.Amount = amount
+	if !item.IsValid() {
+		return nil, errors.New("items: invalid item")
+	}
+	return item, nil
+}
+
+// OFXRows returns the rows to import from the statements. Duplicates are not
+// detected.
+func OFXRows(statements []ofx.Statement, accountID uuid.UUID) []ImportRow {
+	rows := []ImportRow{}
+	for n := range statements {
+		s := &statements[n]
+		for m := range s.Transactions {
+			// OFX files don't have line numbers. Use the indices
+			// instead.
+			row := ImportRow{Line: len(rows) + 1}
+			item, err := ofxItem(s, &s.Transactions[m], accountID)
+			if err != nil {
+				row.Err = err
+			} else {
+				row.Item = *item
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// PreviewOFX parses an OFX or QFX file and prints the rows to import. The rows
+// are imported by Import. An encoding other than UTF-8 can be given as
+// Japanese banks' OFX files might be in Shift_JIS.
+func (i *Items) PreviewOFX(
+	data []byte,
+	encoding string,
+	accountID uuid.UUID) error {
+	text, err := i.decodeText(data, encoding)
+	if err != nil {
+		return err
+	}
+	statements, err := ofx.Parse(text)
+	if err != nil {
+		return err
+	}
+	rows := OFXRows(statements, accountID)
+	i.markDuplicates(rows)
+	i.importRows = rows
+	i.view.PrintImportPreview(rows)
+	return nil
+}
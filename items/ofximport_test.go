@@ -0,0 +1,121 @@
+package items_test
+
+import (
+	"github.com/hajimehoshi/kakeibo/date"
+	. "github.com/hajimehoshi/kakeibo/items"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/money"
+	"github.com/hajimehoshi/kakeibo/ofx"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"io/ioutil"
+	"testing"
+)
+
+func TestOFXRows(t *testing.T) {
+	b, err := ioutil.ReadFile("../ofx/testdata/statement_sgml.ofx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	statements, err := ofx.Parse(string(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	account := uuid.Generate()
+	rows := OFXRows(statements, account)
+	expected := []importResult{
+		{1, date.New(2015, 1, 5), "GROCERY & MORE", 3500, models.DirectionExpense, false},
+		{2, date.New(2015, 1, 25), "PAYROLL", 250000, models.DirectionIncome, false},
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("expected %+v got %+v", expected, rows)
+	}
+	// Importing the same statement again must yield the same IDs.
+	again := OFXRows(statements, account)
+	for i, row := range rows {
+		if row.Err != nil {
+			t.Errorf("row %d: %v", row.Line, row.Err)
+			continue
+		}
+		item := row.Item
+		got := importResult{
+			row.Line,
+			item.Date,
+			item.Subject,
+			item.Amount,
+			item.Direction,
+			false,
+		}
+		if got != expected[i] {
+			t.Errorf("expected %+v got %+v", expected[i], got)
+		}
+		if item.Currency != "USD" {
+			t.Errorf("expected %+v got %+v", "USD", item.Currency)
+		}
+		if item.AccountID != account {
+			t.Errorf("expected %+v got %+v", account, item.AccountID)
+		}
+		if item.Meta.ID != again[i].Item.Meta.ID {
+			t.Errorf(
+				"expected %+v got %+v",
+				item.Meta.ID,
+				again[i].Item.Meta.ID)
+		}
+	}
+	if rows[0].Item.Meta.ID == rows[1].Item.Meta.ID {
+		t.Errorf("expected different IDs for different FITIDs")
+	}
+}
+
+func TestOFXRowsFraction(t *testing.T) {
+	tests := []struct {
+		Currency string
+		Amount   string
+		Expected money.Amount
+		Err      bool
+	}{
+		// Yen doesn't have fractions.
+		{"", "-35.50", 0, true},
+		{"JPY", "-35.00", 35, false},
+		{"USD", "-35.50", 3550, false},
+		{"usd", "-35,5", 3550, false},
+		{"KWD", "-1.234", 1234, false},
+		{"USD", "-1.234", 0, true},
+		{"XXX", "-1", 0, true},
+	}
+	for _, test := range tests {
+		statements := []ofx.Statement{
+			{
+				Currency:  test.Currency,
+				AccountID: "1",
+				Transactions: []ofx.Transaction{
+					{
+						DatePosted: date.New(2015, 1, 5),
+						Amount:     test.Amount,
+						FITID:      "1",
+						Name:       "Coffee",
+					},
+				},
+			},
+		}
+		rows := OFXRows(statements, "")
+		if len(rows) != 1 {
+			t.Fatalf("expected 1 row got %+v", rows)
+		}
+		if test.Err {
+			if rows[0].Err == nil {
+				t.Errorf("expected an error row got %+v", rows[0])
+			}
+			continue
+		}
+		if rows[0].Err != nil {
+			t.Errorf("%+v: %v", test, rows[0].Err)
+			continue
+		}
+		if rows[0].Item.Amount != test.Expected {
+			t.Errorf(
+				"expected %+v got %+v",
+				test.Expected,
+				rows[0].Item.Amount)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package items
+
+import (
+	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/uuid"
+)
+
+// MonthTotals is the totals of the items in a month.
+type MonthTotals struct {
+	// Month is the first day of the month.
+	Month  date.Date
+	Totals Totals
+	// YearToDate is the totals from January to the month.
+	YearToDate Totals
+	// PreviousYear is the totals of the same month of the previous year.
+	PreviousYear Totals
+	// PreviousYearToDate is the totals from January to the same month of
+	// the previous year.
+	PreviousYearToDate Totals
+}
+
+// addTotals adds the other totals. If the totals would overflow, the items
+// counted in the other totals are counted as unconverted instead.
+func (t *Totals) addTotals(other Totals) {
+	t.Unconverted += other.Unconverted
+	income, err := t.Income.Add(other.Income)
+	if err != nil {
+		t.Unconverted += other.Counted
+		return
+	}
+	expense, err := t.Expense.Add(other.Expense)
+	if err != nil {
+		t.Unconverted += other.Counted
+		return
+	}
+	t.Income = income
+	t.Expense = expense
+	t.Counted += other.Counted
+}
+
+// totalsByMonth returns the totals of the saved items by the first days of
+// their months.
+func (i *Items) totalsByMonth() map[date.Date]Totals {
+	result := map[date.Date]Totals{}
+	for _, id := range i.allIDs() {
+		item := i.get(id)
+		ym := date.New(item.Date.Year(), item.Date.Month(), 1)
+		totals := result[ym]
+		i.addToTotals(&totals, item, item.Amount)
+		result[ym] = totals
+	}
+	return result
+}
+
+// yearToDate returns the totals from January to the month of ym.
+func yearToDate(totals map[date.Date]Totals, ym date.Date) Totals {
+	result := Totals{}
+	for m := date.New(ym.Year(), 1, 1); m <= ym; m = m.AddDate(0, 1, 0) {
+		result.addTotals(totals[m])
+	}
+	return result
+}
+
+// monthTotals returns the totals of n months from the month of start.
+func (i *Items) monthTotals(start date.Date, n int) []MonthTotals {
+	totals := i.totalsByMonth()
+	result := make([]MonthTotals, n)
+	for k := range result {
+		ym := start.AddDate(0, k, 0)
+		prev := ym.AddDate(-1, 0, 0)
+		result[k] = MonthTotals{
+			Month:              ym,
+			Totals:             totals[ym],
+			YearToDate:         yearToDate(totals, ym),
+			PreviousYear:       totals[prev],
+			PreviousYearToDate: yearToDate(totals, prev),
+		}
+	}
+	return result
+}
+
+// printMonthTotals prints the totals of n months from the month of start.
+func (i *Items) printMonthTotals(start date.Date, n int) {
+	months := i.monthTotals(start, n)
+	total := Totals{}
+	previousTotal := Totals{}
+	for _, m := range months {
+		total.addTotals(m.Totals)
+		previousTotal.addTotals(m.PreviousYear)
+	}
+	i.view.PrintMonthTotals(months, total, previousTotal)
+	i.printMonthlyExpenseChart(months)
+}
+
+// printYear prints the totals of each month of the year, and the totals of
+// the categories of the year.
+func (i *Items) printYear() {
+	year := i.yearMonth.Year()
+	i.view.PrintItems([]uuid.UUID{})
+	i.printMonthTotals(date.New(year, 1, 1), 12)
+	ids := []uuid.UUID{}
+	for _, id := range i.allIDs() {
+		if i.get(id).Date.Year() == year {
+			ids = append(ids, id)
+		}
+	}
+	i.view.PrintCategoryTotals(i.categoryTotals(ids))
+	i.printCategoryChart(ids)
+	i.view.PrintBudgets([]BudgetStatus{})
+	i.view.PrintBudgetWarnings([]BudgetStatus{})
+}
+
+// printDashboard prints the summary of the current month and the totals of
+// the last 12 months with the charts.
+func (i *Items) printDashboard() {
+	today := date.Today()
+	ym := date.New(today.Year(), today.Month(), 1)
+	i.view.PrintItems([]uuid.UUID{})
+	i.printMonthTotals(ym.AddDate(0, -11, 0), 12)
+	ids := i.itemsInMonth(ym)
+	i.view.PrintCategoryTotals(i.categoryTotals(ids))
+	i.printCategoryChart(ids)
+	i.printCumulativeChart(ym)
+	i.printBudgets(ym, ids)
+}
@@ -0,0 +1,273 @@
+package items_test
+
+import (
+	"github.com/hajimehoshi/kakeibo/currency"
+	"github.com/hajimehoshi/kakeibo/date"
+	. "github.com/hajimehoshi/kakeibo/items"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/money"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"testing"
+)
+
+// overviewView is an ItemsView which records the printed month totals.
+type overviewView struct {
+	itemsView
+	months        []MonthTotals
+	total         Totals
+	previousTotal Totals
+}
+
+func (v *overviewView) PrintMonthTotals(
+	months []MonthTotals,
+	total, previousTotal Totals) {
+	v.months = months
+	v.total = total
+	v.previousTotal = previousTotal
+}
+
+type overviewRate struct {
+	Date date.Date
+	From currency.Code
+	Rate string
+}
+
+func totals(
+	income, expense money.Amount,
+	unconverted, counted int) Totals {
+	return Totals{
+		Income:      income,
+		Expense:     expense,
+		Unconverted: unconverted,
+		Counted:     counted,
+	}
+}
+
+// overviewItem is an item for the overview tests.
+type overviewItem struct {
+	Date      date.Date
+	Amount    money.Amount
+	Direction models.Direction
+	Currency  currency.Code
+}
+
+func newOverviewItems(
+	t *testing.T,
+	view *overviewView,
+	rates []overviewRate,
+	items []overviewItem) *Items {
+	r := NewExchangeRates(nil, nil)
+	for _, rate := range rates {
+		to := currency.Default
+		err := r.Create(rate.Date, rate.From, to, rate.Rate)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &memoryStorage{values: map[uuid.UUID]*models.ItemData{}}
+	i := New(view, s,
+		NewCategories(nil, nil),
+		NewAccounts(nil, nil),
+		NewBudgets(nil),
+		NewRecurringItems(nil, nil),
+		r,
+		NewSettings(nil, nil, ""),
+		NewAttachments(nil),
+		NewLedger(nil, nil, "", "foo@example.com"))
+	values := []interface{}{}
+	for _, item := range items {
+		values = append(values, &models.ItemData{
+			Meta:      models.Meta{ID: uuid.Generate()},
+			Date:      item.Date,
+			Subject:   "Item",
+			Amount:    item.Amount,
+			Currency:  item.Currency,
+			Direction: item.Direction,
+		})
+	}
+	i.OnLoaded(values)
+	return i
+}
+
+func TestYearMonthTotals(t *testing.T) {
+	const (
+		expense  = models.DirectionExpense
+		income   = models.DirectionIncome
+		transfer = models.DirectionTransfer
+	)
+	// The expected totals of some of the months of 2015.
+	months := []MonthTotals{
+		{
+			Month:              date.New(2015, 1, 1),
+			Totals:             totals(5000, 1000, 0, 2),
+			YearToDate:         totals(5000, 1000, 0, 2),
+			PreviousYear:       totals(300, 0, 0, 1),
+			PreviousYearToDate: totals(300, 0, 0, 1),
+		},
+		{
+			Month:              date.New(2015, 2, 1),
+			YearToDate:         totals(5000, 1000, 0, 2),
+			PreviousYearToDate: totals(300, 0, 0, 1),
+		},
+		{
+			Month:              date.New(2015, 3, 1),
+			Totals:             totals(0, 2000, 0, 1),
+			YearToDate:         totals(5000, 3000, 0, 3),
+			PreviousYear:       totals(0, 1500, 0, 1),
+			PreviousYearToDate: totals(300, 1500, 0, 2),
+		},
+		{
+			Month:              date.New(2015, 12, 1),
+			YearToDate:         totals(5000, 3000, 0, 3),
+			PreviousYearToDate: totals(300, 1500, 0, 2),
+		},
+	}
+	currencies := []MonthTotals{
+		{
+			Month:              date.New(2015, 2, 1),
+			Totals:             totals(300, 1200, 1, 2),
+			YearToDate:         totals(300, 1200, 1, 2),
+			PreviousYear:       totals(0, 0, 1, 0),
+			PreviousYearToDate: totals(0, 0, 1, 0),
+		},
+	}
+	max := money.MaxAmount
+	overflow := []MonthTotals{
+		{
+			Month:      date.New(2015, 2, 1),
+			Totals:     totals(3, 0, 0, 2),
+			YearToDate: totals(max, 0, 2, 1),
+		},
+	}
+	tests := []struct {
+		Name          string
+		Rates         []overviewRate
+		Items         []overviewItem
+		Months        []MonthTotals
+		Total         Totals
+		PreviousTotal Totals
+	}{
+		{
+			Name: "months",
+			Items: []overviewItem{
+				{date.New(2015, 1, 10), 1000, expense, ""},
+				{date.New(2015, 1, 20), 5000, income, ""},
+				{date.New(2015, 3, 5), 2000, expense, "JPY"},
+				{date.New(2014, 1, 1), 300, income, ""},
+				{date.New(2014, 3, 1), 1500, expense, ""},
+				{date.New(2016, 1, 1), 700, expense, ""},
+			},
+			Months:        months,
+			Total:         totals(5000, 3000, 0, 3),
+			PreviousTotal: totals(300, 1500, 0, 2),
+		},
+		{
+			Name: "currencies",
+			Rates: []overviewRate{
+				{date.New(2015, 1, 1), "USD", "120"},
+			},
+			Items: []overviewItem{
+				{date.New(2015, 2, 1), 1000, expense, "USD"},
+				{date.New(2015, 2, 2), 500, expense, "EUR"},
+				{date.New(2015, 2, 3), 300, income, "JPY"},
+				{date.New(2015, 2, 4), 900, transfer, ""},
+				{date.New(2014, 2, 1), 1000, expense, "EUR"},
+			},
+			Months:        currencies,
+			Total:         totals(300, 1200, 1, 2),
+			PreviousTotal: totals(0, 0, 1, 0),
+		},
+		{
+			Name: "overflow",
+			Items: []overviewItem{
+				{date.New(2015, 1, 1), max, income, ""},
+				{date.New(2015, 2, 1), 1, income, ""},
+				{date.New(2015, 2, 2), 2, income, ""},
+			},
+			Months: overflow,
+			Total:  totals(max, 0, 2, 1),
+		},
+	}
+	for _, test := range tests {
+		view := &overviewView{}
+		i := newOverviewItems(t, view, test.Rates, test.Items)
+		i.UpdateMode(ModeYear, date.New(2015, 1, 1))
+		if len(view.months) != 12 {
+			t.Errorf("%s: expected 12 months got %d",
+				test.Name, len(view.months))
+			continue
+		}
+		for k, m := range view.months {
+			expected := date.New(2015, 1, 1).AddDate(0, k, 0)
+			if m.Month != expected {
+				t.Errorf("%s: expected %v got %v",
+					test.Name, expected, m.Month)
+			}
+		}
+		for _, expected := range test.Months {
+			actual := view.months[expected.Month.Month()-1]
+			if actual != expected {
+				t.Errorf("%s: expected %+v got %+v",
+					test.Name, expected, actual)
+			}
+		}
+		if view.total != test.Total {
+			t.Errorf("%s: expected %+v got %+v",
+				test.Name, test.Total, view.total)
+		}
+		if view.previousTotal != test.PreviousTotal {
+			t.Errorf("%s: expected %+v got %+v", test.Name,
+				test.PreviousTotal, view.previousTotal)
+		}
+	}
+}
+
+func TestDashboardMonthTotals(t *testing.T) {
+	today := date.Today()
+	ym := date.New(today.Year(), today.Month(), 1)
+	start := ym.AddDate(0, -23, 0)
+	items := []overviewItem{}
+	for m := start; m <= ym; m = m.AddDate(0, 1, 0) {
+		items = append(items,
+			overviewItem{m, 100, models.DirectionExpense, ""})
+	}
+	// sinceJanuary returns the totals of the items from January to m.
+	sinceJanuary := func(m date.Date) Totals {
+		n := 0
+		d := date.New(m.Year(), 1, 1)
+		for ; d <= m; d = d.AddDate(0, 1, 0) {
+			if start <= d {
+				n++
+			}
+		}
+		return totals(0, money.Amount(100*n), 0, n)
+	}
+
+	view := &overviewView{}
+	newOverviewItems(t, view, nil, items)
+	if len(view.months) != 12 {
+		t.Fatalf("expected 12 months got %d", len(view.months))
+	}
+	// The window ends with the current month and crosses the year boundary
+	// unless the current month is December.
+	for k, actual := range view.months {
+		m := ym.AddDate(0, k-11, 0)
+		expected := MonthTotals{
+			Month:              m,
+			Totals:             totals(0, 100, 0, 1),
+			YearToDate:         sinceJanuary(m),
+			PreviousYear:       totals(0, 100, 0, 1),
+			PreviousYearToDate: sinceJanuary(m.AddDate(-1, 0, 0)),
+		}
+		if actual != expected {
+			t.Errorf("expected %+v got %+v", expected, actual)
+		}
+	}
+	expected := totals(0, 1200, 0, 12)
+	if view.total != expected {
+		t.Errorf("expected %+v got %+v", expected, view.total)
+	}
+	if view.previousTotal != expected {
+		t.Errorf("expected %+v got %+v", expected, view.previousTotal)
+	}
+}
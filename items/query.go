@@ -0,0 +1,272 @@
+package items
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// AmountCondition is a condition on the amounts of items like 'amount>5000'.
+type AmountCondition struct {
+	// Op is one of '=', '<', '<=', '>' and '>='.
+	Op string
+	// Value is in the major unit of each item's currency.
+	Value *big.Rat
+}
+
+func (c AmountCondition) match(item *models.ItemData) bool {
+	amount := new(big.Rat).SetInt64(int64(item.Amount))
+	for n := 0; n < item.CurrencyCode().MinorUnits(); n++ {
+		amount.Quo(amount, big.NewRat(10, 1))
+	}
+	cmp := amount.Cmp(c.Value)
+	switch c.Op {
+	case "=":
+		return cmp == 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	panic("not reach")
+}
+
+// Query is a search query of items. An item matches the query when it
+// matches all the terms of the query.
+//
+// A query is terms separated by spaces. Double quotes make a term with spaces
+// like '"dining out"' or 'category:"dining out"'. The terms are:
+//
+//	plumber            the subject or the note contains 'plumber'
+//	amount>5000        the amount is more than 5000 (also =, <, <= and >=)
+//	date:2025-01..2025-06
+//	                   the date is in the range (either end can be omitted)
+//	date:2025          the date is in 2025 (also a month or a day)
+//	category:food      the category or its ancestor is 'food'
+//	tag:reimbursable   the item has the tag
+//
+// Words and categories are matched case-insensitively.
+type Query struct {
+	Words   []string
+	Amounts []AmountCondition
+	// From and To are the first and the last dates. Zero means unbounded.
+	From       date.Date
+	To         date.Date
+	Categories []string
+	Tags       []string
+}
+
+// splitQuery splits the query into terms by spaces outside of double quotes,
+// and removes the double quotes.
+func splitQuery(str string) ([]string, error) {
+	terms := []string{}
+	term := []rune{}
+	inQuotes := false
+	hasTerm := false
+	for _, r := range str {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			hasTerm = true
+		case unicode.IsSpace(r) && !inQuotes:
+			if hasTerm {
+				terms = append(terms, string(term))
+			}
+			term = []rune{}
+			hasTerm = false
+		default:
+			term = append(term, r)
+			hasTerm = true
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("items: unclosed quote: %q", str)
+	}
+	if hasTerm {
+		terms = append(terms, string(term))
+	}
+	return terms, nil
+}
+
+// parseDateRange parses a date, a month or a year as the range of the first
+// and the last dates.
+func parseDateRange(str string) (from, to date.Date, err error) {
+	switch strings.Count(str, "-") {
+	case 0:
+		if from, err = date.ParseISO8601(str + "-01-01"); err != nil {
+			return
+		}
+		return from, from.AddDate(1, 0, -1), nil
+	case 1:
+		if from, err = date.ParseISO8601(str + "-01"); err != nil {
+			return
+		}
+		return from, from.AddDate(0, 1, -1), nil
+	}
+	if from, err = date.ParseISO8601(str); err != nil {
+		return
+	}
+	return from, from, nil
+}
+
+func (q *Query) parseDate(value string) error {
+	if i := strings.Index(value, ".."); i != -1 {
+		start, end := value[:i], value[i+2:]
+		if start == "" && end == "" {
+			return fmt.Errorf("items: invalid date range: %q", value)
+		}
+		if start != "" {
+			from, _, err := parseDateRange(start)
+			if err != nil {
+				return err
+			}
+			q.From = from
+		}
+		if end != "" {
+			_, to, err := parseDateRange(end)
+			if err != nil {
+				return err
+			}
+			q.To = to
+		}
+		return nil
+	}
+	from, to, err := parseDateRange(value)
+	if err != nil {
+		return err
+	}
+	q.From, q.To = from, to
+	return nil
+}
+
+var amountOps = []string{"<=", ">=", "=", "<", ">", ":"}
+
+func (q *Query) parseAmount(term string) error {
+	for _, op := range amountOps {
+		if !strings.HasPrefix(term, op) {
+			continue
+		}
+		value, ok := new(big.Rat).SetString(term[len(op):])
+		if !ok || strings.ContainsAny(term[len(op):], "/eE") {
+			return fmt.Errorf("items: invalid amount: %q", term)
+		}
+		if op == ":" {
+			op = "="
+		}
+		q.Amounts = append(q.Amounts, AmountCondition{op, value})
+		return nil
+	}
+	return fmt.Errorf("items: invalid amount: %q", term)
+}
+
+// isAmountTerm reports whether the term is a condition on amounts, not a word
+// like 'amounts'.
+func isAmountTerm(term string) bool {
+	if !strings.HasPrefix(term, "amount") || term == "amount" {
+		return false
+	}
+	return strings.ContainsAny(term[len("amount"):][:1], "<>=:")
+}
+
+// ParseQuery parses a search query. An empty query matches all the items.
+func ParseQuery(str string) (*Query, error) {
+	terms, err := splitQuery(str)
+	if err != nil {
+		return nil, err
+	}
+	q := &Query{}
+	for _, term := range terms {
+		switch {
+		case isAmountTerm(term):
+			if err := q.parseAmount(term[len("amount"):]); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(term, "date:"):
+			if err := q.parseDate(term[len("date:"):]); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(term, "category:"):
+			c := term[len("category:"):]
+			if c == "" {
+				return nil, errors.New("items: empty category")
+			}
+			q.Categories = append(q.Categories, c)
+		case strings.HasPrefix(term, "tag:"):
+			t := term[len("tag:"):]
+			if t == "" {
+				return nil, errors.New("items: empty tag")
+			}
+			q.Tags = append(q.Tags, t)
+		default:
+			q.Words = append(q.Words, term)
+		}
+	}
+	return q, nil
+}
+
+// Match reports whether the item matches the query. categoryNames returns the
+// names of the category and its ancestors.
+func (q *Query) Match(
+	item *models.ItemData,
+	categoryNames func(id uuid.UUID) []string) bool {
+	for _, w := range q.Words {
+		w = strings.ToLower(w)
+		if strings.Contains(strings.ToLower(item.Subject), w) {
+			continue
+		}
+		if strings.Contains(strings.ToLower(item.Note), w) {
+			continue
+		}
+		return false
+	}
+	for _, c := range q.Amounts {
+		if !c.match(item) {
+			return false
+		}
+	}
+	if q.From != 0 && item.Date < q.From {
+		return false
+	}
+	if q.To != 0 && q.To < item.Date {
+		return false
+	}
+	for _, c := range q.Categories {
+		if !matchCategory(item, c, categoryNames) {
+			return false
+		}
+	}
+	for _, t := range q.Tags {
+		if !item.HasTag(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchCategory reports whether any part of the item is in the category of
+// the name or its descendants.
+func matchCategory(
+	item *models.ItemData,
+	name string,
+	categoryNames func(id uuid.UUID) []string) bool {
+	for _, p := range item.Parts() {
+		if p.CategoryID == "" {
+			continue
+		}
+		for _, n := range categoryNames(p.CategoryID) {
+			if strings.EqualFold(n, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+package items_test
+
+import (
+	"github.com/hajimehoshi/kakeibo/date"
+	. "github.com/hajimehoshi/kakeibo/items"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"reflect"
+	"testing"
+)
+
+// amounts returns the amount conditions of the query as strings like '>5000'
+// since big.Rat values can't be compared by reflect.DeepEqual.
+func amounts(q *Query) []string {
+	result := []string{}
+	for _, c := range q.Amounts {
+		result = append(result, c.Op+c.Value.RatString())
+	}
+	return result
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		Query      string
+		Words      []string
+		Amounts    []string
+		From       date.Date
+		To         date.Date
+		Categories []string
+		Tags       []string
+	}{
+		{
+			Query:   "",
+			Amounts: []string{},
+		},
+		{
+			Query:   "plumber",
+			Words:   []string{"plumber"},
+			Amounts: []string{},
+		},
+		{
+			Query:   `  "plumber payment"  note `,
+			Words:   []string{"plumber payment", "note"},
+			Amounts: []string{},
+		},
+		{
+			Query:   "amount>5000 amount<=12.5 amount:300 amounts",
+			Words:   []string{"amounts"},
+			Amounts: []string{">5000", "<=25/2", "=300"},
+		},
+		{
+			Query:   "date:2025-01..2025-06",
+			Amounts: []string{},
+			From:    date.New(2025, 1, 1),
+			To:      date.New(2025, 6, 30),
+		},
+		{
+			Query:   "date:2024",
+			Amounts: []string{},
+			From:    date.New(2024, 1, 1),
+			To:      date.New(2024, 12, 31),
+		},
+		{
+			Query:   "date:2024-02",
+			Amounts: []string{},
+			From:    date.New(2024, 2, 1),
+			To:      date.New(2024, 2, 29),
+		},
+		{
+			Query:   "date:2025-01-05..",
+			Amounts: []string{},
+			From:    date.New(2025, 1, 5),
+		},
+		{
+			Query:   "date:..2025-01-05",
+			Amounts: []string{},
+			To:      date.New(2025, 1, 5),
+		},
+		{
+			Query:      `category:food category:"dining out" tag:x`,
+			Amounts:    []string{},
+			Categories: []string{"food", "dining out"},
+			Tags:       []string{"x"},
+		},
+	}
+	for _, test := range tests {
+		q, err := ParseQuery(test.Query)
+		if err != nil {
+			t.Errorf("%q: %v", test.Query, err)
+			continue
+		}
+		got := []interface{}{
+			q.Words, amounts(q), q.From, q.To, q.Categories, q.Tags,
+		}
+		expected := []interface{}{
+			test.Words,
+			test.Amounts,
+			test.From,
+			test.To,
+			test.Categories,
+			test.Tags,
+		}
+		if !reflect.DeepEqual(expected, got) {
+			t.Errorf("%q: expected %+v got %+v", test.Query, expected,
+				got)
+		}
+	}
+}
+
+func TestParseQueryError(t *testing.T) {
+	for _, query := range []string{
+		`"plumber`,
+		"amount>",
+		"amount>abc",
+		"amount>1/2",
+		"amount>1e3",
+		"amount=>5",
+		"date:",
+		"date:..",
+		"date:2025-13",
+		"date:2025/01/05",
+		"category:",
+		"tag:",
+	} {
+		if _, err := ParseQuery(query); err == nil {
+			t.Errorf("expected an error for %q", query)
+		}
+	}
+}
+
+func TestQueryMatch(t *testing.T) {
+	food := uuid.Generate()
+	dining := uuid.Generate()
+	names := map[uuid.UUID][]string{
+		food:   {"Food"},
+		dining: {"Food", "Dining out"},
+	}
+	categoryNames := func(id uuid.UUID) []string {
+		return names[id]
+	}
+	item := &models.ItemData{
+		Meta:       models.Meta{ID: uuid.Generate()},
+		Date:       date.New(2025, 3, 10),
+		Subject:    "Plumber",
+		Amount:     1250050,
+		Currency:   "USD",
+		CategoryID: dining,
+		Tags:       []string{"house"},
+		Note:       "Kitchen sink",
+	}
+	tests := []struct {
+		Query    string
+		Expected bool
+	}{
+		{"", true},
+		{"plumber", true},
+		{"SINK", true},
+		{"plumber garden", false},
+		{"amount>12500", true},
+		{"amount>=12500.50", true},
+		{"amount>12500.50", false},
+		{"amount=12500.5", true},
+		{"amount<100", false},
+		{"date:2025-01..2025-06", true},
+		{"date:2025-03-11..", false},
+		{"date:2024", false},
+		{"category:food", true},
+		{`category:"dining out"`, true},
+		{"category:rent", false},
+		{"tag:house", true},
+		{"tag:car", false},
+	}
+	for _, test := range tests {
+		q, err := ParseQuery(test.Query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := q.Match(item, categoryNames)
+		if test.Expected != got {
+			t.Errorf("%q: expected %+v got %+v", test.Query,
+				test.Expected, got)
+		}
+	}
+}
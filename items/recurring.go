@@ -0,0 +1,138 @@
+package items
+
+import (
+	"errors"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"reflect"
+	"sort"
+	"time"
+)
+
+type RecurringItemsView interface {
+	PrintRecurringItems(items []models.RecurringItem)
+}
+
+type RecurringItems struct {
+	items   map[uuid.UUID]*models.RecurringItem
+	view    RecurringItemsView
+	storage Storage
+	// onChanged is called when recurring items are added or removed.
+	onChanged func()
+}
+
+func NewRecurringItems(
+	view RecurringItemsView,
+	storage Storage) *RecurringItems {
+	return &RecurringItems{
+		items:   map[uuid.UUID]*models.RecurringItem{},
+		view:    view,
+		storage: storage,
+	}
+}
+
+func (r *RecurringItems) Type() reflect.Type {
+	return reflect.TypeOf((*models.RecurringItem)(nil)).Elem()
+}
+
+func (r *RecurringItems) OnLoaded(vals []interface{}) {
+	for _, v := range vals {
+		d, ok := v.(*models.RecurringItem)
+		if !ok {
+			print("invalid data")
+			return
+		}
+		id := d.Meta.ID
+		if item, ok := r.items[id]; ok {
+			*item = *d
+			continue
+		}
+		r.items[id] = d
+	}
+	r.changed()
+}
+
+func (r *RecurringItems) changed() {
+	if r.view != nil {
+		r.view.PrintRecurringItems(r.sorted())
+	}
+	if r.onChanged != nil {
+		r.onChanged()
+	}
+}
+
+type sortRecurringItems []models.RecurringItem
+
+func (s sortRecurringItems) Len() int {
+	return len(s)
+}
+
+func (s sortRecurringItems) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s sortRecurringItems) Less(i, j int) bool {
+	if s[i].Subject != s[j].Subject {
+		return s[i].Subject < s[j].Subject
+	}
+	return s[i].Meta.ID < s[j].Meta.ID
+}
+
+// sorted returns the existing recurring items sorted by their subjects.
+func (r *RecurringItems) sorted() []models.RecurringItem {
+	items := []models.RecurringItem{}
+	for _, item := range r.items {
+		if item.Meta.IsDeleted {
+			continue
+		}
+		items = append(items, *item)
+	}
+	sort.Sort(sortRecurringItems(items))
+	return items
+}
+
+func (r *RecurringItems) save(item *models.RecurringItem) error {
+	if !item.IsValid() {
+		return errors.New("RecurringItems.save: invalid data")
+	}
+	item.Meta.LastUpdated = time.Time{}
+	if r.storage == nil {
+		return nil
+	}
+	err := r.storage.Save(item) //gopherjs:blocking
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Create creates a recurring item from the template. The template's Meta is
+// ignored.
+func (r *RecurringItems) Create(template models.RecurringItem) error {
+	item := &template
+	item.Meta = models.Meta{ID: uuid.Generate()}
+	if item.Direction != models.DirectionTransfer {
+		item.ToAccountID = ""
+	}
+	if err := r.save(item); err != nil {
+		return err
+	}
+	r.items[item.Meta.ID] = item
+	r.changed()
+	return nil
+}
+
+// Destroy destroys the recurring item. The items of the past occurrences are
+// kept.
+func (r *RecurringItems) Destroy(id uuid.UUID) error {
+	item, ok := r.items[id]
+	if !ok || item.Meta.IsDeleted {
+		return errors.New("RecurringItems.Destroy: recurring item not found")
+	}
+	item.Destroy()
+	if err := r.save(item); err != nil {
+		return err
+	}
+	r.changed()
+	return nil
+}
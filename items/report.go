@@ -0,0 +1,63 @@
+package items
+
+import (
+	"github.com/hajimehoshi/kakeibo/currency"
+	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+)
+
+// Report computes the totals of the stored values of a user without views,
+// like on a server. The totals are the same as the ones shown in the client.
+type Report struct {
+	items *Items
+}
+
+// NewReport returns a report of the user's values. user is the email address
+// of the user, which identifies the settings.
+func NewReport(user string) *Report {
+	return &Report{
+		items: &Items{
+			items:      map[uuid.UUID]*models.ItemData{},
+			categories: NewCategories(nil, nil),
+			rates:      NewExchangeRates(nil, nil),
+			settings:   NewSettings(nil, nil, user),
+		},
+	}
+}
+
+// Load loads the values. Items, categories, exchange rates and settings are
+// used, and the other values are ignored. Deleted values are loaded as well
+// as they might replace older values, but they are not counted.
+func (r *Report) Load(values []interface{}) {
+	for _, v := range values {
+		switch v := v.(type) {
+		case *models.ItemData:
+			r.items.items[v.Meta.ID] = v
+		case *models.Category:
+			r.items.categories.OnLoaded([]interface{}{v})
+		case *models.ExchangeRate:
+			r.items.rates.OnLoaded([]interface{}{v})
+		case *models.Settings:
+			r.items.settings.OnLoaded([]interface{}{v})
+		}
+	}
+}
+
+// BaseCurrency returns the currency of the totals.
+func (r *Report) BaseCurrency() currency.Code {
+	return r.items.settings.BaseCurrency()
+}
+
+// MonthTotals returns the totals of n months from the month of start.
+func (r *Report) MonthTotals(start date.Date, n int) []MonthTotals {
+	ym := date.New(start.Year(), start.Month(), 1)
+	return r.items.monthTotals(ym, n)
+}
+
+// CategoryTotals returns the totals of the categories, including their
+// subcategories, in the month of ym. The ID of the uncategorized totals is
+// empty.
+func (r *Report) CategoryTotals(ym date.Date) []CategoryTotals {
+	return r.items.categoryTotals(r.items.itemsInMonth(ym))
+}
@@ -0,0 +1,94 @@
+package items
+
+import (
+	"errors"
+	"github.com/hajimehoshi/kakeibo/currency"
+	"github.com/hajimehoshi/kakeibo/models"
+	"reflect"
+	"time"
+)
+
+type SettingsView interface {
+	PrintSettings(settings models.Settings)
+}
+
+// Settings is the user's settings. The settings are not stored until they are
+// changed.
+type Settings struct {
+	settings *models.Settings
+	view     SettingsView
+	storage  Storage
+	// onChanged is called when the settings are changed.
+	onChanged func()
+}
+
+// NewSettings creates the settings of the user, like the email address.
+func NewSettings(view SettingsView, storage Storage, user string) *Settings {
+	return &Settings{
+		settings: &models.Settings{
+			Meta: models.Meta{ID: models.SettingsID(user)},
+		},
+		view:    view,
+		storage: storage,
+	}
+}
+
+func (s *Settings) Type() reflect.Type {
+	return reflect.TypeOf((*models.Settings)(nil)).Elem()
+}
+
+func (s *Settings) OnLoaded(vals []interface{}) {
+	for _, v := range vals {
+		d, ok := v.(*models.Settings)
+		if !ok {
+			print("invalid data")
+			return
+		}
+		// Settings of other IDs are ignored.
+		if d.Meta.ID != s.settings.Meta.ID {
+			continue
+		}
+		*s.settings = *d
+	}
+	s.changed()
+}
+
+func (s *Settings) changed() {
+	if s.view != nil {
+		s.view.PrintSettings(*s.settings)
+	}
+	if s.onChanged != nil {
+		s.onChanged()
+	}
+}
+
+func (s *Settings) save(settings *models.Settings) error {
+	if !settings.IsValid() {
+		return errors.New("Settings.save: invalid data")
+	}
+	settings.Meta.LastUpdated = time.Time{}
+	if s.storage == nil {
+		return nil
+	}
+	err := s.storage.Save(settings) //gopherjs:blocking
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// BaseCurrency returns the currency which totals are converted to.
+func (s *Settings) BaseCurrency() currency.Code {
+	return s.settings.Base()
+}
+
+func (s *Settings) SetBaseCurrency(code currency.Code) error {
+	settings := *s.settings
+	settings.BaseCurrency = code
+	if err := s.save(&settings); err != nil {
+		return err
+	}
+	*s.settings = settings
+	s.changed()
+	return nil
+}
@@ -0,0 +1,288 @@
+// Package journal writes items as plain-text accounting journals of
+// ledger-cli, hledger and beancount.
+package journal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/hajimehoshi/kakeibo/currency"
+	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/money"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type Format int
+
+const (
+	FormatLedger Format = iota
+	FormatHledger
+	FormatBeancount
+)
+
+// Extension returns the file extension of the format.
+func (f Format) Extension() string {
+	switch f {
+	case FormatLedger:
+		return ".ledger"
+	case FormatHledger:
+		return ".journal"
+	case FormatBeancount:
+		return ".beancount"
+	}
+	panic("not reach")
+}
+
+// Entry is an item to write.
+type Entry struct {
+	Date    date.Date
+	Subject string
+	// Amount is in the minor unit of the commodity.
+	Amount money.Amount
+	// Commodity is the currency of Amount. An empty Commodity means the
+	// commodity given to Write.
+	Commodity currency.Code
+	Direction models.Direction
+	// Category is the names of the category and its ancestors from the root.
+	// Category is empty when the item is not categorized.
+	Category []string
+	// Account is the name of the account. Account is empty when the account
+	// is not specified.
+	Account   string
+	ToAccount string
+	// Splits is empty when the entry is not split. Category is not used for
+	// a split entry.
+	Splits []Split
+}
+
+// Split is a part of an entry in its own category.
+type Split struct {
+	Category []string
+	Amount   money.Amount
+}
+
+const (
+	rootAssets   = "Assets"
+	rootIncome   = "Income"
+	rootExpenses = "Expenses"
+
+	unknownAccount  = "Unknown"
+	noCategory      = "Uncategorized"
+	accountSeparate = ":"
+)
+
+type posting struct {
+	account string
+	amount  money.Amount
+}
+
+func assetsAccount(name string) []string {
+	if name == "" {
+		name = unknownAccount
+	}
+	return []string{rootAssets, name}
+}
+
+func categoryAccount(root string, category []string) []string {
+	if len(category) == 0 {
+		return []string{root, noCategory}
+	}
+	return append([]string{root}, category...)
+}
+
+// postings returns the postings of the entry. The amounts sum to zero. A split
+// entry has a posting for each split.
+func (f Format) postings(e *Entry) []posting {
+	splits := e.Splits
+	if len(splits) == 0 {
+		splits = []Split{{e.Category, e.Amount}}
+	}
+	result := []posting{}
+	switch e.Direction {
+	case models.DirectionExpense:
+		for _, s := range splits {
+			account := categoryAccount(rootExpenses, s.Category)
+			p := posting{f.accountName(account), s.Amount}
+			result = append(result, p)
+		}
+		account := assetsAccount(e.Account)
+		p := posting{f.accountName(account), -e.Amount}
+		result = append(result, p)
+	case models.DirectionIncome:
+		account := assetsAccount(e.Account)
+		p := posting{f.accountName(account), e.Amount}
+		result = append(result, p)
+		for _, s := range splits {
+			account := categoryAccount(rootIncome, s.Category)
+			p := posting{f.accountName(account), -s.Amount}
+			result = append(result, p)
+		}
+	case models.DirectionTransfer:
+		to := f.accountName(assetsAccount(e.ToAccount))
+		from := f.accountName(assetsAccount(e.Account))
+		result = append(
+			result,
+			posting{to, e.Amount},
+			posting{from, -e.Amount})
+	}
+	return result
+}
+
+// accountComponent escapes a component of an account name.
+func (f Format) accountComponent(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	if f != FormatBeancount {
+		// Whitespaces are already collapsed as two spaces end the
+		// account. A colon would make another level.
+		return strings.Replace(name, accountSeparate, "-", -1)
+	}
+	// Beancount's components consist of letters, digits and dashes and
+	// start with a capital letter or a digit.
+	runes := []rune{}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			r = '-'
+		}
+		if r == '-' && 0 < len(runes) && runes[len(runes)-1] == '-' {
+			continue
+		}
+		runes = append(runes, r)
+	}
+	if len(runes) == 0 {
+		return "X"
+	}
+	if unicode.IsLower(runes[0]) {
+		runes[0] = unicode.ToUpper(runes[0])
+	}
+	if !unicode.IsUpper(runes[0]) && !unicode.IsDigit(runes[0]) {
+		runes = append([]rune{'X'}, runes...)
+	}
+	return string(runes)
+}
+
+func (f Format) accountName(components []string) string {
+	names := make([]string, len(components))
+	for i, c := range components {
+		names[i] = f.accountComponent(c)
+	}
+	return strings.Join(names, accountSeparate)
+}
+
+// description escapes the subject as the description of a transaction.
+func (f Format) description(subject string) string {
+	subject = strings.Join(strings.Fields(subject), " ")
+	switch f {
+	case FormatLedger, FormatHledger:
+		// A semicolon starts a comment. hledger doesn't require spaces
+		// before it.
+		subject = strings.Replace(subject, ";", ",", -1)
+		// A parenthesized text at the beginning would be a code.
+		if strings.HasPrefix(subject, "(") {
+			return "() " + subject
+		}
+		return subject
+	case FormatBeancount:
+		subject = strings.Replace(subject, `\`, `\\`, -1)
+		subject = strings.Replace(subject, `"`, `\"`, -1)
+		return `"` + subject + `"`
+	}
+	panic("not reach")
+}
+
+func (f Format) date(d date.Date) string {
+	if f == FormatLedger {
+		return fmt.Sprintf("%04d/%02d/%02d", d.Year(), d.Month(), d.Day())
+	}
+	return d.String()
+}
+
+// writeOpens writes beancount's open directives, which are required before
+// the accounts are used.
+func writeOpens(w io.Writer, entries []Entry) error {
+	opened := map[string]date.Date{}
+	for i := range entries {
+		e := &entries[i]
+		for _, p := range FormatBeancount.postings(e) {
+			if d, ok := opened[p.account]; ok && d <= e.Date {
+				continue
+			}
+			opened[p.account] = e.Date
+		}
+	}
+	names := []string{}
+	for name := range opened {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		d := opened[name]
+		_, err := fmt.Fprintf(w, "%s open %s\n", d, name)
+		if err != nil {
+			return err
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// Write writes the entries in the format. commodity is the currency of the
+// entries without their commodities like 'JPY'.
+func Write(
+	w io.Writer,
+	format Format,
+	entries []Entry,
+	commodity currency.Code) error {
+	switch format {
+	case FormatLedger, FormatHledger, FormatBeancount:
+	default:
+		return errors.New("journal: invalid format")
+	}
+	if commodity == "" {
+		return errors.New("journal: commodity is required")
+	}
+	bw := bufio.NewWriter(w)
+	if format == FormatBeancount {
+		if err := writeOpens(bw, entries); err != nil {
+			return err
+		}
+	}
+	for i := range entries {
+		e := &entries[i]
+		if i != 0 {
+			if _, err := fmt.Fprintln(bw); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(
+			bw,
+			"%s * %s\n",
+			format.date(e.Date),
+			format.description(e.Subject))
+		if err != nil {
+			return err
+		}
+		c := e.Commodity
+		if c == "" {
+			c = commodity
+		}
+		for _, p := range format.postings(e) {
+			_, err := fmt.Fprintf(
+				bw,
+				"    %s  %s %s\n",
+				p.account,
+				p.amount.Format(c),
+				c)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,123 @@
+package journal_test
+
+import (
+	"bytes"
+	"flag"
+	"github.com/hajimehoshi/kakeibo/date"
+	. "github.com/hajimehoshi/kakeibo/journal"
+	"github.com/hajimehoshi/kakeibo/models"
+	"io/ioutil"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+var entries = []Entry{
+	{
+		Date:      date.New(2015, 1, 5),
+		Subject:   "Coffee",
+		Amount:    350,
+		Direction: models.DirectionExpense,
+		Category:  []string{"Food", "dining out"},
+		Account:   "Wallet",
+	},
+	{
+		Date:      date.New(2015, 1, 10),
+		Subject:   `Book "Go"; 2nd  edition`,
+		Amount:    3000,
+		Direction: models.DirectionExpense,
+	},
+	{
+		Date:      date.New(2015, 1, 20),
+		Subject:   "(Refund) 書店",
+		Amount:    500,
+		Direction: models.DirectionIncome,
+		Category:  []string{"Misc: other"},
+		Account:   "Bank",
+	},
+	{
+		Date:      date.New(2015, 1, 25),
+		Subject:   "Salary",
+		Amount:    250000,
+		Direction: models.DirectionIncome,
+		Category:  []string{"給与"},
+		Account:   "Bank",
+	},
+	{
+		Date:      date.New(2015, 1, 26),
+		Subject:   "ATM",
+		Amount:    10000,
+		Direction: models.DirectionTransfer,
+		Account:   "Bank",
+		ToAccount: "Wallet",
+	},
+	{
+		Date:      date.New(2015, 1, 27),
+		Subject:   "Museum",
+		Amount:    1250,
+		Commodity: "USD",
+		Direction: models.DirectionExpense,
+		Category:  []string{"Travel"},
+		Account:   "Card",
+	},
+	{
+		Date:      date.New(2015, 1, 28),
+		Subject:   "Supermarket",
+		Amount:    3000,
+		Direction: models.DirectionExpense,
+		Account:   "Wallet",
+		Splits: []Split{
+			{[]string{"Food"}, 2200},
+			{[]string{"Household"}, 500},
+			{nil, 300},
+		},
+	},
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		Format Format
+		Path   string
+	}{
+		{FormatLedger, "testdata/golden.ledger"},
+		{FormatHledger, "testdata/golden.journal"},
+		{FormatBeancount, "testdata/golden.beancount"},
+	}
+	for _, test := range tests {
+		buf := &bytes.Buffer{}
+		if err := Write(buf, test.Format, entries, "JPY"); err != nil {
+			t.Errorf("%s: %v", test.Path, err)
+			continue
+		}
+		if *update {
+			err := ioutil.WriteFile(test.Path, buf.Bytes(), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		expected, err := ioutil.ReadFile(test.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(expected, buf.Bytes()) {
+			t.Errorf(
+				"%s: expected %s got %s",
+				test.Path,
+				expected,
+				buf.Bytes())
+		}
+	}
+}
+
+func TestWriteEmpty(t *testing.T) {
+	for _, f := range []Format{FormatLedger, FormatHledger, FormatBeancount} {
+		buf := &bytes.Buffer{}
+		if err := Write(buf, f, []Entry{}, "JPY"); err != nil {
+			t.Errorf("%+v: %v", f, err)
+			continue
+		}
+		if buf.Len() != 0 {
+			t.Errorf("expected empty got %q", buf.String())
+		}
+	}
+}
@@ -24,13 +24,68 @@ func main() {
 
 	// TODO: Don't use IndexedDB (if needed).
 	// Or, create shared worker.
-	db := idb.New(dbName)
+	ledgerID := idb.CurrentLedger()
+	db := idb.New(dbName, ledgerID)
+	db.SetConflictStrategy(idb.ConflictMerge)
 
 	v := view.NewHTMLView(printError)
-	items := items.New(v, db)
+	categories := items.NewCategories(v, db)
+	accounts := items.NewAccounts(v, db)
+	budgets := items.NewBudgets(db)
+	recurring := items.NewRecurringItems(v, db)
+	rates := items.NewExchangeRates(v, db)
+	user := js.Global.Call("userEmail").Str()
+	settings := items.NewSettings(v, db, user)
+	attachments := items.NewAttachments(db)
+	userID := js.Global.Call("userID").Str()
+	apiTokens := items.NewAPITokens(v, db, userID)
+	ledger := items.NewLedger(v, db, ledgerID, user)
+	items := items.New(
+		v,
+		db,
+		categories,
+		accounts,
+		budgets,
+		recurring,
+		rates,
+		settings,
+		attachments,
+		ledger)
 	v.SetItems(items)
+	v.SetCategories(categories)
+	v.SetAccounts(accounts)
+	v.SetBudgets(budgets)
+	v.SetRecurringItems(recurring)
+	v.SetExchangeRates(rates)
+	v.SetSettings(settings)
+	v.SetAttachments(attachments)
+	v.SetLedger(ledger)
+	v.SetLedgers(db)
+	v.SetBlobs(db)
+	v.SetBackup(db)
+	models := []idb.Model{
+		ledger,
+		settings,
+		rates,
+		categories,
+		accounts,
+		budgets,
+		// Attachments are synced before items so that the attachments
+		// deleted with an item are not deleted again by the server on
+		// receiving the item's tombstone.
+		attachments,
+		// Recurring items are loaded before items so that their
+		// occurrences are materialized when items are loaded.
+		recurring,
+		items,
+	}
+	// API tokens are not shared.
+	if ledgerID == "" {
+		v.SetAPITokens(apiTokens)
+		models = append(models, apiTokens)
+	}
 
-	if err := db.Init([]idb.Model{items}); err != nil {
+	if err := db.Init(models); err != nil {
 		printError(err)
 		return
 	}
@@ -58,11 +113,24 @@ func main() {
 	js.Global.Get("window").Set("onhashchange", v.OnHashChange)
 	js.Global.Get("window").Call("onhashchange")
 
+	forbidden := false
 	for {
-		err := db.SyncIfNeeded([]idb.Model{items})
-		if err != nil {
+		err := db.SyncIfNeeded(models)
+		switch {
+		case err == idb.ErrForbidden:
+			// The user might have been removed from the shared
+			// ledger or lost the permission to write it. The values
+			// are kept on this client and synced again later.
+			if !forbidden {
+				msg := "You can't sync this ledger."
+				js.Global.Call("alert", msg)
+			}
+			forbidden = true
+		case err != nil:
 			printError(err)
 			return
+		default:
+			forbidden = false
 		}
 		time.Sleep(10 * time.Second)
 	}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"github.com/hajimehoshi/kakeibo/money"
+)
+
+// Account is a place where money is kept, like a wallet, a bank account or a
+// credit card.
+type Account struct {
+	Meta           Meta
+	Name           string
+	OpeningBalance money.Amount
+}
+
+func (a *Account) IsValid() bool {
+	if !a.Meta.IsValid() {
+		return false
+	}
+	if a.Meta.IsDeleted {
+		return true
+	}
+	if a.Name == "" {
+		return false
+	}
+	if !a.OpeningBalance.IsValid() {
+		return false
+	}
+	return true
+}
+
+func (a *Account) Destroy() {
+	meta := a.Meta
+	meta.IsDeleted = true
+	*a = Account{Meta: meta}
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// apiTokenSecretSize is the size of the random part of an API token in bytes.
+const apiTokenSecretSize = 32
+
+// APIToken is a credential for the REST API of a user, which is used by
+// scripts instead of logging in. The token itself is shown to the user only
+// once when it is created, and only the hash of its secret is stored.
+// Destroying an APIToken revokes it.
+type APIToken struct {
+	Meta Meta
+	Name string
+	// Hash is the SHA-256 hash of the token's secret in lowercase
+	// hexadecimal.
+	Hash string
+}
+
+func (a *APIToken) IsValid() bool {
+	if !a.Meta.IsValid() {
+		return false
+	}
+	if a.Meta.IsDeleted {
+		return true
+	}
+	if a.Name == "" {
+		return false
+	}
+	if !IsValidHash(a.Hash) {
+		return false
+	}
+	return true
+}
+
+func (a *APIToken) Destroy() {
+	meta := a.Meta
+	meta.IsDeleted = true
+	*a = APIToken{Meta: meta}
+}
+
+// Matches reports whether the token's secret has the hash. A deleted token
+// matches nothing.
+func (a *APIToken) Matches(hash string) bool {
+	if a.Meta.IsDeleted || a.Hash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(a.Hash), []byte(hash)) == 1
+}
+
+func hashSecret(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
+// NewAPIToken generates a token of the user, and returns the token and its
+// hash to store in an APIToken. The token is like '<user ID>.<secret>' so that
+// the server can find the user's APITokens.
+func NewAPIToken(userID string) (token string, hash string, err error) {
+	if userID == "" {
+		return "", "", errors.New("models: empty user ID")
+	}
+	b := make([]byte, apiTokenSecretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret := hex.EncodeToString(b)
+	id := base64.RawURLEncoding.EncodeToString([]byte(userID))
+	return id + "." + secret, hashSecret(secret), nil
+}
+
+// ParseAPIToken returns the ID of the token's user and the hash of the
+// token's secret.
+func ParseAPIToken(token string) (userID string, hash string, err error) {
+	tokens := strings.SplitN(token, ".", 2)
+	if len(tokens) != 2 || tokens[1] == "" {
+		return "", "", errors.New("models: invalid API token")
+	}
+	id, err := base64.RawURLEncoding.DecodeString(tokens[0])
+	if err != nil || len(id) == 0 {
+		return "", "", errors.New("models: invalid API token")
+	}
+	return string(id), hashSecret(tokens[1]), nil
+}
@@ -0,0 +1,65 @@
+package models_test
+
+import (
+	. "github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"strings"
+	"testing"
+)
+
+func TestAPIToken(t *testing.T) {
+	token, hash, err := NewAPIToken("foo@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &APIToken{
+		Meta: Meta{ID: uuid.Generate()},
+		Name: "Phone",
+		Hash: hash,
+	}
+	if !a.IsValid() {
+		t.Errorf("expected valid got %+v", a)
+	}
+	userID, got, err := ParseAPIToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID != "foo@example.com" {
+		t.Errorf("expected %+v got %+v", "foo@example.com", userID)
+	}
+	if !a.Matches(got) {
+		t.Errorf("expected %+v matches %+v", a, got)
+	}
+
+	// A token of another secret doesn't match.
+	other, _, err := NewAPIToken("foo@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, got, _ := ParseAPIToken(other); a.Matches(got) {
+		t.Errorf("expected %+v doesn't match %+v", a, got)
+	}
+
+	a.Destroy()
+	if !a.IsValid() {
+		t.Errorf("expected valid got %+v", a)
+	}
+	if a.Matches(hash) {
+		t.Errorf("expected a deleted token doesn't match")
+	}
+
+	for _, token := range []string{
+		"",
+		"foo",
+		".secret",
+		strings.Split(token, ".")[0] + ".",
+		"!!!.secret",
+	} {
+		if _, _, err := ParseAPIToken(token); err == nil {
+			t.Errorf("expected an error for %q", token)
+		}
+	}
+	if _, _, err := NewAPIToken(""); err == nil {
+		t.Errorf("expected an error for an empty user ID")
+	}
+}
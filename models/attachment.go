@@ -0,0 +1,67 @@
+package models
+
+import (
+	"github.com/hajimehoshi/kakeibo/uuid"
+)
+
+// MaxAttachmentSize is the maximum size of an attachment's content in bytes.
+const MaxAttachmentSize = 10 << 20
+
+// Attachment is a file attached to an item like a photo of the receipt. The
+// content is not a part of Attachment, and is stored as a blob by its hash.
+type Attachment struct {
+	Meta   Meta
+	ItemID uuid.UUID
+	// Name is the original file name, which is empty when it is unknown.
+	Name        string `json:",omitempty"`
+	ContentType string
+	Size        int64
+	// Hash is the SHA-256 hash of the content in lowercase hexadecimal.
+	Hash string
+}
+
+// IsValidHash reports whether the string is a SHA-256 hash in lowercase
+// hexadecimal.
+func IsValidHash(hash string) bool {
+	if len(hash) != 64 {
+		return false
+	}
+	for _, r := range hash {
+		if ('0' <= r && r <= '9') || ('a' <= r && r <= 'f') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+func (a *Attachment) IsValid() bool {
+	if !a.Meta.IsValid() {
+		return false
+	}
+	if !a.ItemID.IsValid() {
+		return false
+	}
+	if !IsValidHash(a.Hash) {
+		return false
+	}
+	if a.Meta.IsDeleted {
+		return true
+	}
+	if a.ContentType == "" {
+		return false
+	}
+	if a.Size <= 0 || MaxAttachmentSize < a.Size {
+		return false
+	}
+	return true
+}
+
+// Destroy marks the attachment as deleted. Unlike other values, ItemID and
+// Hash are kept so that the server can delete the content which is no longer
+// referred to.
+func (a *Attachment) Destroy() {
+	meta := a.Meta
+	meta.IsDeleted = true
+	*a = Attachment{Meta: meta, ItemID: a.ItemID, Hash: a.Hash}
+}
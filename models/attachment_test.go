@@ -0,0 +1,67 @@
+package models_test
+
+import (
+	. "github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"strings"
+	"testing"
+)
+
+func TestAttachmentIsValid(t *testing.T) {
+	meta := Meta{ID: uuid.Generate()}
+	deleted := Meta{ID: meta.ID, IsDeleted: true}
+	itemID := uuid.Generate()
+	hash := strings.Repeat("0123456789abcdef", 4)
+	tests := []struct {
+		Attachment Attachment
+		Expected   bool
+	}{
+		{Attachment{meta, itemID, "a.jpg", "image/jpeg", 100, hash}, true},
+		{Attachment{meta, itemID, "", "image/jpeg", 100, hash}, true},
+		{Attachment{meta, "", "", "image/jpeg", 100, hash}, false},
+		{Attachment{meta, itemID, "", "", 100, hash}, false},
+		{Attachment{meta, itemID, "", "image/jpeg", 0, hash}, false},
+		{
+			Attachment{
+				meta, itemID, "", "image/jpeg",
+				MaxAttachmentSize + 1, hash,
+			},
+			false,
+		},
+		{
+			Attachment{
+				meta, itemID, "", "image/jpeg", 100,
+				strings.ToUpper(hash),
+			},
+			false,
+		},
+		{Attachment{meta, itemID, "", "image/jpeg", 100, hash[1:]}, false},
+		{Attachment{Meta: deleted, ItemID: itemID, Hash: hash}, true},
+		{Attachment{Meta: deleted}, false},
+	}
+	for _, test := range tests {
+		got := test.Attachment.IsValid()
+		if test.Expected != got {
+			t.Errorf("%+v: expected %+v got %+v", test.Attachment,
+				test.Expected, got)
+		}
+	}
+}
+
+func TestAttachmentDestroy(t *testing.T) {
+	a := &Attachment{
+		Meta:        Meta{ID: uuid.Generate()},
+		ItemID:      uuid.Generate(),
+		Name:        "receipt.jpg",
+		ContentType: "image/jpeg",
+		Size:        100,
+		Hash:        strings.Repeat("0123456789abcdef", 4),
+	}
+	a.Destroy()
+	if !a.Meta.IsDeleted || a.Name != "" || a.Size != 0 {
+		t.Errorf("not destroyed: %+v", a)
+	}
+	if !a.IsValid() {
+		t.Errorf("invalid after Destroy: %+v", a)
+	}
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// BackupVersion is the version of the backup format.
+const BackupVersion = 1
+
+// Backup is a copy of all the synced values of a user including deleted ones.
+// The values keep their Meta except for UserID so that they can be restored to
+// another server.
+type Backup struct {
+	Version int
+	Created time.Time
+	// Values is the values for each synced type's name.
+	Values map[string][]interface{}
+}
+
+func NewBackup(now time.Time) *Backup {
+	return &Backup{
+		Version: BackupVersion,
+		Created: now,
+		Values:  map[string][]interface{}{},
+	}
+}
+
+func (b *Backup) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Version int
+		Created time.Time
+		Values  map[string]json.RawMessage
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.Version < 1 || BackupVersion < raw.Version {
+		return fmt.Errorf("models: unsupported backup version: %d", raw.Version)
+	}
+	b.Version = raw.Version
+	b.Created = raw.Created
+	b.Values = map[string][]interface{}{}
+	for name, r := range raw.Values {
+		t, err := LookupSyncedType(name)
+		if err != nil {
+			return err
+		}
+		values, err := t.DecodeValues(r)
+		if err != nil {
+			return err
+		}
+		b.Values[name] = values
+	}
+	return nil
+}
+
+// ShouldRestore reports whether a value in a backup should overwrite the
+// current value. backup is the Meta of the value in the backup, and current is
+// the Meta of the current value, or nil if the value doesn't exist. A restored
+// value is newer than the backup, so restoring the same backup again doesn't
+// change anything.
+func ShouldRestore(backup *Meta, current *Meta) bool {
+	if current == nil {
+		return true
+	}
+	// An edit not synced yet is newer than any backup.
+	if current.LastUpdated.IsZero() {
+		return false
+	}
+	return backup.LastUpdated.After(current.LastUpdated)
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/money"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"strings"
+)
+
+// Budget is a limit of expenses in a month. A budget targets either a category
+// (including its descendants) or items whose subjects contain a pattern.
+type Budget struct {
+	Meta           Meta
+	CategoryID     uuid.UUID `json:",omitempty"`
+	SubjectPattern string    `json:",omitempty"`
+	// Month is the first day of the month the budget is for. Month is zero
+	// when the budget recurs every month.
+	Month date.Date `json:",omitempty"`
+	Limit money.Amount
+}
+
+func (b *Budget) IsValid() bool {
+	if !b.Meta.IsValid() {
+		return false
+	}
+	if b.Meta.IsDeleted {
+		return true
+	}
+	if (b.CategoryID == "") == (b.SubjectPattern == "") {
+		return false
+	}
+	if b.CategoryID != "" && !b.CategoryID.IsValid() {
+		return false
+	}
+	if b.Month != 0 && b.Month.Day() != 1 {
+		return false
+	}
+	if b.Limit <= 0 || !b.Limit.IsValid() {
+		return false
+	}
+	return true
+}
+
+func (b *Budget) Destroy() {
+	meta := b.Meta
+	meta.IsDeleted = true
+	*b = Budget{Meta: meta}
+}
+
+// IsRecurring reports whether the budget recurs every month.
+func (b *Budget) IsRecurring() bool {
+	return b.Month == 0
+}
+
+// AppliesTo reports whether the budget is for the month of ym.
+func (b *Budget) AppliesTo(ym date.Date) bool {
+	if b.IsRecurring() {
+		return true
+	}
+	return b.Month.Year() == ym.Year() && b.Month.Month() == ym.Month()
+}
+
+// MatchesSubject reports whether the subject contains the budget's subject
+// pattern, ignoring case.
+func (b *Budget) MatchesSubject(subject string) bool {
+	if b.SubjectPattern == "" {
+		return false
+	}
+	s := strings.ToLower(subject)
+	return strings.Contains(s, strings.ToLower(b.SubjectPattern))
+}
@@ -0,0 +1,48 @@
+package models_test
+
+import (
+	"github.com/hajimehoshi/kakeibo/date"
+	. "github.com/hajimehoshi/kakeibo/models"
+	"testing"
+)
+
+func TestBudgetAppliesTo(t *testing.T) {
+	may := date.New(2026, 5, 1)
+	tests := []struct {
+		Budget   Budget
+		Date     date.Date
+		Expected bool
+	}{
+		{Budget{}, date.New(2026, 5, 20), true},
+		{Budget{}, date.New(1999, 12, 31), true},
+		{Budget{Month: may}, date.New(2026, 5, 31), true},
+		{Budget{Month: may}, date.New(2026, 6, 1), false},
+		{Budget{Month: may}, date.New(2025, 5, 1), false},
+	}
+	for _, test := range tests {
+		got := test.Budget.AppliesTo(test.Date)
+		if got != test.Expected {
+			t.Errorf("expected %+v got %+v", test.Expected, got)
+		}
+	}
+}
+
+func TestBudgetMatchesSubject(t *testing.T) {
+	tests := []struct {
+		Pattern  string
+		Subject  string
+		Expected bool
+	}{
+		{"coffee", "Coffee beans", true},
+		{"Coffee", "iced coffee", true},
+		{"coffee", "Tea", false},
+		{"", "Tea", false},
+	}
+	for _, test := range tests {
+		b := Budget{SubjectPattern: test.Pattern}
+		got := b.MatchesSubject(test.Subject)
+		if got != test.Expected {
+			t.Errorf("expected %+v got %+v", test.Expected, got)
+		}
+	}
+}
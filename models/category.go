@@ -0,0 +1,40 @@
+package models
+
+import (
+	"github.com/hajimehoshi/kakeibo/uuid"
+)
+
+// Category is a classification of items. Categories form a tree by ParentID,
+// e.g. 'Food > Dining out'. A category whose ParentID is empty is a root.
+type Category struct {
+	Meta     Meta
+	ParentID uuid.UUID `json:",omitempty"`
+	Name     string
+}
+
+func (c *Category) IsValid() bool {
+	if !c.Meta.IsValid() {
+		return false
+	}
+	if c.Meta.IsDeleted {
+		return true
+	}
+	if c.Name == "" {
+		return false
+	}
+	if c.ParentID != "" {
+		if !c.ParentID.IsValid() {
+			return false
+		}
+		if c.ParentID == c.Meta.ID {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Category) Destroy() {
+	meta := c.Meta
+	meta.IsDeleted = true
+	*c = Category{Meta: meta}
+}
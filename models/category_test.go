@@ -0,0 +1,42 @@
+package models_test
+
+import (
+	. "github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"testing"
+)
+
+func TestCategoryIsValid(t *testing.T) {
+	id := uuid.Generate()
+	category := func(parentID uuid.UUID, name string) Category {
+		return Category{
+			Meta:     Meta{ID: id},
+			ParentID: parentID,
+			Name:     name,
+		}
+	}
+	deleted := category(id, "Food")
+	deleted.Destroy()
+	tests := []struct {
+		Category Category
+		Valid    bool
+	}{
+		{category("", "Food"), true},
+		{category(uuid.Generate(), "Food"), true},
+		{category("", ""), false},
+		{category("foo", "Food"), false},
+		// A category can't be its own parent.
+		{category(id, "Food"), false},
+		{deleted, true},
+	}
+	for _, test := range tests {
+		got := test.Category.IsValid()
+		if test.Valid != got {
+			t.Errorf("%+v: expected %+v got %+v",
+				test.Category, test.Valid, got)
+		}
+	}
+	if deleted.ParentID != "" || deleted.Name != "" {
+		t.Errorf("expected the fields cleared got %+v", deleted)
+	}
+}
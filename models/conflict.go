@@ -0,0 +1,63 @@
+package models
+
+import (
+	"reflect"
+)
+
+// Conflict is a value edited on a client which the server rejected because
+// the value had been updated by another client.
+type Conflict struct {
+	// Local is the value edited on the client.
+	Local interface{}
+	// Server is the value stored on the server.
+	Server interface{}
+	// Result is the value which resolves the conflict.
+	Result interface{}
+}
+
+// Merge merges the fields of the values edited on a client (local) and on the
+// server (server) field by field. base is the value which the client's edit
+// is based on. For each field, the changed value is taken. If both changed the
+// field differently, the server's value is taken and the field's name is
+// returned in conflicted. The result has the server's Meta except for
+// IsDeleted, which is taken if either is deleted.
+func Merge(base, local, server interface{}) (
+	result interface{},
+	conflicted []string) {
+	b := reflect.ValueOf(base).Elem()
+	l := reflect.ValueOf(local).Elem()
+	s := reflect.ValueOf(server).Elem()
+	r := reflect.New(s.Type())
+	r.Elem().Set(s)
+	result = r.Interface()
+	conflicted = []string{}
+
+	meta := MetaOf(result)
+	if MetaOf(local).IsDeleted || MetaOf(server).IsDeleted {
+		deleted := *meta
+		deleted.IsDeleted = true
+		// A deleted value doesn't have any other fields.
+		r.Elem().Set(reflect.Zero(s.Type()))
+		*MetaOf(result) = deleted
+		return
+	}
+
+	t := s.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Type == reflect.TypeOf((*Meta)(nil)).Elem() {
+			continue
+		}
+		bf := b.Field(i).Interface()
+		lf := l.Field(i).Interface()
+		sf := s.Field(i).Interface()
+		if reflect.DeepEqual(lf, bf) || reflect.DeepEqual(lf, sf) {
+			continue
+		}
+		if reflect.DeepEqual(sf, bf) {
+			r.Elem().Field(i).Set(l.Field(i))
+			continue
+		}
+		conflicted = append(conflicted, t.Field(i).Name)
+	}
+	return
+}
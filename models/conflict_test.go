@@ -0,0 +1,54 @@
+package models_test
+
+import (
+	. "github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	id := uuid.Generate()
+	base := &ItemData{
+		Meta:    Meta{ID: id, Revision: 1},
+		Subject: "Lunch",
+		Amount:  800,
+	}
+	tests := []struct {
+		Local      ItemData
+		Server     ItemData
+		Expected   ItemData
+		Conflicted []string
+	}{
+		{
+			ItemData{Meta: Meta{ID: id, Revision: 1}, Subject: "Lunch", Amount: 900},
+			ItemData{Meta: Meta{ID: id, Revision: 2}, Subject: "Dinner", Amount: 800},
+			ItemData{Meta: Meta{ID: id, Revision: 2}, Subject: "Dinner", Amount: 900},
+			[]string{},
+		},
+		{
+			ItemData{Meta: Meta{ID: id, Revision: 1}, Subject: "Lunch", Amount: 900},
+			ItemData{Meta: Meta{ID: id, Revision: 2}, Subject: "Lunch", Amount: 1000},
+			ItemData{Meta: Meta{ID: id, Revision: 2}, Subject: "Lunch", Amount: 1000},
+			[]string{"Amount"},
+		},
+		{
+			ItemData{Meta: Meta{ID: id, Revision: 1, IsDeleted: true}},
+			ItemData{Meta: Meta{ID: id, Revision: 2}, Subject: "Lunch", Amount: 1000},
+			ItemData{Meta: Meta{ID: id, Revision: 2, IsDeleted: true}},
+			[]string{},
+		},
+	}
+
+	for _, test := range tests {
+		local := test.Local
+		server := test.Server
+		result, conflicted := Merge(base, &local, &server)
+		if !reflect.DeepEqual(&test.Expected, result) {
+			t.Errorf("expected %+v got %+v", test.Expected, result)
+		}
+		if !reflect.DeepEqual(test.Conflicted, conflicted) {
+			t.Errorf("expected %+v got %+v", test.Conflicted, conflicted)
+		}
+	}
+}
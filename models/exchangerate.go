@@ -0,0 +1,58 @@
+package models
+
+import (
+	"github.com/hajimehoshi/kakeibo/currency"
+	"github.com/hajimehoshi/kakeibo/date"
+	"math/big"
+)
+
+// ExchangeRate is the rate between two currencies on a date.
+type ExchangeRate struct {
+	Meta Meta
+	Date date.Date
+	From currency.Code
+	To   currency.Code
+	// Rate is the value of one major unit of From in major units of To as a
+	// decimal like '150.25' for USD to JPY. Rate is a string so that it is
+	// kept exactly.
+	Rate string
+}
+
+func (e *ExchangeRate) IsValid() bool {
+	if !e.Meta.IsValid() {
+		return false
+	}
+	if e.Meta.IsDeleted {
+		return true
+	}
+	if !e.From.IsValid() || !e.To.IsValid() {
+		return false
+	}
+	if e.From == e.To {
+		return false
+	}
+	if _, err := currency.ParseRate(e.Rate); err != nil {
+		return false
+	}
+	return true
+}
+
+func (e *ExchangeRate) Destroy() {
+	meta := e.Meta
+	meta.IsDeleted = true
+	*e = ExchangeRate{Meta: meta}
+}
+
+// Ratio returns the rate to convert an amount from From to To.
+func (e *ExchangeRate) Ratio() *big.Rat {
+	r, err := currency.ParseRate(e.Rate)
+	if err != nil {
+		panic("ExchangeRate.Ratio: invalid rate")
+	}
+	return r
+}
+
+// InverseRatio returns the rate to convert an amount from To to From.
+func (e *ExchangeRate) InverseRatio() *big.Rat {
+	return new(big.Rat).Inv(e.Ratio())
+}
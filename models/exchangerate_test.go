@@ -0,0 +1,32 @@
+package models_test
+
+import (
+	"github.com/hajimehoshi/kakeibo/date"
+	. "github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"testing"
+)
+
+func TestExchangeRateIsValid(t *testing.T) {
+	meta := Meta{ID: uuid.Generate()}
+	d := date.New(2015, 1, 5)
+	tests := []struct {
+		Rate     ExchangeRate
+		Expected bool
+	}{
+		{ExchangeRate{meta, d, "USD", "JPY", "150.25"}, true},
+		{ExchangeRate{meta, d, "USD", "USD", "1"}, false},
+		{ExchangeRate{meta, d, "USD", "XXX", "1"}, false},
+		{ExchangeRate{meta, d, "USD", "JPY", "0"}, false},
+		{ExchangeRate{meta, d, "USD", "JPY", "-150"}, false},
+		{ExchangeRate{meta, d, "USD", "JPY", ""}, false},
+		{ExchangeRate{Meta: Meta{ID: meta.ID, IsDeleted: true}}, true},
+	}
+	for _, test := range tests {
+		got := test.Rate.IsValid()
+		if test.Expected != got {
+			t.Errorf("%+v: expected %+v got %+v", test.Rate,
+				test.Expected, got)
+		}
+	}
+}
@@ -1,17 +1,96 @@
 package models
 
 import (
+	"errors"
+	"github.com/hajimehoshi/kakeibo/currency"
 	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/money"
+	"github.com/hajimehoshi/kakeibo/uuid"
 	"strconv"
 )
 
-// TODO: Implement 'income' and 'outgo'
+// Direction represents which way the money of an item flows.
+type Direction int
+
+const (
+	// DirectionExpense is the zero value so that items stored before
+	// Direction existed are treated as expenses.
+	DirectionExpense Direction = iota
+	DirectionIncome
+	DirectionTransfer
+)
+
+var directionNames = map[Direction]string{
+	DirectionExpense:  "expense",
+	DirectionIncome:   "income",
+	DirectionTransfer: "transfer",
+}
+
+func (d Direction) IsValid() bool {
+	_, ok := directionNames[d]
+	return ok
+}
+
+func (d Direction) String() string {
+	if name, ok := directionNames[d]; ok {
+		return name
+	}
+	return "Direction(" + strconv.Itoa(int(d)) + ")"
+}
+
+func (d Direction) MarshalText() ([]byte, error) {
+	if !d.IsValid() {
+		return nil, errors.New("Direction.MarshalText: invalid direction")
+	}
+	return []byte(d.String()), nil
+}
+
+func (d *Direction) UnmarshalText(text []byte) error {
+	for dir, name := range directionNames {
+		if name == string(text) {
+			*d = dir
+			return nil
+		}
+	}
+	return errors.New("Direction.UnmarshalText: invalid direction")
+}
+
+// Split is a part of an item in its own category, like food and household
+// goods on the same receipt. The amount is in the item's currency.
+type Split struct {
+	CategoryID uuid.UUID `json:",omitempty"`
+	Amount     money.Amount
+	Memo       string `json:",omitempty"`
+}
 
 type ItemData struct {
 	Meta    Meta
 	Date    date.Date
 	Subject string
-	Amount  int32
+	// Amount is in the minor unit of the currency, like cents for USD.
+	Amount money.Amount
+	// Currency is empty for items recorded before currencies existed, which
+	// are in currency.Default.
+	Currency  currency.Code `json:",omitempty"`
+	Direction Direction
+	// CategoryID is empty when the item is not categorized.
+	CategoryID uuid.UUID `json:",omitempty"`
+	// AccountID is the account the money comes from, or goes to for an
+	// income. AccountID is empty when the account is not specified.
+	AccountID uuid.UUID `json:",omitempty"`
+	// ToAccountID is the account the money goes to for a transfer.
+	ToAccountID uuid.UUID `json:",omitempty"`
+	// RecurringID is the recurring item which the item is an occurrence of.
+	// RecurringID is empty when the item is entered by hand.
+	RecurringID uuid.UUID `json:",omitempty"`
+	// Tags is the labels across categories like 'reimbursable'.
+	Tags []string `json:",omitempty"`
+	// Note is a memo longer than Subject. Note is not indexed as the
+	// datastore can't index long strings.
+	Note string `json:",omitempty" datastore:",noindex"`
+	// Splits is empty when the item is not split. The amounts of the splits
+	// sum to Amount, and CategoryID is empty for a split item.
+	Splits []Split `json:",omitempty"`
 }
 
 func (i *ItemData) IsValid() bool {
@@ -24,9 +103,112 @@ func (i *ItemData) IsValid() bool {
 	if i.Subject == "" {
 		return false
 	}
+	if !i.Amount.IsValid() {
+		return false
+	}
+	if !i.Direction.IsValid() {
+		return false
+	}
+	if i.Currency != "" && !i.Currency.IsValid() {
+		return false
+	}
+	if i.CategoryID != "" && !i.CategoryID.IsValid() {
+		return false
+	}
+	if i.AccountID != "" && !i.AccountID.IsValid() {
+		return false
+	}
+	if i.RecurringID != "" && !i.RecurringID.IsValid() {
+		return false
+	}
+	if i.ToAccountID != "" {
+		if i.Direction != DirectionTransfer {
+			return false
+		}
+		if !i.ToAccountID.IsValid() {
+			return false
+		}
+		if i.ToAccountID == i.AccountID {
+			return false
+		}
+	}
+	if !areTagsValid(i.Tags) {
+		return false
+	}
+	if !i.areSplitsValid() {
+		return false
+	}
 	return true
 }
 
+func (i *ItemData) areSplitsValid() bool {
+	if len(i.Splits) == 0 {
+		return true
+	}
+	// Transfers don't have categories.
+	if i.Direction == DirectionTransfer {
+		return false
+	}
+	if i.CategoryID != "" {
+		return false
+	}
+	sum := money.Amount(0)
+	for _, s := range i.Splits {
+		if s.CategoryID != "" && !s.CategoryID.IsValid() {
+			return false
+		}
+		if !s.Amount.IsValid() {
+			return false
+		}
+		var err error
+		sum, err = sum.Add(s.Amount)
+		if err != nil {
+			return false
+		}
+	}
+	return sum == i.Amount
+}
+
+// Parts returns the splits of the item, or the whole item as one split if the
+// item is not split.
+func (i *ItemData) Parts() []Split {
+	if len(i.Splits) == 0 {
+		return []Split{{CategoryID: i.CategoryID, Amount: i.Amount}}
+	}
+	return i.Splits
+}
+
+// CurrencyCode returns the currency of the amount.
+func (i *ItemData) CurrencyCode() currency.Code {
+	if i.Currency == "" {
+		return currency.Default
+	}
+	return i.Currency
+}
+
+// BalanceChange returns how much the item changes the balance of the account.
+// The change is in the item's currency.
+func (i *ItemData) BalanceChange(accountID uuid.UUID) money.Amount {
+	if accountID == "" || i.Meta.IsDeleted {
+		return 0
+	}
+	// A transfer to the same account is invalid, so the change is always
+	// one of the amount, its negation or zero.
+	change := money.Amount(0)
+	if i.AccountID == accountID {
+		switch i.Direction {
+		case DirectionIncome:
+			change = i.Amount
+		case DirectionExpense, DirectionTransfer:
+			change = -i.Amount
+		}
+	}
+	if i.ToAccountID == accountID {
+		change = i.Amount
+	}
+	return change
+}
+
 func (i *ItemData) Destroy() {
 	meta := i.Meta
 	meta.IsDeleted = true
@@ -37,6 +219,25 @@ func (i *ItemData) CSVRecord() []string {
 	return []string{
 		i.Date.String(),
 		i.Subject,
-		strconv.Itoa(int(i.Amount)),
+		i.Amount.Format(i.CurrencyCode()),
+		i.Direction.String(),
+		i.CurrencyCode().String(),
+	}
+}
+
+// SplitCSVRecords returns the records of the splits, which follow the item's
+// record. The dates and the directions are empty so that the records are not
+// taken as items.
+func (i *ItemData) SplitCSVRecords() [][]string {
+	records := [][]string{}
+	for _, s := range i.Splits {
+		records = append(records, []string{
+			"",
+			s.Memo,
+			s.Amount.Format(i.CurrencyCode()),
+			"",
+			i.CurrencyCode().String(),
+		})
 	}
+	return records
 }
@@ -0,0 +1,221 @@
+package models_test
+
+import (
+	"encoding/json"
+	. "github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/money"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"testing"
+)
+
+func TestDirection(t *testing.T) {
+	tests := []struct {
+		Direction Direction
+		Valid     bool
+		JSON      string
+	}{
+		{DirectionExpense, true, `"expense"`},
+		{DirectionIncome, true, `"income"`},
+		{DirectionTransfer, true, `"transfer"`},
+		{Direction(-1), false, ""},
+		{Direction(3), false, ""},
+	}
+	for _, test := range tests {
+		if got := test.Direction.IsValid(); got != test.Valid {
+			t.Errorf("%s: expected %+v got %+v", test.Direction,
+				test.Valid, got)
+		}
+		b, err := json.Marshal(test.Direction)
+		if !test.Valid {
+			if err == nil {
+				t.Errorf("%s: expected an error got %s",
+					test.Direction, b)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.Direction, err)
+			continue
+		}
+		if string(b) != test.JSON {
+			t.Errorf("expected %s got %s", test.JSON, b)
+		}
+		var d Direction
+		if err := json.Unmarshal(b, &d); err != nil {
+			t.Errorf("%s: %v", test.Direction, err)
+			continue
+		}
+		if d != test.Direction {
+			t.Errorf("expected %+v got %+v", test.Direction, d)
+		}
+	}
+
+	for _, str := range []string{`"Income"`, `""`, `"refund"`, `1`} {
+		d := DirectionIncome
+		if err := json.Unmarshal([]byte(str), &d); err == nil {
+			t.Errorf("%s: expected an error got %+v", str, d)
+		}
+		if d != DirectionIncome {
+			t.Errorf("%s: expected unchanged got %+v", str, d)
+		}
+	}
+	// Items stored before Direction existed are expenses.
+	item := ItemData{}
+	str := `{"Meta":{"ID":"3d6f3c4a-8a4e-4b1b-9c57-0f3a3f1d2b6c"}}`
+	if err := json.Unmarshal([]byte(str), &item); err != nil {
+		t.Fatal(err)
+	}
+	if d := item.Direction; d != DirectionExpense {
+		t.Errorf("expected %+v got %+v", DirectionExpense, d)
+	}
+}
+
+func TestBalanceChange(t *testing.T) {
+	wallet := uuid.Generate()
+	bank := uuid.Generate()
+	tests := []struct {
+		Item     ItemData
+		Account  uuid.UUID
+		Expected money.Amount
+	}{
+		{
+			ItemData{Amount: 100, AccountID: wallet},
+			wallet,
+			-100,
+		},
+		{
+			ItemData{Amount: 100, AccountID: wallet},
+			bank,
+			0,
+		},
+		{
+			ItemData{
+				Amount:    100,
+				Direction: DirectionIncome,
+				AccountID: bank,
+			},
+			bank,
+			100,
+		},
+		{
+			ItemData{
+				Amount:      100,
+				Direction:   DirectionTransfer,
+				AccountID:   bank,
+				ToAccountID: wallet,
+			},
+			bank,
+			-100,
+		},
+		{
+			ItemData{
+				Amount:      100,
+				Direction:   DirectionTransfer,
+				AccountID:   bank,
+				ToAccountID: wallet,
+			},
+			wallet,
+			100,
+		},
+	}
+
+	for _, test := range tests {
+		got := test.Item.BalanceChange(test.Account)
+		if test.Expected != got {
+			t.Errorf("expected %+v got %+v", test.Expected, got)
+		}
+	}
+}
+
+func TestItemDataLegacyJSON(t *testing.T) {
+	// Items stored before currencies and money.Amount existed.
+	str := `{"Meta":{"ID":"3d6f3c4a-8a4e-4b1b-9c57-0f3a3f1d2b6c"},` +
+		`"Date":"2014-05-06","Subject":"Lunch","Amount":2147483647}`
+	var item ItemData
+	if err := json.Unmarshal([]byte(str), &item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Amount != 2147483647 {
+		t.Errorf("expected %+v got %+v", 2147483647, item.Amount)
+	}
+	if item.CurrencyCode() != "JPY" {
+		t.Errorf("expected %+v got %+v", "JPY", item.CurrencyCode())
+	}
+	if !item.IsValid() {
+		t.Errorf("expected valid: %+v", item)
+	}
+
+	item.Amount = money.MaxAmount + 1
+	if item.IsValid() {
+		t.Errorf("expected invalid: %+v", item)
+	}
+}
+
+func TestItemDataSplits(t *testing.T) {
+	food := uuid.Generate()
+	household := uuid.Generate()
+	newItem := func(amount money.Amount, splits ...Split) ItemData {
+		return ItemData{
+			Meta:    Meta{ID: uuid.Generate()},
+			Subject: "Supermarket",
+			Amount:  amount,
+			Splits:  splits,
+		}
+	}
+	tests := []struct {
+		Item  ItemData
+		Valid bool
+	}{
+		{newItem(1000), true},
+		{
+			newItem(
+				1000,
+				Split{CategoryID: food, Amount: 700},
+				Split{CategoryID: household, Amount: 300, Memo: "Soap"}),
+			true,
+		},
+		// Splits must sum to the amount.
+		{
+			newItem(
+				1000,
+				Split{CategoryID: food, Amount: 700},
+				Split{CategoryID: household, Amount: 200}),
+			false,
+		},
+		{
+			newItem(
+				0,
+				Split{Amount: money.MaxAmount},
+				Split{Amount: 1},
+				Split{Amount: -1}),
+			false,
+		},
+	}
+	split := newItem(100, Split{CategoryID: food, Amount: 100})
+	split.CategoryID = household
+	tests = append(tests, struct {
+		Item  ItemData
+		Valid bool
+	}{split, false})
+	transfer := newItem(100, Split{CategoryID: food, Amount: 100})
+	transfer.Direction = DirectionTransfer
+	tests = append(tests, struct {
+		Item  ItemData
+		Valid bool
+	}{transfer, false})
+
+	for _, test := range tests {
+		got := test.Item.IsValid()
+		if test.Valid != got {
+			t.Errorf("%+v: expected %+v got %+v", test.Item, test.Valid, got)
+		}
+	}
+
+	item := newItem(1000)
+	item.CategoryID = food
+	parts := item.Parts()
+	if len(parts) != 1 || parts[0].CategoryID != food ||
+		parts[0].Amount != 1000 {
+		t.Errorf("unexpected parts: %+v", parts)
+	}
+}
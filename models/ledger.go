@@ -0,0 +1,148 @@
+package models
+
+import (
+	"errors"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"strconv"
+)
+
+// Role is the permission of a member of a ledger.
+type Role int
+
+const (
+	// RoleViewer can read the values of the ledger.
+	RoleViewer Role = iota
+	// RoleEditor can also write the values of the ledger.
+	RoleEditor
+	// RoleOwner can also edit the ledger itself like its members.
+	RoleOwner
+)
+
+var roleNames = map[Role]string{
+	RoleViewer: "viewer",
+	RoleEditor: "editor",
+	RoleOwner:  "owner",
+}
+
+func (r Role) IsValid() bool {
+	_, ok := roleNames[r]
+	return ok
+}
+
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return "Role(" + strconv.Itoa(int(r)) + ")"
+}
+
+func (r Role) MarshalText() ([]byte, error) {
+	if !r.IsValid() {
+		return nil, errors.New("Role.MarshalText: invalid role")
+	}
+	return []byte(r.String()), nil
+}
+
+func (r *Role) UnmarshalText(text []byte) error {
+	for role, name := range roleNames {
+		if name == string(text) {
+			*r = role
+			return nil
+		}
+	}
+	return errors.New("Role.UnmarshalText: invalid role")
+}
+
+// CanWrite reports whether a member of the role can write values.
+func (r Role) CanWrite() bool {
+	return RoleEditor <= r
+}
+
+// Member is a user who can access a ledger.
+type Member struct {
+	Email string
+	Role  Role
+}
+
+// Ledger is a book shared by its members, like a household's. The values of a
+// ledger are stored apart from the members' own values, and the Ledger itself
+// is stored with them.
+type Ledger struct {
+	Meta    Meta
+	Name    string
+	Members []Member
+}
+
+// LedgerSummary is a ledger which a user is a member of.
+type LedgerSummary struct {
+	ID   uuid.UUID
+	Name string
+	Role Role
+}
+
+// IsValid reports whether the ledger is valid. A ledger has at least one
+// owner so that it can always be edited.
+func (l *Ledger) IsValid() bool {
+	if !l.Meta.IsValid() {
+		return false
+	}
+	if l.Meta.IsDeleted {
+		return true
+	}
+	if l.Name == "" {
+		return false
+	}
+	emails := map[string]struct{}{}
+	hasOwner := false
+	for _, m := range l.Members {
+		if m.Email == "" || !m.Role.IsValid() {
+			return false
+		}
+		if _, ok := emails[m.Email]; ok {
+			return false
+		}
+		emails[m.Email] = struct{}{}
+		if m.Role == RoleOwner {
+			hasOwner = true
+		}
+	}
+	return hasOwner
+}
+
+func (l *Ledger) Destroy() {
+	meta := l.Meta
+	meta.IsDeleted = true
+	*l = Ledger{Meta: meta}
+}
+
+// RoleOf returns the role of the member. RoleOf returns false if the user is
+// not a member.
+func (l *Ledger) RoleOf(email string) (Role, bool) {
+	if l.Meta.IsDeleted {
+		return 0, false
+	}
+	for _, m := range l.Members {
+		if m.Email == email {
+			return m.Role, true
+		}
+	}
+	return 0, false
+}
+
+// Membership records that a user is a member of a ledger so that the user can
+// find the ledger. Memberships are managed by the server, and a membership
+// remains after the user is removed from the ledger.
+type Membership struct {
+	Meta   Meta
+	Ledger uuid.UUID
+}
+
+func (m *Membership) IsValid() bool {
+	if !m.Meta.IsValid() {
+		return false
+	}
+	if m.Meta.IsDeleted {
+		return true
+	}
+	return m.Ledger != ""
+}
@@ -0,0 +1,81 @@
+package models_test
+
+import (
+	"encoding/json"
+	. "github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"testing"
+)
+
+func TestLedgerIsValid(t *testing.T) {
+	meta := Meta{ID: uuid.Generate()}
+	owner := Member{"foo@example.com", RoleOwner}
+	editor := Member{"bar@example.com", RoleEditor}
+	noEmail := Member{"", RoleViewer}
+	invalidRole := Member{"baz@example.com", Role(3)}
+	tests := []struct {
+		Ledger   Ledger
+		Expected bool
+	}{
+		{Ledger{meta, "Home", []Member{owner, editor}}, true},
+		{Ledger{meta, "", []Member{owner}}, false},
+		// A ledger needs an owner.
+		{Ledger{meta, "Home", []Member{editor}}, false},
+		{Ledger{meta, "Home", []Member{owner, owner}}, false},
+		{Ledger{meta, "Home", []Member{owner, noEmail}}, false},
+		{Ledger{meta, "Home", []Member{owner, invalidRole}}, false},
+		{Ledger{Meta: Meta{ID: meta.ID, IsDeleted: true}}, true},
+	}
+	for _, test := range tests {
+		got := test.Ledger.IsValid()
+		if got != test.Expected {
+			t.Errorf("%+v: expected %+v got %+v", test.Ledger,
+				test.Expected, got)
+		}
+	}
+}
+
+func TestLedgerRoleOf(t *testing.T) {
+	l := &Ledger{
+		Meta: Meta{ID: uuid.Generate()},
+		Name: "Home",
+		Members: []Member{
+			{"foo@example.com", RoleOwner},
+			{"bar@example.com", RoleViewer},
+		},
+	}
+	if role, ok := l.RoleOf("bar@example.com"); !ok || role != RoleViewer {
+		t.Errorf("expected %+v got %+v (%+v)", RoleViewer, role, ok)
+	}
+	if RoleViewer.CanWrite() || !RoleEditor.CanWrite() {
+		t.Errorf("only editors and owners can write")
+	}
+	if _, ok := l.RoleOf("baz@example.com"); ok {
+		t.Errorf("expected not a member")
+	}
+	l.Destroy()
+	if _, ok := l.RoleOf("foo@example.com"); ok {
+		t.Errorf("expected no members of a deleted ledger")
+	}
+}
+
+func TestRoleJSON(t *testing.T) {
+	b, err := json.Marshal(Member{"foo@example.com", RoleEditor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"Email":"foo@example.com","Role":"editor"}`
+	if string(b) != expected {
+		t.Errorf("expected %s got %s", expected, b)
+	}
+	m := Member{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Role != RoleEditor {
+		t.Errorf("expected %+v got %+v", RoleEditor, m.Role)
+	}
+	if err := json.Unmarshal([]byte(`{"Role":"admin"}`), &m); err == nil {
+		t.Errorf("expected an error for an invalid role")
+	}
+}
@@ -9,9 +9,24 @@ type Meta struct {
 	ID          uuid.UUID
 	LastUpdated time.Time
 	IsDeleted   bool
-	UserID      string `json:"-"`
+	// Revision is incremented by the server whenever the value is stored. A
+	// client sends the revision its edit is based on, and the server rejects
+	// the edit if the stored revision is different.
+	Revision int
+	UserID   string `json:"-"`
+	// CreatedBy and UpdatedBy are the email addresses of the users who
+	// created and last modified the value. They are set by the server, and
+	// are empty for values stored before they existed.
+	CreatedBy string `json:",omitempty"`
+	UpdatedBy string `json:",omitempty"`
 }
 
 func (m *Meta) IsValid() bool {
-	return m.ID.IsValid()
+	if !m.ID.IsValid() {
+		return false
+	}
+	if m.Revision < 0 {
+		return false
+	}
+	return true
 }
@@ -2,46 +2,40 @@ package models
 
 import (
 	"encoding/json"
-	"errors"
-	"reflect"
+	"github.com/hajimehoshi/kakeibo/uuid"
 	"time"
 )
 
 type SyncRequest struct {
-	Type        string
+	Type string
+	// Ledger is the shared ledger whose values are synced. Ledger is empty
+	// for the user's own values.
+	Ledger      uuid.UUID `json:",omitempty"`
 	LastUpdated time.Time
 	Values      []interface{}
+	// Cursor is the continuation token of the previous response. Cursor is
+	// empty for the first page.
+	Cursor string `json:",omitempty"`
+	// Limit is the maximum number of values in the response. The server
+	// might return fewer values than Limit. Zero means the server's limit.
+	Limit int `json:",omitempty"`
 }
 
 type syncRequestRaw struct {
 	Type        string
+	Ledger      uuid.UUID
 	LastUpdated time.Time
 	RawValues   json.RawMessage `json:"Values"`
+	Cursor      string
+	Limit       int
 }
 
-func toValues(t string, raw json.RawMessage) (values []interface{}, err error) {
-	switch t {
-	case reflect.TypeOf((*ItemData)(nil)).Elem().Name():
-		values, err = toItemData(raw)
-	default:
-		err = errors.New("SyncRequest.UnmarshalJSON: unknown type")
-	}
-	return
-}
-
-func toItemData(raw json.RawMessage) ([]interface{}, error) {
-	values := []*ItemData{}
-	if err := json.Unmarshal(raw, &values); err != nil {
+func toValues(t string, raw json.RawMessage) ([]interface{}, error) {
+	st, err := LookupSyncedType(t)
+	if err != nil {
 		return nil, err
 	}
-	result := make([]interface{}, len(values))
-	for i, v := range values {
-		if !v.IsValid() {
-			return nil, errors.New("protocol: invalid item")
-		}
-		result[i] = v
-	}
-	return result, nil
+	return st.DecodeValues(raw)
 }
 
 func (s *SyncRequest) UnmarshalJSON(b []byte) (err error) {
@@ -50,7 +44,10 @@ func (s *SyncRequest) UnmarshalJSON(b []byte) (err error) {
 		return
 	}
 	s.Type = raw.Type
+	s.Ledger = raw.Ledger
 	s.LastUpdated = raw.LastUpdated
+	s.Cursor = raw.Cursor
+	s.Limit = raw.Limit
 	s.Values, err = toValues(s.Type, raw.RawValues)
 	return
 }
@@ -59,12 +56,20 @@ type SyncResponse struct {
 	Type        string
 	LastUpdated time.Time
 	Values      []interface{}
+	// Rejected is the stored values of the requested values which are not
+	// stored because of conflicts.
+	Rejected []interface{}
+	// Cursor is the continuation token for the next page. Cursor is empty
+	// when the client is caught up.
+	Cursor string `json:",omitempty"`
 }
 
 type syncResponseRaw struct {
 	Type        string
 	LastUpdated time.Time
 	RawValues   json.RawMessage `json:"Values"`
+	RawRejected json.RawMessage `json:"Rejected"`
+	Cursor      string
 }
 
 func (s *SyncResponse) UnmarshalJSON(b []byte) (err error) {
@@ -74,6 +79,14 @@ func (s *SyncResponse) UnmarshalJSON(b []byte) (err error) {
 	}
 	s.Type = raw.Type
 	s.LastUpdated = raw.LastUpdated
-	s.Values, err = toValues(s.Type, raw.RawValues)
+	s.Cursor = raw.Cursor
+	if s.Values, err = toValues(s.Type, raw.RawValues); err != nil {
+		return
+	}
+	// Rejected is omitted by an old server.
+	if raw.RawRejected == nil {
+		return
+	}
+	s.Rejected, err = toValues(s.Type, raw.RawRejected)
 	return
 }
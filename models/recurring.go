@@ -0,0 +1,224 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hajimehoshi/kakeibo/currency"
+	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/money"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"strconv"
+	"time"
+)
+
+// Recurrence is how often a recurring item occurs.
+type Recurrence int
+
+const (
+	// RecurrenceMonthly occurs on the same day every month.
+	RecurrenceMonthly Recurrence = iota
+	// RecurrenceLastBusinessDay occurs on the last weekday of every month.
+	RecurrenceLastBusinessDay
+	// RecurrenceWeekly occurs every some weeks.
+	RecurrenceWeekly
+	// RecurrenceYearly occurs on the same day every year.
+	RecurrenceYearly
+)
+
+var recurrenceNames = map[Recurrence]string{
+	RecurrenceMonthly:         "monthly",
+	RecurrenceLastBusinessDay: "last-business-day",
+	RecurrenceWeekly:          "weekly",
+	RecurrenceYearly:          "yearly",
+}
+
+func (r Recurrence) IsValid() bool {
+	_, ok := recurrenceNames[r]
+	return ok
+}
+
+func (r Recurrence) String() string {
+	if name, ok := recurrenceNames[r]; ok {
+		return name
+	}
+	return "Recurrence(" + strconv.Itoa(int(r)) + ")"
+}
+
+func (r Recurrence) MarshalText() ([]byte, error) {
+	if !r.IsValid() {
+		return nil, errors.New("Recurrence.MarshalText: invalid recurrence")
+	}
+	return []byte(r.String()), nil
+}
+
+func (r *Recurrence) UnmarshalText(text []byte) error {
+	for rec, name := range recurrenceNames {
+		if name == string(text) {
+			*r = rec
+			return nil
+		}
+	}
+	return errors.New("Recurrence.UnmarshalText: invalid recurrence")
+}
+
+// RecurringItem is a template of items which occur repeatedly like rent or
+// salary. The items of the occurrences are materialized as ItemData.
+type RecurringItem struct {
+	Meta        Meta
+	Subject     string
+	Amount      money.Amount
+	Currency    currency.Code `json:",omitempty"`
+	Direction   Direction
+	CategoryID  uuid.UUID `json:",omitempty"`
+	AccountID   uuid.UUID `json:",omitempty"`
+	ToAccountID uuid.UUID `json:",omitempty"`
+	Recurrence  Recurrence
+	// Day is the day of the month for RecurrenceMonthly. If a month doesn't
+	// have the day, the item occurs on the last day of the month.
+	Day int `json:",omitempty"`
+	// Weeks is the interval for RecurrenceWeekly.
+	Weeks int `json:",omitempty"`
+	// Start is the first date when the item can occur. For RecurrenceWeekly
+	// and RecurrenceYearly, Start is also the first occurrence.
+	Start date.Date
+	// End is the last date when the item can occur. End is zero when the
+	// item occurs forever.
+	End date.Date `json:",omitempty"`
+}
+
+func (r *RecurringItem) IsValid() bool {
+	if !r.Meta.IsValid() {
+		return false
+	}
+	if r.Meta.IsDeleted {
+		return true
+	}
+	if r.Start == 0 {
+		return false
+	}
+	if !r.Item(r.Start).IsValid() {
+		return false
+	}
+	switch r.Recurrence {
+	case RecurrenceMonthly:
+		if r.Day < 1 || 31 < r.Day {
+			return false
+		}
+	case RecurrenceLastBusinessDay, RecurrenceYearly:
+	case RecurrenceWeekly:
+		if r.Weeks < 1 {
+			return false
+		}
+	default:
+		return false
+	}
+	if r.End != 0 && r.End < r.Start {
+		return false
+	}
+	return true
+}
+
+func (r *RecurringItem) Destroy() {
+	meta := r.Meta
+	meta.IsDeleted = true
+	*r = RecurringItem{Meta: meta}
+}
+
+// Description returns a human readable description of the recurrence like
+// 'Monthly on day 25'.
+func (r *RecurringItem) Description() string {
+	switch r.Recurrence {
+	case RecurrenceMonthly:
+		return fmt.Sprintf("Monthly on day %d", r.Day)
+	case RecurrenceLastBusinessDay:
+		return "Monthly on the last business day"
+	case RecurrenceWeekly:
+		if r.Weeks == 1 {
+			return "Weekly on " + r.Start.Weekday().String()
+		}
+		return fmt.Sprintf(
+			"Every %d weeks on %s", r.Weeks, r.Start.Weekday())
+	case RecurrenceYearly:
+		return fmt.Sprintf("Yearly on %s %d", r.Start.Month(), r.Start.Day())
+	}
+	return r.Recurrence.String()
+}
+
+func lastDayOfMonth(d date.Date) date.Date {
+	first := date.New(d.Year(), d.Month(), 1)
+	return first.AddDate(0, 1, -1)
+}
+
+// occurrence returns the n-th candidate date of the occurrences counted from
+// Start. The result might be before Start.
+func (r *RecurringItem) occurrence(n int) date.Date {
+	switch r.Recurrence {
+	case RecurrenceMonthly:
+		month := date.New(r.Start.Year(), r.Start.Month(), 1)
+		month = month.AddDate(0, n, 0)
+		last := lastDayOfMonth(month)
+		if last.Day() < r.Day {
+			return last
+		}
+		return date.New(month.Year(), month.Month(), r.Day)
+	case RecurrenceLastBusinessDay:
+		month := date.New(r.Start.Year(), r.Start.Month(), 1)
+		d := lastDayOfMonth(month.AddDate(0, n, 0))
+		for d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+			d = d.AddDate(0, 0, -1)
+		}
+		return d
+	case RecurrenceWeekly:
+		return r.Start.AddDate(0, 0, 7*r.Weeks*n)
+	case RecurrenceYearly:
+		return r.Start.AddDate(n, 0, 0)
+	}
+	panic("not reach")
+}
+
+// Occurrences returns the dates of the occurrences until the given date.
+func (r *RecurringItem) Occurrences(until date.Date) []date.Date {
+	result := []date.Date{}
+	if r.Meta.IsDeleted || !r.Recurrence.IsValid() {
+		return result
+	}
+	if r.Recurrence == RecurrenceWeekly && r.Weeks < 1 {
+		return result
+	}
+	if r.End != 0 && r.End < until {
+		until = r.End
+	}
+	for n := 0; ; n++ {
+		d := r.occurrence(n)
+		if until < d {
+			break
+		}
+		if d < r.Start {
+			continue
+		}
+		result = append(result, d)
+	}
+	return result
+}
+
+// OccurrenceID returns the ID of the item of the occurrence on d. The ID is
+// the same on every client so that an occurrence is materialized only once.
+func (r *RecurringItem) OccurrenceID(d date.Date) uuid.UUID {
+	return uuid.Derive(r.Meta.ID, d.String())
+}
+
+// Item returns the item of the occurrence on d.
+func (r *RecurringItem) Item(d date.Date) *ItemData {
+	return &ItemData{
+		Meta:        Meta{ID: r.OccurrenceID(d)},
+		Date:        d,
+		Subject:     r.Subject,
+		Amount:      r.Amount,
+		Currency:    r.Currency,
+		Direction:   r.Direction,
+		CategoryID:  r.CategoryID,
+		AccountID:   r.AccountID,
+		ToAccountID: r.ToAccountID,
+		RecurringID: r.Meta.ID,
+	}
+}
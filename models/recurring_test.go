@@ -0,0 +1,134 @@
+package models_test
+
+import (
+	"github.com/hajimehoshi/kakeibo/date"
+	. "github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"reflect"
+	"testing"
+)
+
+func TestOccurrences(t *testing.T) {
+	tests := []struct {
+		Item     RecurringItem
+		Until    date.Date
+		Expected []date.Date
+	}{
+		{
+			RecurringItem{
+				Recurrence: RecurrenceMonthly,
+				Day:        31,
+				Start:      date.New(2015, 1, 10),
+			},
+			date.New(2015, 4, 30),
+			[]date.Date{
+				date.New(2015, 1, 31),
+				date.New(2015, 2, 28),
+				date.New(2015, 3, 31),
+				date.New(2015, 4, 30),
+			},
+		},
+		{
+			RecurringItem{
+				Recurrence: RecurrenceMonthly,
+				Day:        5,
+				Start:      date.New(2015, 1, 10),
+				End:        date.New(2015, 3, 4),
+			},
+			date.New(2015, 12, 31),
+			[]date.Date{
+				date.New(2015, 2, 5),
+			},
+		},
+		{
+			// 2015-01-31 and 2015-05-31 are Saturday and Sunday.
+			RecurringItem{
+				Recurrence: RecurrenceLastBusinessDay,
+				Start:      date.New(2015, 1, 1),
+			},
+			date.New(2015, 5, 31),
+			[]date.Date{
+				date.New(2015, 1, 30),
+				date.New(2015, 2, 27),
+				date.New(2015, 3, 31),
+				date.New(2015, 4, 30),
+				date.New(2015, 5, 29),
+			},
+		},
+		{
+			RecurringItem{
+				Recurrence: RecurrenceWeekly,
+				Weeks:      2,
+				Start:      date.New(2015, 1, 5),
+			},
+			date.New(2015, 2, 15),
+			[]date.Date{
+				date.New(2015, 1, 5),
+				date.New(2015, 1, 19),
+				date.New(2015, 2, 2),
+			},
+		},
+		{
+			RecurringItem{
+				Recurrence: RecurrenceYearly,
+				Start:      date.New(2013, 4, 1),
+			},
+			date.New(2015, 3, 31),
+			[]date.Date{
+				date.New(2013, 4, 1),
+				date.New(2014, 4, 1),
+			},
+		},
+		{
+			RecurringItem{
+				Recurrence: RecurrenceWeekly,
+				Start:      date.New(2015, 1, 5),
+			},
+			date.New(2015, 2, 15),
+			[]date.Date{},
+		},
+	}
+	for _, test := range tests {
+		got := test.Item.Occurrences(test.Until)
+		if !reflect.DeepEqual(test.Expected, got) {
+			t.Errorf("expected %+v got %+v", test.Expected, got)
+		}
+	}
+}
+
+func TestOccurrenceID(t *testing.T) {
+	r := RecurringItem{
+		Meta:       Meta{ID: uuid.Generate()},
+		Subject:    "Rent",
+		Amount:     80000,
+		Recurrence: RecurrenceMonthly,
+		Day:        25,
+		Start:      date.New(2015, 1, 1),
+	}
+	if !r.IsValid() {
+		t.Errorf("expected valid got invalid: %+v", r)
+	}
+	d := date.New(2015, 1, 25)
+	item := r.Item(d)
+	if !item.IsValid() {
+		t.Errorf("expected valid got invalid: %+v", item)
+	}
+	if item.Meta.ID != r.OccurrenceID(d) {
+		t.Errorf("expected %+v got %+v", r.OccurrenceID(d), item.Meta.ID)
+	}
+	r2 := r
+	if r.OccurrenceID(d) != r2.OccurrenceID(d) {
+		t.Errorf("expected the same ID for the same occurrence")
+	}
+	if r.OccurrenceID(d) == r.OccurrenceID(d.AddDate(0, 1, 0)) {
+		t.Errorf("expected different IDs for different occurrences")
+	}
+	if item.RecurringID != r.Meta.ID {
+		t.Errorf("expected %+v got %+v", r.Meta.ID, item.RecurringID)
+	}
+	r3 := r
+	r3.Start = 0
+	if r3.IsValid() {
+		t.Errorf("expected invalid got valid: %+v", r3)
+	}
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Decoder decodes a JSON value into a pointer to a synced value.
+type Decoder func(raw json.RawMessage) (interface{}, error)
+
+// Validator reports whether the synced value is valid.
+type Validator func(v interface{}) bool
+
+// SyncedType is a type of values synced between clients and the server.
+type SyncedType struct {
+	// Name is the type name used in the sync protocol and as the name of
+	// the IndexedDB's object store.
+	Name string
+	// Kind is the kind of the App Engine's datastore entities.
+	Kind     string
+	Type     reflect.Type
+	Decode   Decoder
+	Validate Validator
+}
+
+var syncedTypes = map[string]*SyncedType{}
+
+// Register registers a synced type. Register panics if a type with the same
+// name is already registered.
+func Register(t *SyncedType) {
+	if _, ok := syncedTypes[t.Name]; ok {
+		panic(fmt.Sprintf("models: type %s is already registered", t.Name))
+	}
+	syncedTypes[t.Name] = t
+}
+
+// LookupSyncedType returns the synced type registered with the name.
+func LookupSyncedType(name string) (*SyncedType, error) {
+	t, ok := syncedTypes[name]
+	if !ok {
+		return nil, fmt.Errorf("models: unknown type: %s", name)
+	}
+	return t, nil
+}
+
+type sortSyncedTypes []*SyncedType
+
+func (s sortSyncedTypes) Len() int {
+	return len(s)
+}
+
+func (s sortSyncedTypes) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s sortSyncedTypes) Less(i, j int) bool {
+	return s[i].Name < s[j].Name
+}
+
+// SyncedTypes returns all the registered types sorted by their names.
+func SyncedTypes() []*SyncedType {
+	result := make([]*SyncedType, 0, len(syncedTypes))
+	for _, t := range syncedTypes {
+		result = append(result, t)
+	}
+	sort.Sort(sortSyncedTypes(result))
+	return result
+}
+
+// New returns a pointer to a new zero value of the type.
+func (t *SyncedType) New() interface{} {
+	return reflect.New(t.Type).Interface()
+}
+
+// DecodeValues decodes a JSON array and validates each value.
+func (t *SyncedType) DecodeValues(raw json.RawMessage) ([]interface{}, error) {
+	raws := []json.RawMessage{}
+	if err := json.Unmarshal(raw, &raws); err != nil {
+		return nil, err
+	}
+	result := make([]interface{}, len(raws))
+	for i, r := range raws {
+		v, err := t.Decode(r)
+		if err != nil {
+			return nil, err
+		}
+		if !t.Validate(v) {
+			e := fmt.Sprintf("models: invalid value of %s", t.Name)
+			return nil, errors.New(e)
+		}
+		result[i] = v
+	}
+	return result, nil
+}
+
+// MetaOf returns the Meta of a synced value.
+func MetaOf(v interface{}) *Meta {
+	m := reflect.ValueOf(v).Elem().FieldByName("Meta")
+	return m.Addr().Interface().(*Meta)
+}
+
+type validatable interface {
+	IsValid() bool
+}
+
+// registerModel registers a type of this package, which has IsValid method
+// and is encoded as a plain JSON object.
+func registerModel(v validatable, kind string) {
+	t := reflect.TypeOf(v).Elem()
+	Register(&SyncedType{
+		Name: t.Name(),
+		Kind: kind,
+		Type: t,
+		Decode: func(raw json.RawMessage) (interface{}, error) {
+			v := reflect.New(t).Interface()
+			if err := json.Unmarshal(raw, v); err != nil {
+				return nil, err
+			}
+			return v, nil
+		},
+		Validate: func(v interface{}) bool {
+			return v.(validatable).IsValid()
+		},
+	})
+}
+
+func init() {
+	registerModel((*ItemData)(nil), "Items")
+	registerModel((*Category)(nil), "Categories")
+	registerModel((*Account)(nil), "Accounts")
+	registerModel((*Budget)(nil), "Budgets")
+	registerModel((*RecurringItem)(nil), "RecurringItems")
+	registerModel((*ExchangeRate)(nil), "ExchangeRates")
+	registerModel((*Settings)(nil), "Settings")
+	registerModel((*Attachment)(nil), "Attachments")
+	registerModel((*APIToken)(nil), "APITokens")
+	registerModel((*Ledger)(nil), "Ledgers")
+	registerModel((*Membership)(nil), "Memberships")
+}
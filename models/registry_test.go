@@ -0,0 +1,142 @@
+package models_test
+
+import (
+	"encoding/json"
+	"github.com/hajimehoshi/kakeibo/date"
+	. "github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSyncRequestRoundTrip(t *testing.T) {
+	lastUpdated := time.Date(2014, 5, 6, 7, 8, 9, 0, time.UTC)
+	parentID := uuid.Generate()
+	tests := []SyncRequest{
+		{
+			Type:        "ItemData",
+			LastUpdated: lastUpdated,
+			Values: []interface{}{
+				&ItemData{
+					Meta:      Meta{ID: uuid.Generate()},
+					Date:      date.New(2014, 5, 6),
+					Subject:   "Lunch",
+					Amount:    800,
+					Direction: DirectionExpense,
+				},
+				&ItemData{
+					Meta:      Meta{ID: uuid.Generate()},
+					Date:      date.New(2014, 5, 25),
+					Subject:   "Salary",
+					Amount:    200000,
+					Direction: DirectionIncome,
+				},
+				&ItemData{
+					Meta:      Meta{ID: uuid.Generate()},
+					Date:      date.New(2014, 5, 26),
+					Subject:   "Souvenir",
+					Amount:    1250,
+					Currency:  "USD",
+					Direction: DirectionExpense,
+					Tags:      []string{"trip-okinawa-2026"},
+					Note:      "For the neighbors",
+				},
+			},
+		},
+		{
+			Type:        "Category",
+			LastUpdated: lastUpdated,
+			Cursor:      "cursor",
+			Limit:       100,
+			Values: []interface{}{
+				&Category{
+					Meta: Meta{ID: parentID},
+					Name: "Food",
+				},
+				&Category{
+					Meta:     Meta{ID: uuid.Generate()},
+					ParentID: parentID,
+					Name:     "Dining out",
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		b, err := json.Marshal(test)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := SyncRequest{}
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(test, got) {
+			t.Errorf("expected %+v got %+v", test, got)
+		}
+
+		res := SyncResponse{
+			Type:        test.Type,
+			LastUpdated: test.LastUpdated,
+			Values:      test.Values,
+			Rejected:    test.Values[:1],
+			Cursor:      test.Cursor,
+		}
+		b, err = json.Marshal(res)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotRes := SyncResponse{}
+		if err := json.Unmarshal(b, &gotRes); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(res, gotRes) {
+			t.Errorf("expected %+v got %+v", res, gotRes)
+		}
+	}
+}
+
+func TestSyncRequestUnknownType(t *testing.T) {
+	b := []byte(`{"Type":"Unknown","Values":[]}`)
+	req := SyncRequest{}
+	if err := json.Unmarshal(b, &req); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestSyncRequestInvalidValue(t *testing.T) {
+	// A category without a name is invalid.
+	req := SyncRequest{
+		Type: "Category",
+		Values: []interface{}{
+			&Category{Meta: Meta{ID: uuid.Generate()}},
+		},
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &SyncRequest{}); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestSyncedTypes(t *testing.T) {
+	for _, st := range SyncedTypes() {
+		got, err := LookupSyncedType(st.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != st {
+			t.Errorf("expected %+v got %+v", st, got)
+		}
+		if st.Name != st.Type.Name() {
+			t.Errorf("expected %+v got %+v", st.Type.Name(), st.Name)
+		}
+		v := st.New()
+		if MetaOf(v) == nil {
+			t.Errorf("%s doesn't have Meta", st.Name)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"github.com/hajimehoshi/kakeibo/currency"
+	"github.com/hajimehoshi/kakeibo/uuid"
+)
+
+// settingsNamespace is the base to derive the IDs of settings.
+const settingsNamespace uuid.UUID = "5a0bd0f6-2a4c-4c1e-9d6e-6b5c1f0e8a37"
+
+// Settings is the preferences of a user. Each user has one Settings whose ID
+// is SettingsID.
+type Settings struct {
+	Meta Meta
+	// BaseCurrency is the currency which totals are converted to. An empty
+	// BaseCurrency means currency.Default.
+	BaseCurrency currency.Code `json:",omitempty"`
+}
+
+// SettingsID returns the ID of the user's settings. As the ID is derived from
+// the user, clients creating the settings at the same time create the same
+// value.
+func SettingsID(user string) uuid.UUID {
+	return uuid.Derive(settingsNamespace, user)
+}
+
+func (s *Settings) IsValid() bool {
+	if !s.Meta.IsValid() {
+		return false
+	}
+	if s.BaseCurrency != "" && !s.BaseCurrency.IsValid() {
+		return false
+	}
+	return true
+}
+
+// Base returns the base currency.
+func (s *Settings) Base() currency.Code {
+	if s.BaseCurrency == "" {
+		return currency.Default
+	}
+	return s.BaseCurrency
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+func isTagSeparator(r rune) bool {
+	return unicode.IsSpace(r) || r == ','
+}
+
+// IsValidTag reports whether the tag is not empty and doesn't contain
+// whitespaces or commas, which separate tags.
+func IsValidTag(tag string) bool {
+	if tag == "" {
+		return false
+	}
+	return strings.IndexFunc(tag, isTagSeparator) == -1
+}
+
+func areTagsValid(tags []string) bool {
+	found := map[string]struct{}{}
+	for _, t := range tags {
+		if !IsValidTag(t) {
+			return false
+		}
+		if _, ok := found[t]; ok {
+			return false
+		}
+		found[t] = struct{}{}
+	}
+	return true
+}
+
+// ParseTags parses tags separated by whitespaces or commas like
+// 'trip-okinawa-2026, reimbursable'. Duplicated tags are removed. ParseTags
+// returns nil if there are no tags.
+func ParseTags(str string) []string {
+	var tags []string
+	found := map[string]struct{}{}
+	for _, t := range strings.FieldsFunc(str, isTagSeparator) {
+		if _, ok := found[t]; ok {
+			continue
+		}
+		found[t] = struct{}{}
+		tags = append(tags, t)
+	}
+	return tags
+}
+
+// HasTag reports whether the item has the tag.
+func (i *ItemData) HasTag(tag string) bool {
+	for _, t := range i.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package models_test
+
+import (
+	. "github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"reflect"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		Str      string
+		Expected []string
+	}{
+		{"", nil},
+		{" , ", nil},
+		{"reimbursable", []string{"reimbursable"}},
+		{
+			"trip-okinawa-2026, reimbursable\ttrip-okinawa-2026",
+			[]string{"trip-okinawa-2026", "reimbursable"},
+		},
+		{"旅行　沖縄", []string{"旅行", "沖縄"}},
+	}
+	for _, test := range tests {
+		got := ParseTags(test.Str)
+		if !reflect.DeepEqual(test.Expected, got) {
+			t.Errorf("expected %+v got %+v", test.Expected, got)
+		}
+	}
+}
+
+func TestItemDataTags(t *testing.T) {
+	tests := []struct {
+		Tags  []string
+		Valid bool
+	}{
+		{nil, true},
+		{[]string{"trip-okinawa-2026", "reimbursable"}, true},
+		{[]string{""}, false},
+		{[]string{"a b"}, false},
+		{[]string{"a,b"}, false},
+		{[]string{"a", "a"}, false},
+	}
+	for _, test := range tests {
+		item := ItemData{
+			Meta:    Meta{ID: uuid.Generate()},
+			Subject: "Lunch",
+			Tags:    test.Tags,
+		}
+		got := item.IsValid()
+		if test.Valid != got {
+			t.Errorf("%+v: expected %+v got %+v", test.Tags, test.Valid, got)
+		}
+	}
+}
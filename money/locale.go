@@ -0,0 +1,45 @@
+package money
+
+import (
+	"strings"
+)
+
+// Locale is the separators to format amounts for people.
+type Locale struct {
+	// Decimal is the decimal separator like '.'.
+	Decimal string
+	// Group is the separator of every three digits like ','. An empty Group
+	// means no grouping.
+	Group string
+}
+
+var DefaultLocale = Locale{Decimal: ".", Group: ","}
+
+var locales = map[string]Locale{
+	"de":    {Decimal: ",", Group: "."},
+	"de-ch": {Decimal: ".", Group: "\u2019"},
+	"en":    {Decimal: ".", Group: ","},
+	"es":    {Decimal: ",", Group: "."},
+	"fr":    {Decimal: ",", Group: "\u202f"},
+	"it":    {Decimal: ",", Group: "."},
+	"ja":    {Decimal: ".", Group: ","},
+	"pt":    {Decimal: ",", Group: "."},
+	"ru":    {Decimal: ",", Group: "\u00a0"},
+}
+
+// LookupLocale returns the locale for a BCP 47 language tag like 'en-US' or
+// 'de-CH'. DefaultLocale is returned for unknown languages.
+func LookupLocale(tag string) Locale {
+	tag = strings.ToLower(strings.Replace(tag, "_", "-", -1))
+	for tag != "" {
+		if l, ok := locales[tag]; ok {
+			return l
+		}
+		i := strings.LastIndex(tag, "-")
+		if i == -1 {
+			break
+		}
+		tag = tag[:i]
+	}
+	return DefaultLocale
+}
@@ -0,0 +1,248 @@
+// Package money provides a fixed-point amount of money.
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hajimehoshi/kakeibo/currency"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Amount is an amount of money in the minor unit of its currency, like cents
+// for USD. The currency is not a part of Amount.
+//
+// Amount is encoded as a JSON number and stored as an integer in the
+// datastore, which are the same as the former int32 amounts.
+type Amount int64
+
+// MaxAmount is the maximum absolute value of Amount. Amounts are limited to
+// the integers which JavaScript's numbers can represent exactly, as amounts
+// are stored in IndexedDB as JSON.
+const MaxAmount Amount = 1<<53 - 1
+
+var ErrOverflow = errors.New("money: overflow")
+
+// IsValid reports whether the amount is in the range of MaxAmount.
+func (a Amount) IsValid() bool {
+	return -MaxAmount <= a && a <= MaxAmount
+}
+
+func checked(a Amount) (Amount, error) {
+	if !a.IsValid() {
+		return 0, ErrOverflow
+	}
+	return a, nil
+}
+
+// Add returns a+b. The arguments must be valid.
+func (a Amount) Add(b Amount) (Amount, error) {
+	// As the arguments are in the range of MaxAmount, the sum never
+	// overflows int64.
+	return checked(a + b)
+}
+
+// Sub returns a-b. The arguments must be valid.
+func (a Amount) Sub(b Amount) (Amount, error) {
+	return checked(a - b)
+}
+
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+func round(r *big.Rat) (Amount, error) {
+	num := new(big.Int).Abs(r.Num())
+	q, m := new(big.Int).QuoRem(num, r.Denom(), new(big.Int))
+	m.Mul(m, big.NewInt(2))
+	if r.Denom().Cmp(m) <= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if r.Sign() < 0 {
+		q.Neg(q)
+	}
+	if !q.IsInt64() {
+		return 0, ErrOverflow
+	}
+	return checked(Amount(q.Int64()))
+}
+
+// MulRat returns the amount multiplied by the ratio. The result is rounded
+// half away from zero.
+func (a Amount) MulRat(ratio *big.Rat) (Amount, error) {
+	r := new(big.Rat).SetInt64(int64(a))
+	r.Mul(r, ratio)
+	return round(r)
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
+
+// Convert converts the amount in the minor unit of from to the minor unit of
+// to. rate is the value of one major unit of from in major units of to, like
+// 150 for USD to JPY. The result is rounded half away from zero.
+func (a Amount) Convert(
+	from currency.Code,
+	to currency.Code,
+	rate *big.Rat) (Amount, error) {
+	ratio := new(big.Rat).Set(rate)
+	shift := to.MinorUnits() - from.MinorUnits()
+	if 0 <= shift {
+		ratio.Mul(ratio, new(big.Rat).SetInt64(pow10(shift)))
+	} else {
+		ratio.Quo(ratio, new(big.Rat).SetInt64(pow10(-shift)))
+	}
+	return a.MulRat(ratio)
+}
+
+// Format formats the amount in the currency without grouping, like '1234.50'
+// for 123450 in USD. This is for machines like CSV files.
+func (a Amount) Format(code currency.Code) string {
+	return a.FormatLocale(code, Locale{Decimal: "."})
+}
+
+// FormatLocale formats the amount in the currency for the locale, like
+// '1,234.50' in English or '1.234,50' in German.
+func (a Amount) FormatLocale(code currency.Code, l Locale) string {
+	digits := code.MinorUnits()
+	sign := ""
+	if a < 0 {
+		sign = "-"
+	}
+	p := Amount(pow10(digits))
+	integer := strconv.FormatInt(int64(a.Abs()/p), 10)
+	if l.Group != "" {
+		groups := []string{}
+		for 3 < len(integer) {
+			n := len(integer) - 3
+			groups = append([]string{integer[n:]}, groups...)
+			integer = integer[:n]
+		}
+		groups = append([]string{integer}, groups...)
+		integer = strings.Join(groups, l.Group)
+	}
+	if digits == 0 {
+		return sign + integer
+	}
+	fraction := fmt.Sprintf("%0*d", digits, int64(a.Abs()%p))
+	return sign + integer + l.Decimal + fraction
+}
+
+// Parse parses an amount like '1234.50' in the currency. Parse returns an
+// error if the amount has more digits after the decimal point than the
+// currency has.
+func Parse(str string, code currency.Code) (Amount, error) {
+	str = strings.TrimSpace(str)
+	negative := false
+	switch {
+	case strings.HasPrefix(str, "-"):
+		negative = true
+		str = str[1:]
+	case strings.HasPrefix(str, "+"):
+		str = str[1:]
+	}
+	integer, fraction := str, ""
+	if i := strings.Index(str, "."); i != -1 {
+		integer, fraction = str[:i], str[i+1:]
+	}
+	if integer == "" && fraction == "" {
+		return 0, fmt.Errorf("money: invalid amount: %q", str)
+	}
+	digits := code.MinorUnits()
+	if digits < len(fraction) {
+		e := fmt.Sprintf(
+			"money: %s has %d digits after the decimal point",
+			code,
+			digits)
+		return 0, errors.New(e)
+	}
+	fraction += strings.Repeat("0", digits-len(fraction))
+	result := Amount(0)
+	for _, r := range integer + fraction {
+		if r < '0' || '9' < r {
+			return 0, fmt.Errorf("money: invalid amount: %q", str)
+		}
+		result = result*10 + Amount(r-'0')
+		if !result.IsValid() {
+			return 0, ErrOverflow
+		}
+	}
+	if negative {
+		result = -result
+	}
+	return result, nil
+}
+
+// ParseLocale parses an amount formatted for the locale like '1.234,50' in
+// German. Group separators are optional, but must be at every three digits.
+func ParseLocale(str string, code currency.Code, l Locale) (Amount, error) {
+	str = strings.TrimSpace(str)
+	if l.Group != "" && strings.TrimSpace(l.Group) == "" {
+		// Spaces are used instead of special spaces like U+202F.
+		str = strings.Replace(str, " ", l.Group, -1)
+	}
+	integer, fraction := str, ""
+	hasFraction := false
+	if i := strings.LastIndex(str, l.Decimal); i != -1 {
+		integer, fraction = str[:i], str[i+len(l.Decimal):]
+		hasFraction = true
+	}
+	if l.Group != "" && strings.Contains(integer, l.Group) {
+		groups := strings.Split(integer, l.Group)
+		first := strings.TrimLeft(groups[0], "+-")
+		if len(first) < 1 || 3 < len(first) {
+			return 0, fmt.Errorf("money: invalid grouping: %q", str)
+		}
+		for _, g := range groups[1:] {
+			if len(g) != 3 {
+				e := fmt.Sprintf("money: invalid grouping: %q", str)
+				return 0, errors.New(e)
+			}
+		}
+		integer = strings.Join(groups, "")
+	}
+	if hasFraction {
+		return Parse(integer+"."+fraction, code)
+	}
+	return Parse(integer, code)
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	if !a.IsValid() {
+		return nil, ErrOverflow
+	}
+	return []byte(strconv.FormatInt(int64(a), 10)), nil
+}
+
+// UnmarshalJSON decodes a JSON number in the minor unit. A number with a
+// fraction or out of the range of MaxAmount is an error.
+func (a *Amount) UnmarshalJSON(b []byte) error {
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	// json.Number accepts a number in a string like "12".
+	if strings.HasPrefix(string(b), `"`) {
+		return fmt.Errorf("money: invalid amount: %s", b)
+	}
+	v, err := strconv.ParseInt(n.String(), 10, 64)
+	if err != nil {
+		return fmt.Errorf("money: invalid amount: %s", n)
+	}
+	result, err := checked(Amount(v))
+	if err != nil {
+		return err
+	}
+	*a = result
+	return nil
+}
@@ -0,0 +1,249 @@
+package money_test
+
+import (
+	"encoding/json"
+	"github.com/hajimehoshi/kakeibo/currency"
+	. "github.com/hajimehoshi/kakeibo/money"
+	"math/big"
+	"testing"
+)
+
+func TestAddSub(t *testing.T) {
+	if _, err := MaxAmount.Add(1); err != ErrOverflow {
+		t.Errorf("expected %+v got %+v", ErrOverflow, err)
+	}
+	if _, err := (-MaxAmount).Sub(1); err != ErrOverflow {
+		t.Errorf("expected %+v got %+v", ErrOverflow, err)
+	}
+	a, err := Amount(1234).Sub(2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != -766 {
+		t.Errorf("expected %+v got %+v", -766, a)
+	}
+}
+
+func TestMulRat(t *testing.T) {
+	tests := []struct {
+		Amount   Amount
+		Ratio    string
+		Expected Amount
+	}{
+		{1000, "1/3", 333},
+		{1000, "2/3", 667},
+		// Half is rounded away from zero.
+		{5, "1/2", 3},
+		{-5, "1/2", -3},
+		{-1000, "2/3", -667},
+		{0, "5", 0},
+	}
+	for _, test := range tests {
+		ratio, _ := new(big.Rat).SetString(test.Ratio)
+		got, err := test.Amount.MulRat(ratio)
+		if err != nil {
+			t.Errorf("%+v: %v", test, err)
+			continue
+		}
+		if test.Expected != got {
+			t.Errorf("expected %+v got %+v", test.Expected, got)
+		}
+	}
+	if _, err := MaxAmount.MulRat(big.NewRat(2, 1)); err != ErrOverflow {
+		t.Errorf("expected %+v got %+v", ErrOverflow, err)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		Amount   Amount
+		From     currency.Code
+		To       currency.Code
+		Rate     string
+		Expected Amount
+	}{
+		// USD 12.34 at 150.25 JPY/USD is JPY 1854.085.
+		{1234, "USD", "JPY", "150.25", 1854},
+		// USD 12.35 at 150.2 JPY/USD is JPY 1854.97.
+		{1235, "USD", "JPY", "150.2", 1855},
+		{-1235, "USD", "JPY", "150.2", -1855},
+		// JPY 1000 at 0.0066 USD/JPY is USD 6.60.
+		{1000, "JPY", "USD", "0.0066", 660},
+		// KWD 1.234 at 3.25 USD/KWD is USD 4.0105.
+		{1234, "KWD", "USD", "3.25", 401},
+		// Half is rounded away from zero.
+		{1, "USD", "JPY", "50", 1},
+		{-1, "USD", "JPY", "50", -1},
+		{1000, "JPY", "KWD", "0.0021", 2100},
+	}
+	for _, test := range tests {
+		rate, err := currency.ParseRate(test.Rate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := test.Amount.Convert(test.From, test.To, rate)
+		if err != nil {
+			t.Errorf("%+v: %v", test, err)
+			continue
+		}
+		if test.Expected != got {
+			t.Errorf("expected %+v got %+v", test.Expected, got)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	de := LookupLocale("de-DE")
+	tests := []struct {
+		Amount   Amount
+		Code     currency.Code
+		Locale   Locale
+		Expected string
+	}{
+		{1234, "JPY", Locale{Decimal: "."}, "1234"},
+		{-1234, "JPY", Locale{Decimal: "."}, "-1234"},
+		{1234, "USD", Locale{Decimal: "."}, "12.34"},
+		{-5, "USD", Locale{Decimal: "."}, "-0.05"},
+		{1234, "KWD", Locale{Decimal: "."}, "1.234"},
+		{1234567, "JPY", DefaultLocale, "1,234,567"},
+		{-123456, "JPY", DefaultLocale, "-123,456"},
+		{123456789, "USD", de, "1.234.567,89"},
+		{100, "USD", de, "1,00"},
+		{123456789, "EUR", LookupLocale("fr"), "1\u202f234\u202f567,89"},
+		{123456789, "CHF", LookupLocale("de-CH"), "1’234’567.89"},
+		{MaxAmount, "JPY", DefaultLocale, "9,007,199,254,740,991"},
+	}
+	for _, test := range tests {
+		got := test.Amount.FormatLocale(test.Code, test.Locale)
+		if test.Expected != got {
+			t.Errorf("expected %+v got %+v", test.Expected, got)
+		}
+	}
+	if got := Amount(-123450).Format("USD"); got != "-1234.50" {
+		t.Errorf("expected %+v got %+v", "-1234.50", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		Str      string
+		Code     currency.Code
+		Expected Amount
+		Error    bool
+	}{
+		{"1234", "JPY", 1234, false},
+		{" -1234 ", "JPY", -1234, false},
+		{"12.3", "JPY", 0, true},
+		{"12.34", "USD", 1234, false},
+		{"12.3", "USD", 1230, false},
+		{"12", "USD", 1200, false},
+		{".5", "USD", 50, false},
+		{"12.345", "USD", 0, true},
+		{"1.234", "KWD", 1234, false},
+		{"1,234", "JPY", 0, true},
+		{"", "JPY", 0, true},
+		{"-", "JPY", 0, true},
+		{"9007199254740991", "JPY", MaxAmount, false},
+		{"9007199254740992", "JPY", 0, true},
+		{"99999999999999999999", "JPY", 0, true},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.Str, test.Code)
+		if test.Error {
+			if err == nil {
+				t.Errorf("expected an error for %q", test.Str)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.Str, err)
+			continue
+		}
+		if test.Expected != got {
+			t.Errorf("expected %+v got %+v", test.Expected, got)
+		}
+	}
+}
+
+func TestParseLocale(t *testing.T) {
+	tests := []struct {
+		Str      string
+		Code     currency.Code
+		Locale   string
+		Expected Amount
+		Error    bool
+	}{
+		{"1,234,567", "JPY", "en-US", 1234567, false},
+		{"-1,234", "JPY", "ja", -1234, false},
+		{"1234", "JPY", "ja", 1234, false},
+		{"1,234.5", "USD", "en", 123450, false},
+		{"1.234,5", "EUR", "de", 123450, false},
+		{"12,50", "EUR", "de-AT", 1250, false},
+		{"1\u202f234,50", "EUR", "fr-FR", 123450, false},
+		{"1 234,50", "EUR", "fr", 123450, false},
+		{"1’234.50", "CHF", "de-CH", 123450, false},
+		// Group separators must be at every three digits.
+		{"12,34", "JPY", "en", 0, true},
+		{"1234,567", "JPY", "en", 0, true},
+		{",123", "JPY", "en", 0, true},
+		// The decimal separator of another locale is an error.
+		{"12.50", "EUR", "de", 0, true},
+	}
+	for _, test := range tests {
+		l := LookupLocale(test.Locale)
+		got, err := ParseLocale(test.Str, test.Code, l)
+		if test.Error {
+			if err == nil {
+				t.Errorf("expected an error for %q", test.Str)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.Str, err)
+			continue
+		}
+		if test.Expected != got {
+			t.Errorf("expected %+v got %+v", test.Expected, got)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	// Amounts were int32 before Amount existed.
+	legacy := struct {
+		Amount int32
+	}{-1234}
+	b, err := json.Marshal(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v struct {
+		Amount Amount
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Amount != -1234 {
+		t.Errorf("expected %+v got %+v", -1234, v.Amount)
+	}
+	b2, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(b2) {
+		t.Errorf("expected %+v got %+v", string(b), string(b2))
+	}
+
+	for _, str := range []string{
+		`{"Amount":1.5}`,
+		`{"Amount":"12"}`,
+		`{"Amount":9007199254740992}`,
+	} {
+		if err := json.Unmarshal([]byte(str), &v); err == nil {
+			t.Errorf("expected an error for %s", str)
+		}
+	}
+	if _, err := json.Marshal(Amount(MaxAmount + 1)); err == nil {
+		t.Errorf("expected an error")
+	}
+}
@@ -0,0 +1,221 @@
+// Package ofx parses bank statements in OFX (Open Financial Exchange). Both
+// OFX 1.x, which is SGML and doesn't close elements with values, and OFX 2.x,
+// which is XML, are supported. QFX is OFX with some extra elements.
+package ofx
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hajimehoshi/kakeibo/date"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// Transaction is a STMTTRN record.
+type Transaction struct {
+	// Type is TRNTYPE like 'DEBIT' or 'CREDIT'.
+	Type       string
+	DatePosted date.Date
+	// Amount is TRNAMT as it is like '-1234.56'. A negative amount is a
+	// debit.
+	Amount string
+	// FITID is the ID of the transaction which is unique in the account.
+	FITID string
+	Name  string
+	Memo  string
+}
+
+// Statement is a statement of a bank account (STMTRS) or a credit card
+// (CCSTMTRS).
+type Statement struct {
+	Currency string
+	// BankID is empty for a credit card.
+	BankID       string
+	AccountID    string
+	Transactions []Transaction
+}
+
+type element struct {
+	name     string
+	value    string
+	hasValue bool
+	children []*element
+}
+
+func (e *element) child(name string) *element {
+	for _, c := range e.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (e *element) childValue(name string) string {
+	c := e.child(name)
+	if c == nil {
+		return ""
+	}
+	return c.value
+}
+
+// findAll returns the descendants with the given name.
+func (e *element) findAll(name string) []*element {
+	result := []*element{}
+	for _, c := range e.children {
+		if c.name == name {
+			result = append(result, c)
+			continue
+		}
+		result = append(result, c.findAll(name)...)
+	}
+	return result
+}
+
+// parseElements parses the elements of both SGML and XML. An element with a
+// value is closed implicitly by the next tag as SGML allows.
+func parseElements(text string) (*element, error) {
+	root := &element{}
+	stack := []*element{root}
+	top := func() *element {
+		return stack[len(stack)-1]
+	}
+	for {
+		start := strings.Index(text, "<")
+		if start == -1 {
+			break
+		}
+		if value := strings.TrimSpace(text[:start]); value != "" {
+			e := top()
+			if e == root {
+				// The header of OFX 1.x
+				text = text[start:]
+				continue
+			}
+			e.value = html.UnescapeString(value)
+			e.hasValue = true
+		}
+		end := strings.Index(text[start:], ">")
+		if end == -1 {
+			return nil, errors.New("ofx: unclosed tag")
+		}
+		tag := text[start+1 : start+end]
+		text = text[start+end+1:]
+
+		// Processing instructions and declarations
+		if strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") {
+			continue
+		}
+		if strings.HasPrefix(tag, "/") {
+			name := strings.TrimSpace(tag[1:])
+			for i := len(stack) - 1; 0 < i; i-- {
+				if stack[i].name != name {
+					continue
+				}
+				stack = stack[:i]
+				break
+			}
+			continue
+		}
+		selfClosing := strings.HasSuffix(tag, "/")
+		name := strings.TrimSpace(strings.TrimSuffix(tag, "/"))
+		if i := strings.IndexAny(name, " \t\r\n"); i != -1 {
+			name = name[:i]
+		}
+		if top().hasValue {
+			stack = stack[:len(stack)-1]
+		}
+		e := &element{name: name}
+		parent := top()
+		parent.children = append(parent.children, e)
+		if !selfClosing {
+			stack = append(stack, e)
+		}
+	}
+	if root.child("OFX") == nil {
+		return nil, errors.New("ofx: OFX element not found")
+	}
+	return root, nil
+}
+
+// parseDate parses a date like '20150105' or '20150105120000.000[-5:EST]'.
+// The time and the time zone are ignored.
+func parseDate(str string) (date.Date, error) {
+	if len(str) < 8 {
+		return 0, fmt.Errorf("ofx: invalid date: %s", str)
+	}
+	year, err := strconv.Atoi(str[0:4])
+	if err != nil {
+		return 0, fmt.Errorf("ofx: invalid date: %s", str)
+	}
+	month, err := strconv.Atoi(str[4:6])
+	if err != nil || month < 1 || 12 < month {
+		return 0, fmt.Errorf("ofx: invalid date: %s", str)
+	}
+	day, err := strconv.Atoi(str[6:8])
+	if err != nil || day < 1 || 31 < day {
+		return 0, fmt.Errorf("ofx: invalid date: %s", str)
+	}
+	d := date.New(year, 1, 1).AddDate(0, month-1, day-1)
+	if int(d.Month()) != month {
+		return 0, fmt.Errorf("ofx: invalid date: %s", str)
+	}
+	return d, nil
+}
+
+func parseTransaction(e *element) (Transaction, error) {
+	t := Transaction{
+		Type:   e.childValue("TRNTYPE"),
+		Amount: e.childValue("TRNAMT"),
+		FITID:  e.childValue("FITID"),
+		Name:   e.childValue("NAME"),
+		Memo:   e.childValue("MEMO"),
+	}
+	if t.FITID == "" {
+		return Transaction{}, errors.New("ofx: FITID not found")
+	}
+	if t.Amount == "" {
+		return Transaction{}, errors.New("ofx: TRNAMT not found")
+	}
+	d, err := parseDate(e.childValue("DTPOSTED"))
+	if err != nil {
+		return Transaction{}, err
+	}
+	t.DatePosted = d
+	return t, nil
+}
+
+// Parse parses an OFX document decoded to text.
+func Parse(text string) ([]Statement, error) {
+	root, err := parseElements(text)
+	if err != nil {
+		return nil, err
+	}
+	statements := []Statement{}
+	rss := append(root.findAll("STMTRS"), root.findAll("CCSTMTRS")...)
+	for _, rs := range rss {
+		s := Statement{
+			Currency:     rs.childValue("CURDEF"),
+			Transactions: []Transaction{},
+		}
+		if from := rs.child("BANKACCTFROM"); from != nil {
+			s.BankID = from.childValue("BANKID")
+			s.AccountID = from.childValue("ACCTID")
+		}
+		if from := rs.child("CCACCTFROM"); from != nil {
+			s.AccountID = from.childValue("ACCTID")
+		}
+		if list := rs.child("BANKTRANLIST"); list != nil {
+			for _, e := range list.findAll("STMTTRN") {
+				t, err := parseTransaction(e)
+				if err != nil {
+					return nil, err
+				}
+				s.Transactions = append(s.Transactions, t)
+			}
+		}
+		statements = append(statements, s)
+	}
+	return statements, nil
+}
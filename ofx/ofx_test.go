@@ -0,0 +1,102 @@
+package ofx_test
+
+import (
+	"github.com/hajimehoshi/kakeibo/date"
+	. "github.com/hajimehoshi/kakeibo/ofx"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		Path     string
+		Expected []Statement
+	}{
+		{
+			"testdata/statement_sgml.ofx",
+			[]Statement{
+				{
+					Currency:  "USD",
+					BankID:    "121000248",
+					AccountID: "0123456789",
+					Transactions: []Transaction{
+						{
+							Type:       "DEBIT",
+							DatePosted: date.New(2015, 1, 5),
+							Amount:     "-35.00",
+							FITID:      "2015010501",
+							Name:       "GROCERY & MORE",
+							Memo:       "POS PURCHASE",
+						},
+						{
+							Type:       "CREDIT",
+							DatePosted: date.New(2015, 1, 25),
+							Amount:     "2500.00",
+							FITID:      "2015012501",
+							Name:       "PAYROLL",
+						},
+					},
+				},
+			},
+		},
+		{
+			"testdata/statement_xml.qfx",
+			[]Statement{
+				{
+					Currency:  "JPY",
+					AccountID: "4111111111111111",
+					Transactions: []Transaction{
+						{
+							Type:       "DEBIT",
+							DatePosted: date.New(2015, 1, 10),
+							Amount:     "-1200",
+							FITID:      "A0001",
+							Name:       "書店",
+						},
+						{
+							Type:       "CREDIT",
+							DatePosted: date.New(2015, 1, 20),
+							Amount:     "300",
+							FITID:      "A0002",
+							Memo:       "Refund",
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		b, err := ioutil.ReadFile(test.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Parse(string(b))
+		if err != nil {
+			t.Errorf("%s: %v", test.Path, err)
+			continue
+		}
+		if !reflect.DeepEqual(test.Expected, got) {
+			t.Errorf("expected %+v got %+v", test.Expected, got)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"<HTML></HTML>",
+		"<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><BANKTRANLIST>" +
+			"<STMTTRN><TRNAMT>1<DTPOSTED>20150101</STMTTRN>" +
+			"</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>",
+		"<OFX><CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS><BANKTRANLIST>" +
+			"<STMTTRN><TRNAMT>1<FITID>1<DTPOSTED>20150230</STMTTRN>" +
+			"</BANKTRANLIST></CCSTMTRS></CCSTMTTRNRS>" +
+			"</CREDITCARDMSGSRSV1></OFX>",
+	}
+	for _, test := range tests {
+		if _, err := Parse(test); err == nil {
+			t.Errorf("expected an error for %q", test)
+		}
+	}
+}
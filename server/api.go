@@ -0,0 +1,304 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/hajimehoshi/kakeibo/blob"
+	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/storage"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// APIItemsPath is the path of the REST API of items.
+	APIItemsPath = "/api/items"
+
+	// MaxAPIRequestSize is the maximum size of a request body of the REST
+	// API in bytes.
+	MaxAPIRequestSize = 1 << 20
+)
+
+var errInvalidToken = errors.New("server: invalid API token")
+
+// apiTokenUser returns the ID and the email address of the user whose API
+// token is in the Authorization header like 'Bearer <token>'. The email
+// address is the creator of the token recorded by the server. Revoked tokens
+// and tokens without their creators are not accepted.
+func apiTokenUser(
+	r *http.Request,
+	b storage.Backend) (string, string, error) {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) {
+		return "", "", errInvalidToken
+	}
+	userID, hash, err := models.ParseAPIToken(auth[len(prefix):])
+	if err != nil {
+		return "", "", errInvalidToken
+	}
+	s, err := b.Open(userID, "APIToken")
+	if err != nil {
+		return "", "", err
+	}
+	tokens, err := storage.GetAll(s)
+	if err != nil {
+		return "", "", err
+	}
+	for _, v := range tokens {
+		t := v.(*models.APIToken)
+		if !t.Matches(hash) {
+			continue
+		}
+		if t.Meta.CreatedBy == "" {
+			return "", "", errInvalidToken
+		}
+		return userID, t.Meta.CreatedBy, nil
+	}
+	return "", "", errInvalidToken
+}
+
+type sortItemsByDate []*models.ItemData
+
+func (s sortItemsByDate) Len() int {
+	return len(s)
+}
+
+func (s sortItemsByDate) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s sortItemsByDate) Less(i, j int) bool {
+	if s[i].Date != s[j].Date {
+		return s[i].Date < s[j].Date
+	}
+	return s[i].Meta.ID < s[j].Meta.ID
+}
+
+// liveItems returns the items of the storage which are not deleted sorted by
+// date.
+func liveItems(s storage.Storage) ([]*models.ItemData, error) {
+	values, err := storage.GetAll(s)
+	if err != nil {
+		return nil, err
+	}
+	items := []*models.ItemData{}
+	for _, v := range values {
+		item := v.(*models.ItemData)
+		if item.Meta.IsDeleted {
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.Sort(sortItemsByDate(items))
+	return items, nil
+}
+
+// parseOptionalDate parses a date like '2006-01-02'. An empty value means no
+// date.
+func parseOptionalDate(value string) (date.Date, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := date.ParseISO8601(value)
+	if err != nil {
+		return 0, errors.New("server: invalid date: " + value)
+	}
+	return d, nil
+}
+
+// putItem stores the item in the same way as a sync, which validates the item
+// and stamps its Meta. Clients receive the item on their next sync. putItem
+// responds an error and returns false if the item is not stored.
+func putItem(
+	w http.ResponseWriter,
+	s storage.Storage,
+	item *models.ItemData,
+	email string) bool {
+	if !item.IsValid() {
+		http.Error(w, "server: invalid item", http.StatusBadRequest)
+		return false
+	}
+	stampUser([]interface{}{item}, email)
+	_, rejected, err := s.Put(time.Time{}, []interface{}{item})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	// The item is edited by another client at the same time.
+	if len(rejected) != 0 {
+		http.Error(w, "Conflict", http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+func writeItem(w http.ResponseWriter, item *models.ItemData, status int) {
+	b, err := json.Marshal(item)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+// HandleAPIItems handles the REST API of items authenticated by an API token:
+//
+//	GET    /api/items?from=2006-01-02&to=2006-01-31
+//	POST   /api/items
+//	GET    /api/items/<ID>
+//	PATCH  /api/items/<ID>
+//	DELETE /api/items/<ID>
+//
+// Items are encoded in JSON as in a sync. 'from' and 'to' are optional and
+// inclusive. The Meta of a posted item is ignored. A PATCH request contains
+// the fields to update. Deleting an item deletes its attachments as a sync
+// does. The items are of the ledger in the 'ledger' parameter if it is
+// specified.
+func HandleAPIItems(
+	w http.ResponseWriter,
+	r *http.Request,
+	b storage.Backend,
+	blobs blob.Store) {
+	userID, email, err := apiTokenUser(r, b)
+	if err == errInvalidToken {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	write := r.Method != "GET"
+	ns, ok := requestNamespace(w, r, b, userID, email, write)
+	if !ok {
+		return
+	}
+	s, err := b.Open(ns, "ItemData")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, MaxAPIRequestSize)
+
+	path := strings.TrimPrefix(r.URL.Path, APIItemsPath)
+	if path == "" || path == "/" {
+		switch r.Method {
+		case "GET":
+			handleListItems(w, r, s)
+		case "POST":
+			handleCreateItem(w, r, s, email)
+		default:
+			http.Error(w, "Method Not Allowed",
+				http.StatusMethodNotAllowed)
+		}
+		return
+	}
+	id, err := uuid.ParseString(strings.TrimPrefix(path, "/"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	items, err := liveItems(s)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var item *models.ItemData
+	for _, i := range items {
+		if i.Meta.ID == id {
+			item = i
+			break
+		}
+	}
+	if item == nil {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		writeItem(w, item, http.StatusOK)
+	case "PATCH":
+		meta := item.Meta
+		if err := json.NewDecoder(r.Body).Decode(item); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// The update is based on the stored item.
+		item.Meta = meta
+		if putItem(w, s, item, email) {
+			writeItem(w, item, http.StatusOK)
+		}
+	case "DELETE":
+		item.Destroy()
+		if !putItem(w, s, item, email) {
+			return
+		}
+		values := []interface{}{item}
+		err := followTombstones(b, blobs, ns, "ItemData", values)
+		if err != nil {
+			http.Error(w, err.Error(),
+				http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func handleListItems(
+	w http.ResponseWriter,
+	r *http.Request,
+	s storage.Storage) {
+	q := r.URL.Query()
+	from, err := parseOptionalDate(q.Get("from"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := parseOptionalDate(q.Get("to"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	items, err := liveItems(s)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	result := []*models.ItemData{}
+	for _, item := range items {
+		if from != 0 && item.Date < from {
+			continue
+		}
+		if to != 0 && to < item.Date {
+			continue
+		}
+		result = append(result, item)
+	}
+	writeJSON(w, result)
+}
+
+func handleCreateItem(
+	w http.ResponseWriter,
+	r *http.Request,
+	s storage.Storage,
+	email string) {
+	item := &models.ItemData{}
+	if err := json.NewDecoder(r.Body).Decode(item); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	item.Meta = models.Meta{ID: uuid.Generate()}
+	if putItem(w, s, item, email) {
+		writeItem(w, item, http.StatusCreated)
+	}
+}
@@ -0,0 +1,208 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/hajimehoshi/kakeibo/blob"
+	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/models"
+	. "github.com/hajimehoshi/kakeibo/server"
+	"github.com/hajimehoshi/kakeibo/storage"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func doAPI(
+	t *testing.T,
+	method string,
+	url string,
+	token string,
+	body string) *http.Response {
+	r, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func decodeItem(t *testing.T, res *http.Response) *models.ItemData {
+	defer res.Body.Close()
+	item := &models.ItemData{}
+	if err := json.NewDecoder(res.Body).Decode(item); err != nil {
+		t.Fatal(err)
+	}
+	return item
+}
+
+func TestAPIItems(t *testing.T) {
+	s := newServer(t)
+	defer s.Close()
+
+	tokenValue, hash, err := models.NewAPIToken(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := &models.APIToken{
+		Meta: models.Meta{ID: uuid.Generate()},
+		Name: "Phone",
+		Hash: hash,
+	}
+	req := &models.SyncRequest{
+		Type:   "APIToken",
+		Values: []interface{}{token},
+	}
+	tokenRes, code := sync(t, s.URL, req, password)
+	if code != http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, code)
+	}
+
+	url := s.URL + "/api/items"
+	res := doAPI(t, "GET", url, "", "")
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected %+v got %+v", http.StatusUnauthorized,
+			res.StatusCode)
+	}
+	// A token which is not saved is not accepted.
+	other, _, err := models.NewAPIToken(nonAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res = doAPI(t, "GET", url, other, "")
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected %+v got %+v", http.StatusUnauthorized,
+			res.StatusCode)
+	}
+
+	body := `{"Date":"2015-01-05","Subject":"Lunch","Amount":800}`
+	res = doAPI(t, "POST", url, tokenValue, body)
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("expected %+v got %+v", http.StatusCreated,
+			res.StatusCode)
+	}
+	lunch := decodeItem(t, res)
+	if lunch.Subject != "Lunch" || lunch.Meta.Revision != 1 {
+		t.Errorf("unexpected item: %+v", lunch)
+	}
+	body = `{"Date":"2015-02-05","Subject":"Dinner","Amount":1200}`
+	res = doAPI(t, "POST", url, tokenValue, body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("expected %+v got %+v", http.StatusCreated,
+			res.StatusCode)
+	}
+	res = doAPI(t, "POST", url, tokenValue, `{"Subject":""}`)
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected %+v got %+v", http.StatusBadRequest,
+			res.StatusCode)
+	}
+
+	// Web clients receive the items on their next sync.
+	req = &models.SyncRequest{Type: "ItemData"}
+	syncRes, code := sync(t, s.URL, req, password)
+	if code != http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, code)
+	}
+	if len(syncRes.Values) != 2 {
+		t.Errorf("expected 2 values got %+v", syncRes.Values)
+	}
+
+	res = doAPI(t, "GET", url+"?from=2015-01-01&to=2015-01-31",
+		tokenValue, "")
+	items := []*models.ItemData{}
+	if err := json.NewDecoder(res.Body).Decode(&items); err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if len(items) != 1 || items[0].Meta.ID != lunch.Meta.ID {
+		t.Errorf("expected %+v got %+v", lunch, items)
+	}
+
+	itemURL := url + "/" + lunch.Meta.ID.String()
+	res = doAPI(t, "PATCH", itemURL, tokenValue, `{"Subject":"Brunch"}`)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, res.StatusCode)
+	}
+	brunch := decodeItem(t, res)
+	if brunch.Subject != "Brunch" || brunch.Amount != 800 ||
+		brunch.Date != date.New(2015, 1, 5) ||
+		brunch.Meta.Revision != 2 {
+		t.Errorf("unexpected item: %+v", brunch)
+	}
+
+	res = doAPI(t, "DELETE", itemURL, tokenValue, "")
+	res.Body.Close()
+	if res.StatusCode != http.StatusNoContent {
+		t.Errorf("expected %+v got %+v", http.StatusNoContent,
+			res.StatusCode)
+	}
+	for _, method := range []string{"GET", "PATCH", "DELETE"} {
+		res = doAPI(t, method, itemURL, tokenValue, "{}")
+		res.Body.Close()
+		if res.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: expected %+v got %+v", method,
+				http.StatusNotFound, res.StatusCode)
+		}
+	}
+
+	// A revoked token is not accepted.
+	revoked := tokenRes.Values[0].(*models.APIToken)
+	revoked.Destroy()
+	req = &models.SyncRequest{
+		Type:   "APIToken",
+		Values: []interface{}{revoked},
+	}
+	if _, code := sync(t, s.URL, req, password); code != http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, code)
+	}
+	res = doAPI(t, "GET", url, tokenValue, "")
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected %+v got %+v", http.StatusUnauthorized,
+			res.StatusCode)
+	}
+}
+
+func TestAPITokenWithoutCreator(t *testing.T) {
+	b := storage.NewMemory()
+	tokenValue, hash, err := models.NewAPIToken(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The token is stored without the creator recorded by a sync.
+	token := &models.APIToken{
+		Meta: models.Meta{ID: uuid.Generate()},
+		Name: "Phone",
+		Hash: hash,
+	}
+	s, err := b.Open(email, "APIToken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Put(time.Time{}, []interface{}{token}); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := http.NewRequest("GET", APIItemsPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Authorization", "Bearer "+tokenValue)
+	w := httptest.NewRecorder()
+	HandleAPIItems(w, r, b, blob.NewMemory())
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected %+v got %+v", http.StatusUnauthorized, w.Code)
+	}
+}
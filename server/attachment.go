@@ -0,0 +1,234 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/hajimehoshi/kakeibo/blob"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/storage"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"time"
+)
+
+const (
+	// attachmentType is the name of the synced type of attachments.
+	attachmentType = "Attachment"
+
+	// attachmentsPath is the path under which the contents of attachments
+	// are uploaded and downloaded by their hashes.
+	attachmentsPath = "/attachments/"
+)
+
+// HandleUpload stores the content of an attachment in the request body. hash
+// is the SHA-256 hash of the content. A content which is already stored is
+// not stored again, and the response status is 200 instead of 201. The
+// content belongs to the ledger in the 'ledger' parameter if it is specified.
+func HandleUpload(
+	w http.ResponseWriter,
+	r *http.Request,
+	b storage.Backend,
+	blobs blob.Store,
+	userID string,
+	email string,
+	hash string) {
+	if !models.IsValidHash(hash) {
+		http.Error(w, "server: invalid hash", http.StatusBadRequest)
+		return
+	}
+	ns, ok := requestNamespace(w, r, b, userID, email, true)
+	if !ok {
+		return
+	}
+	// Read one more byte to know whether the content is too large.
+	body := io.LimitReader(r.Body, models.MaxAttachmentSize+1)
+	content, err := ioutil.ReadAll(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if models.MaxAttachmentSize < len(content) {
+		http.Error(
+			w,
+			"server: attachment is too large",
+			http.StatusRequestEntityTooLarge)
+		return
+	}
+	if len(content) == 0 {
+		http.Error(w, "server: empty attachment", http.StatusBadRequest)
+		return
+	}
+	sum := sha256.Sum256(content)
+	if hex.EncodeToString(sum[:]) != hash {
+		http.Error(w, "server: hash mismatch", http.StatusBadRequest)
+		return
+	}
+	has, err := blobs.Has(ns, hash)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if has {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if err := blobs.Put(ns, hash, content); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// liveAttachments returns the attachments of the user which are not deleted.
+func liveAttachments(
+	b storage.Backend,
+	userID string) ([]*models.Attachment, error) {
+	s, err := b.Open(userID, attachmentType)
+	if err != nil {
+		return nil, err
+	}
+	values, err := storage.GetAll(s)
+	if err != nil {
+		return nil, err
+	}
+	result := []*models.Attachment{}
+	for _, v := range values {
+		a := v.(*models.Attachment)
+		if a.Meta.IsDeleted {
+			continue
+		}
+		result = append(result, a)
+	}
+	return result, nil
+}
+
+// HandleDownload responds the content of the hash. The content is served only
+// while an attachment of the user, or of the ledger in the 'ledger' parameter,
+// which is not deleted refers to it.
+func HandleDownload(
+	w http.ResponseWriter,
+	r *http.Request,
+	b storage.Backend,
+	blobs blob.Store,
+	userID string,
+	email string,
+	hash string) {
+	ns, ok := requestNamespace(w, r, b, userID, email, false)
+	if !ok {
+		return
+	}
+	attachments, err := liveAttachments(b, ns)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var attachment *models.Attachment
+	for _, a := range attachments {
+		if a.Hash == hash {
+			attachment = a
+			break
+		}
+	}
+	if attachment == nil {
+		http.NotFound(w, r)
+		return
+	}
+	content, err := blobs.Get(ns, hash)
+	if err == blob.ErrNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h := w.Header()
+	h.Set("Content-Type", attachment.ContentType)
+	// Contents are uploaded by users, and must not run as pages of this
+	// site.
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Content-Security-Policy", "sandbox")
+	if attachment.Name != "" {
+		params := map[string]string{"filename": attachment.Name}
+		h.Set("Content-Disposition", mime.FormatMediaType("inline", params))
+	}
+	w.Write(content)
+}
+
+// followTombstones deletes the attachments of the items deleted by a sync
+// request, and then the contents which no attachments refer to anymore.
+// values are the values accepted by the sync request. Contents which fail to
+// be deleted are not deleted later, as no attachments refer to them anymore.
+func followTombstones(
+	b storage.Backend,
+	blobs blob.Store,
+	userID string,
+	typeName string,
+	values []interface{}) error {
+	hashes := map[string]struct{}{}
+	switch typeName {
+	case "ItemData":
+		deletedItems := map[uuid.UUID]struct{}{}
+		for _, v := range values {
+			meta := models.MetaOf(v)
+			if meta.IsDeleted {
+				deletedItems[meta.ID] = struct{}{}
+			}
+		}
+		if len(deletedItems) == 0 {
+			return nil
+		}
+		attachments, err := liveAttachments(b, userID)
+		if err != nil {
+			return err
+		}
+		toDelete := []interface{}{}
+		for _, a := range attachments {
+			if _, ok := deletedItems[a.ItemID]; !ok {
+				continue
+			}
+			a.Destroy()
+			toDelete = append(toDelete, a)
+			hashes[a.Hash] = struct{}{}
+		}
+		if len(toDelete) == 0 {
+			return nil
+		}
+		s, err := b.Open(userID, attachmentType)
+		if err != nil {
+			return err
+		}
+		// The attachments have the stored revisions and are never
+		// rejected unless they are updated at the same time. Such
+		// attachments are deleted by the client later.
+		if _, _, err := s.Put(time.Time{}, toDelete); err != nil {
+			return err
+		}
+	case attachmentType:
+		for _, v := range values {
+			a := v.(*models.Attachment)
+			if a.Meta.IsDeleted {
+				hashes[a.Hash] = struct{}{}
+			}
+		}
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	attachments, err := liveAttachments(b, userID)
+	if err != nil {
+		return err
+	}
+	for _, a := range attachments {
+		delete(hashes, a.Hash)
+	}
+	for hash := range hashes {
+		if err := blobs.Delete(userID, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package server_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func hashOf(content []byte) string {
+	h := sha256.Sum256(content)
+	return hex.EncodeToString(h[:])
+}
+
+func TestUpload(t *testing.T) {
+	s := newServer(t)
+	defer s.Close()
+
+	content := []byte("receipt")
+	hash := hashOf(content)
+	large := make([]byte, models.MaxAttachmentSize+1)
+	tests := []struct {
+		Hash     string
+		Content  []byte
+		Expected int
+	}{
+		{hash, content, http.StatusCreated},
+		// The same content is not stored again.
+		{hash, content, http.StatusOK},
+		{hash, []byte("other"), http.StatusBadRequest},
+		{"foo", content, http.StatusBadRequest},
+		{hashOf(nil), nil, http.StatusBadRequest},
+		{hashOf(large), large, http.StatusRequestEntityTooLarge},
+	}
+	for _, test := range tests {
+		url := s.URL + "/attachments/" + test.Hash
+		res := do(t, "PUT", url, email, test.Content)
+		res.Body.Close()
+		if res.StatusCode != test.Expected {
+			t.Errorf("expected %+v got %+v", test.Expected,
+				res.StatusCode)
+		}
+	}
+}
+
+func TestAttachmentFollowsItem(t *testing.T) {
+	s := newServer(t)
+	defer s.Close()
+
+	content := []byte("receipt")
+	hash := hashOf(content)
+	url := s.URL + "/attachments/" + hash
+	res := do(t, "PUT", url, email, content)
+	res.Body.Close()
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("expected %+v got %+v", http.StatusCreated,
+			res.StatusCode)
+	}
+
+	// The content is not served until an attachment refers to it.
+	res = do(t, "GET", url, email, nil)
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("expected %+v got %+v", http.StatusNotFound,
+			res.StatusCode)
+	}
+
+	item := &models.ItemData{
+		Meta:    models.Meta{ID: uuid.Generate()},
+		Date:    date.New(2015, 1, 5),
+		Subject: "Coffee",
+		Amount:  350,
+	}
+	attachment := &models.Attachment{
+		Meta:        models.Meta{ID: uuid.Generate()},
+		ItemID:      item.Meta.ID,
+		ContentType: "image/jpeg",
+		Size:        int64(len(content)),
+		Hash:        hash,
+	}
+	req := &models.SyncRequest{
+		Type:   "ItemData",
+		Values: []interface{}{item},
+	}
+	itemRes, code := sync(t, s.URL, req, password)
+	if code != http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, code)
+	}
+	req = &models.SyncRequest{
+		Type:   "Attachment",
+		Values: []interface{}{attachment},
+	}
+	attachmentRes, code := sync(t, s.URL, req, password)
+	if code != http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, code)
+	}
+
+	res = do(t, "GET", url, email, nil)
+	got, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, res.StatusCode)
+	}
+	if !bytes.Equal(content, got) {
+		t.Errorf("expected %q got %q", content, got)
+	}
+	if ct := res.Header.Get("Content-Type"); ct != "image/jpeg" {
+		t.Errorf("expected %+v got %+v", "image/jpeg", ct)
+	}
+
+	// Other users can't download the content.
+	res = do(t, "GET", url, nonAdmin, nil)
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("expected %+v got %+v", http.StatusNotFound,
+			res.StatusCode)
+	}
+
+	// Deleting the item deletes the attachment and its content.
+	deleted := itemRes.Values[0].(*models.ItemData)
+	deleted.Destroy()
+	req = &models.SyncRequest{
+		Type:   "ItemData",
+		Values: []interface{}{deleted},
+	}
+	if _, code := sync(t, s.URL, req, password); code != http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, code)
+	}
+	req = &models.SyncRequest{
+		Type:        "Attachment",
+		LastUpdated: attachmentRes.LastUpdated,
+	}
+	attachmentRes, code = sync(t, s.URL, req, password)
+	if code != http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, code)
+	}
+	if len(attachmentRes.Values) != 1 {
+		t.Fatalf("expected 1 value got %+v", attachmentRes.Values)
+	}
+	a := attachmentRes.Values[0].(*models.Attachment)
+	if !a.Meta.IsDeleted {
+		t.Errorf("expected a deleted attachment got %+v", a)
+	}
+	res = do(t, "GET", url, email, nil)
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("expected %+v got %+v", http.StatusNotFound,
+			res.StatusCode)
+	}
+	res = do(t, "PUT", url, email, content)
+	res.Body.Close()
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("expected %+v got %+v", http.StatusCreated,
+			res.StatusCode)
+	}
+}
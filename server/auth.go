@@ -0,0 +1,199 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// User is an authenticated user.
+type User struct {
+	ID    string
+	Email string
+}
+
+// Auth authenticates requests.
+type Auth interface {
+	// User returns the user of the request, or nil if the request is not
+	// authenticated.
+	User(r *http.Request) *User
+
+	// Challenge responds to a request which is not authenticated.
+	Challenge(w http.ResponseWriter, r *http.Request)
+}
+
+// Users is a set of permitted users and their password hashes.
+type Users map[string]string
+
+// LoadUsers loads a users file. Each line of the file is an email address
+// optionally followed by a password hash generated by HashPassword. Empty
+// lines and lines starting with '#' are ignored.
+func LoadUsers(path string) (Users, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	users := Users{}
+	lines := bytes.Split(content, []byte("\n"))
+	for _, l := range lines {
+		l := strings.Trim(string(l), " \r\n\t\f")
+		if len(l) == 0 || l[0] == '#' {
+			continue
+		}
+		tokens := strings.Fields(l)
+		switch len(tokens) {
+		case 1:
+			users[tokens[0]] = ""
+		case 2:
+			users[tokens[0]] = tokens[1]
+		default:
+			return nil, fmt.Errorf("server: invalid line: %s", l)
+		}
+	}
+	return users, nil
+}
+
+const (
+	// passwordHashPrefix is the prefix of the hashes generated by
+	// HashPassword. A hash is 'pbkdf2-sha256:<iterations>:<salt>:<key>'.
+	passwordHashPrefix = "pbkdf2-sha256:"
+	// pbkdf2Iterations is the number of iterations of PBKDF2 for new
+	// hashes. The number is stored in each hash.
+	pbkdf2Iterations = 100000
+	pbkdf2KeyLength  = sha256.Size
+)
+
+// pbkdf2 derives a key from the password by PBKDF2 with HMAC-SHA256 (RFC
+// 8018).
+func pbkdf2(password, salt []byte, iterations, keyLength int) []byte {
+	prf := hmac.New(sha256.New, password)
+	key := []byte{}
+	for block := uint32(1); len(key) < keyLength; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write([]byte{
+			byte(block >> 24),
+			byte(block >> 16),
+			byte(block >> 8),
+			byte(block),
+		})
+		u := prf.Sum(nil)
+		t := append([]byte(nil), u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLength]
+}
+
+func hashPassword(salt string, iterations int, password string) string {
+	key := pbkdf2([]byte(password), []byte(salt), iterations,
+		pbkdf2KeyLength)
+	return fmt.Sprintf("%s%d:%s:%s", passwordHashPrefix, iterations, salt,
+		hex.EncodeToString(key))
+}
+
+// HashPassword returns a salted hash of the password for the users file.
+func HashPassword(password string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hashPassword(hex.EncodeToString(b), pbkdf2Iterations, password),
+		nil
+}
+
+func checkPassword(hash, password string) bool {
+	if !strings.HasPrefix(hash, passwordHashPrefix) {
+		return false
+	}
+	tokens := strings.SplitN(hash[len(passwordHashPrefix):], ":", 3)
+	if len(tokens) != 3 {
+		return false
+	}
+	iterations, err := strconv.Atoi(tokens[0])
+	if err != nil || iterations < 1 {
+		return false
+	}
+	expected := hashPassword(tokens[1], iterations, password)
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(hash)) == 1
+}
+
+// BasicAuth authenticates requests by HTTP basic authentication. Use it only
+// over HTTPS.
+type BasicAuth struct {
+	Users Users
+	Realm string
+}
+
+func (a *BasicAuth) User(r *http.Request) *User {
+	email, password, ok := r.BasicAuth()
+	if !ok {
+		return nil
+	}
+	hash, ok := a.Users[email]
+	if !ok || !checkPassword(hash, password) {
+		return nil
+	}
+	return &User{ID: email, Email: email}
+}
+
+func (a *BasicAuth) Challenge(w http.ResponseWriter, r *http.Request) {
+	realm := strings.Replace(a.Realm, `"`, "", -1)
+	w.Header().Set(
+		"WWW-Authenticate",
+		fmt.Sprintf("Basic realm=\"%s\"", realm))
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
+// HeaderAuth trusts the email address in a request header set by an
+// authenticating reverse proxy. The server must not be reachable except via
+// the proxy.
+type HeaderAuth struct {
+	Users  Users
+	Header string
+}
+
+func (a *HeaderAuth) User(r *http.Request) *User {
+	email := r.Header.Get(a.Header)
+	if email == "" {
+		return nil
+	}
+	if _, ok := a.Users[email]; !ok {
+		return nil
+	}
+	return &User{ID: email, Email: email}
+}
+
+func (a *HeaderAuth) Challenge(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "Forbidden", http.StatusForbidden)
+}
+
+// NewAuth returns an Auth by its name, 'basic' or 'header'.
+func NewAuth(name string, users Users, header string) (Auth, error) {
+	switch name {
+	case "basic":
+		return &BasicAuth{Users: users, Realm: "Kakeibo"}, nil
+	case "header":
+		if header == "" {
+			return nil, errors.New("server: header is not specified")
+		}
+		return &HeaderAuth{Users: users, Header: header}, nil
+	}
+	return nil, fmt.Errorf("server: unknown auth: %s", name)
+}
@@ -0,0 +1,55 @@
+package server_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	. "github.com/hajimehoshi/kakeibo/server"
+	"net/http"
+	"testing"
+)
+
+func TestBasicAuthPasswordHash(t *testing.T) {
+	hash, err := HashPassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("0123" + password))
+	legacy := "sha256:0123:" + hex.EncodeToString(sum[:])
+	// Test vectors of PBKDF2-HMAC-SHA256.
+	const (
+		pbkdf2Hash1 = "pbkdf2-sha256:1:salt:" +
+			"120fb6cffcf8b32c43e7225256c4f837" +
+			"a86548c92ccc35480805987cb70be17b"
+		pbkdf2Hash4096 = "pbkdf2-sha256:4096:salt:" +
+			"c5e478d59288c841aa530db6845c4c8d" +
+			"962893a001ce4e11a4963873aa98134a"
+	)
+	tests := []struct {
+		Hash     string
+		Password string
+		Expected bool
+	}{
+		{hash, password, true},
+		{hash, "wrong", false},
+		{pbkdf2Hash1, "password", true},
+		{pbkdf2Hash4096, "password", true},
+		{pbkdf2Hash4096, "passwore", false},
+		{"pbkdf2-sha256:0:salt:", "password", false},
+		// Hashes of plain SHA-256 are not accepted.
+		{legacy, password, false},
+		{"", password, false},
+	}
+	for _, test := range tests {
+		auth := &BasicAuth{Users: Users{email: test.Hash}}
+		r, err := http.NewRequest("GET", "/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.SetBasicAuth(email, test.Password)
+		got := auth.User(r) != nil
+		if got != test.Expected {
+			t.Errorf("%s: expected %+v got %+v",
+				test.Hash, test.Expected, got)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/storage"
+	"net/http"
+	"time"
+)
+
+// MaxBackupSize is the maximum size of a backup to restore in bytes.
+const MaxBackupSize = 64 << 20
+
+// HandleBackup responds the backup of all the values of the user as a JSON
+// file. The shared ledgers which the user is a member of are not included;
+// each ledger is backed up separately with its LedgerNamespace as userID.
+func HandleBackup(
+	w http.ResponseWriter,
+	r *http.Request,
+	b storage.Backend,
+	userID string) {
+	backup, err := storage.Dump(b, userID, time.Now().UTC())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resBytes, err := json.Marshal(backup)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(
+		"Content-Disposition",
+		"attachment; filename=\"kakeibo-backup.json\"")
+	w.Write(resBytes)
+}
+
+// HandleRestore restores a backup in the request body to the user's values.
+// HandleRestore can be called repeatedly with the same backup.
+func HandleRestore(
+	w http.ResponseWriter,
+	r *http.Request,
+	b storage.Backend,
+	userID string) {
+	backup := &models.Backup{}
+	body := http.MaxBytesReader(w, r.Body, MaxBackupSize)
+	if err := json.NewDecoder(body).Decode(backup); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	n, err := storage.Restore(b, userID, backup)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, "{\"Restored\":%d}\n", n)
+}
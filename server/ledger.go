@@ -0,0 +1,337 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/storage"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"net/http"
+	"sort"
+	"time"
+)
+
+const (
+	ledgerType     = "Ledger"
+	membershipType = "Membership"
+)
+
+// ErrForbidden is returned when a user accesses a ledger without permission.
+var ErrForbidden = errors.New("server: forbidden")
+
+// personalTypes is the types which are stored only as the users' own values.
+var personalTypes = map[string]struct{}{
+	"APIToken": {},
+}
+
+// LedgerNamespace returns the ID which the values of a shared ledger are
+// stored under instead of a user ID. The stored values belong to the ledger
+// rather than to its members.
+func LedgerNamespace(id uuid.UUID) string {
+	return "ledger:" + id.String()
+}
+
+// memberNamespace returns the ID which the memberships of the user are stored
+// under.
+func memberNamespace(email string) string {
+	return "member:" + email
+}
+
+// loadLedger returns the stored ledger, or nil if it doesn't exist.
+func loadLedger(b storage.Backend, id uuid.UUID) (*models.Ledger, error) {
+	s, err := b.Open(LedgerNamespace(id), ledgerType)
+	if err != nil {
+		return nil, err
+	}
+	values, err := storage.GetAll(s)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		if l := v.(*models.Ledger); l.Meta.ID == id {
+			return l, nil
+		}
+	}
+	return nil, nil
+}
+
+// Namespace returns the ID which the values of the ledger are stored under
+// after checking that the user can read them, or write them if write is true.
+// An empty ledger means the user's own values. Namespace returns ErrForbidden
+// if the user is not a member with the permission.
+func Namespace(
+	b storage.Backend,
+	userID string,
+	email string,
+	ledger string,
+	write bool) (string, error) {
+	if ledger == "" {
+		return userID, nil
+	}
+	id, err := uuid.ParseString(ledger)
+	if err != nil {
+		return "", ErrForbidden
+	}
+	l, err := loadLedger(b, id)
+	if err != nil {
+		return "", err
+	}
+	if l == nil {
+		return "", ErrForbidden
+	}
+	role, ok := l.RoleOf(email)
+	if !ok || (write && !role.CanWrite()) {
+		return "", ErrForbidden
+	}
+	return LedgerNamespace(id), nil
+}
+
+// authorizeSync returns the ID which the values of the sync request are
+// stored under after checking the user's permission. Only the owners can
+// edit a ledger itself.
+func authorizeSync(
+	b storage.Backend,
+	userID string,
+	email string,
+	req *models.SyncRequest) (string, error) {
+	ledger := req.Ledger.String()
+	write := len(req.Values) != 0
+	// Memberships are managed only by the server.
+	if req.Type == membershipType {
+		return "", ErrForbidden
+	}
+	if ledger != "" {
+		if _, ok := personalTypes[req.Type]; ok {
+			return "", ErrForbidden
+		}
+	}
+	if req.Type != ledgerType {
+		return Namespace(b, userID, email, ledger, write)
+	}
+	// Ledgers are created by HandleLedgers, not by syncs.
+	if ledger == "" {
+		if write {
+			return "", ErrForbidden
+		}
+		return userID, nil
+	}
+	ns, err := Namespace(b, userID, email, ledger, false)
+	if err != nil {
+		return "", err
+	}
+	if !write {
+		return ns, nil
+	}
+	l, err := loadLedger(b, req.Ledger)
+	if err != nil {
+		return "", err
+	}
+	if role, _ := l.RoleOf(email); role != models.RoleOwner {
+		return "", ErrForbidden
+	}
+	for _, v := range req.Values {
+		if models.MetaOf(v).ID != req.Ledger {
+			return "", ErrForbidden
+		}
+	}
+	return ns, nil
+}
+
+// stampUser records the user as the creator and the modifier of the values.
+// The creator is replaced with the stored value's one when the values are
+// stored.
+func stampUser(values []interface{}, email string) {
+	for _, v := range values {
+		meta := models.MetaOf(v)
+		meta.CreatedBy = email
+		meta.UpdatedBy = email
+	}
+}
+
+// indexLedgers stores the memberships of the ledgers' members so that the
+// members can find the ledgers.
+func indexLedgers(b storage.Backend, ledgers []interface{}) error {
+	for _, v := range ledgers {
+		l := v.(*models.Ledger)
+		for _, m := range l.Members {
+			ns := memberNamespace(m.Email)
+			s, err := b.Open(ns, membershipType)
+			if err != nil {
+				return err
+			}
+			values, err := storage.GetAll(s)
+			if err != nil {
+				return err
+			}
+			found := false
+			for _, v := range values {
+				if v.(*models.Membership).Ledger == l.Meta.ID {
+					found = true
+					break
+				}
+			}
+			if found {
+				continue
+			}
+			membership := &models.Membership{
+				Meta:   models.Meta{ID: uuid.Generate()},
+				Ledger: l.Meta.ID,
+			}
+			values = []interface{}{membership}
+			if _, _, err := s.Put(time.Time{}, values); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type sortLedgersByName []models.LedgerSummary
+
+func (s sortLedgersByName) Len() int {
+	return len(s)
+}
+
+func (s sortLedgersByName) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s sortLedgersByName) Less(i, j int) bool {
+	if s[i].Name != s[j].Name {
+		return s[i].Name < s[j].Name
+	}
+	return s[i].ID < s[j].ID
+}
+
+// ledgers returns the ledgers which the user is a member of sorted by their
+// names.
+func ledgers(
+	b storage.Backend,
+	email string) ([]models.LedgerSummary, error) {
+	s, err := b.Open(memberNamespace(email), membershipType)
+	if err != nil {
+		return nil, err
+	}
+	memberships, err := storage.GetAll(s)
+	if err != nil {
+		return nil, err
+	}
+	result := []models.LedgerSummary{}
+	for _, v := range memberships {
+		// The user might have been removed from the ledger.
+		id := v.(*models.Membership).Ledger
+		l, err := loadLedger(b, id)
+		if err != nil {
+			return nil, err
+		}
+		if l == nil {
+			continue
+		}
+		role, ok := l.RoleOf(email)
+		if !ok {
+			continue
+		}
+		result = append(result, models.LedgerSummary{
+			ID:   id,
+			Name: l.Name,
+			Role: role,
+		})
+	}
+	sort.Sort(sortLedgersByName(result))
+	return result, nil
+}
+
+// HandleLedgers responds the ledgers which the user is a member of for a GET
+// request, and creates a ledger whose owner is the user for a POST request
+// like '{"Name":"Home"}'.
+func HandleLedgers(
+	w http.ResponseWriter,
+	r *http.Request,
+	b storage.Backend,
+	email string) {
+	switch r.Method {
+	case "GET":
+		result, err := ledgers(b, email)
+		if err != nil {
+			http.Error(w, err.Error(),
+				http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, result)
+	case "POST":
+		req := struct{ Name string }{}
+		body := http.MaxBytesReader(w, r.Body, MaxAPIRequestSize)
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		owner := models.Member{Email: email, Role: models.RoleOwner}
+		l := &models.Ledger{
+			Meta:    models.Meta{ID: uuid.Generate()},
+			Name:    req.Name,
+			Members: []models.Member{owner},
+		}
+		if !l.IsValid() {
+			http.Error(w, "server: invalid ledger",
+				http.StatusBadRequest)
+			return
+		}
+		s, err := b.Open(LedgerNamespace(l.Meta.ID), ledgerType)
+		if err != nil {
+			http.Error(w, err.Error(),
+				http.StatusInternalServerError)
+			return
+		}
+		values := []interface{}{l}
+		stampUser(values, email)
+		if _, _, err := s.Put(time.Time{}, values); err != nil {
+			http.Error(w, err.Error(),
+				http.StatusInternalServerError)
+			return
+		}
+		if err := indexLedgers(b, values); err != nil {
+			http.Error(w, err.Error(),
+				http.StatusInternalServerError)
+			return
+		}
+		summary := models.LedgerSummary{
+			ID:   l.Meta.ID,
+			Name: l.Name,
+			Role: models.RoleOwner,
+		}
+		res, err := json.Marshal(summary)
+		if err != nil {
+			http.Error(w, err.Error(),
+				http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(res)
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// requestNamespace returns the ID which the values of the ledger in the
+// 'ledger' parameter are stored under. requestNamespace responds an error and
+// returns false if the user can't access the ledger.
+func requestNamespace(
+	w http.ResponseWriter,
+	r *http.Request,
+	b storage.Backend,
+	userID string,
+	email string,
+	write bool) (string, bool) {
+	ledger := r.URL.Query().Get("ledger")
+	ns, err := Namespace(b, userID, email, ledger, write)
+	if err == ErrForbidden {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return "", false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return "", false
+	}
+	return ns, true
+}
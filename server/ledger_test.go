@@ -0,0 +1,198 @@
+package server_test
+
+import (
+	"encoding/json"
+	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"net/http"
+	"testing"
+)
+
+func getLedgers(t *testing.T, url string, user string) []models.LedgerSummary {
+	res := do(t, "GET", url+"/ledgers", user, nil)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, res.StatusCode)
+	}
+	ledgers := []models.LedgerSummary{}
+	if err := json.NewDecoder(res.Body).Decode(&ledgers); err != nil {
+		t.Fatal(err)
+	}
+	return ledgers
+}
+
+// putLedger syncs the ledger as the user, and returns the stored ledger.
+func putLedger(
+	t *testing.T,
+	url string,
+	user string,
+	ledger *models.Ledger) (*models.Ledger, int) {
+	req := &models.SyncRequest{
+		Type:   "Ledger",
+		Ledger: ledger.Meta.ID,
+		Values: []interface{}{ledger},
+	}
+	res, code := syncAs(t, url, req, user, password)
+	if code != http.StatusOK {
+		return nil, code
+	}
+	if len(res.Rejected) != 0 || len(res.Values) != 1 {
+		t.Fatalf("unexpected response: %+v", res)
+	}
+	return res.Values[0].(*models.Ledger), code
+}
+
+func TestLedgers(t *testing.T) {
+	s := newServer(t)
+	defer s.Close()
+
+	res := do(t, "POST", s.URL+"/ledgers", email, []byte(`{"Name":""}`))
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected %+v got %+v", http.StatusBadRequest,
+			res.StatusCode)
+	}
+	res = do(t, "POST", s.URL+"/ledgers", email, []byte(`{"Name":"Home"}`))
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("expected %+v got %+v", http.StatusCreated,
+			res.StatusCode)
+	}
+	summary := models.LedgerSummary{}
+	if err := json.NewDecoder(res.Body).Decode(&summary); err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	id := summary.ID
+	if summary.Name != "Home" || summary.Role != models.RoleOwner {
+		t.Errorf("unexpected ledger: %+v", summary)
+	}
+	if ledgers := getLedgers(t, s.URL, email); len(ledgers) != 1 ||
+		ledgers[0] != summary {
+		t.Errorf("expected [%+v] got %+v", summary, ledgers)
+	}
+	if ledgers := getLedgers(t, s.URL, nonAdmin); len(ledgers) != 0 {
+		t.Errorf("expected no ledgers got %+v", ledgers)
+	}
+
+	item := &models.ItemData{
+		Meta:    models.Meta{ID: uuid.Generate()},
+		Date:    date.New(2015, 1, 5),
+		Subject: "Rent",
+		Amount:  80000,
+	}
+	itemReq := &models.SyncRequest{
+		Type:   "ItemData",
+		Ledger: id,
+		Values: []interface{}{item},
+	}
+	// A user who is not a member can't access the ledger.
+	_, code := syncAs(t, s.URL, itemReq, nonAdmin, password)
+	if code != http.StatusForbidden {
+		t.Errorf("expected %+v got %+v", http.StatusForbidden, code)
+	}
+	readReq := &models.SyncRequest{Type: "ItemData", Ledger: id}
+	_, code = syncAs(t, s.URL, readReq, nonAdmin, password)
+	if code != http.StatusForbidden {
+		t.Errorf("expected %+v got %+v", http.StatusForbidden, code)
+	}
+	// API tokens are not shared.
+	tokenReq := &models.SyncRequest{Type: "APIToken", Ledger: id}
+	if _, code := sync(t, s.URL, tokenReq, password); code !=
+		http.StatusForbidden {
+		t.Errorf("expected %+v got %+v", http.StatusForbidden, code)
+	}
+
+	ledgerReq := &models.SyncRequest{Type: "Ledger", Ledger: id}
+	ledgerRes, code := sync(t, s.URL, ledgerReq, password)
+	if code != http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, code)
+	}
+	if len(ledgerRes.Values) != 1 {
+		t.Fatalf("expected 1 value got %+v", ledgerRes.Values)
+	}
+	ledger := ledgerRes.Values[0].(*models.Ledger)
+	if ledger.Meta.CreatedBy != email {
+		t.Errorf("expected %+v got %+v", email, ledger.Meta.CreatedBy)
+	}
+	ledger.Members = append(ledger.Members, models.Member{
+		Email: nonAdmin,
+		Role:  models.RoleViewer,
+	})
+	if ledger, code = putLedger(t, s.URL, email, ledger); code !=
+		http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, code)
+	}
+	ledgers := getLedgers(t, s.URL, nonAdmin)
+	if len(ledgers) != 1 || ledgers[0].Role != models.RoleViewer {
+		t.Errorf("unexpected ledgers: %+v", ledgers)
+	}
+
+	// A viewer can read the ledger, but can't write it.
+	_, code = syncAs(t, s.URL, readReq, nonAdmin, password)
+	if code != http.StatusOK {
+		t.Errorf("expected %+v got %+v", http.StatusOK, code)
+	}
+	_, code = syncAs(t, s.URL, itemReq, nonAdmin, password)
+	if code != http.StatusForbidden {
+		t.Errorf("expected %+v got %+v", http.StatusForbidden, code)
+	}
+	ledger.Members[1].Role = models.RoleOwner
+	if _, code := putLedger(t, s.URL, nonAdmin, ledger); code !=
+		http.StatusForbidden {
+		t.Errorf("expected %+v got %+v", http.StatusForbidden, code)
+	}
+
+	ledger.Members[1].Role = models.RoleEditor
+	if ledger, code = putLedger(t, s.URL, email, ledger); code !=
+		http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, code)
+	}
+	itemRes, code := syncAs(t, s.URL, itemReq, nonAdmin, password)
+	if code != http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, code)
+	}
+	created := itemRes.Values[0].(*models.ItemData)
+	if created.Meta.CreatedBy != nonAdmin ||
+		created.Meta.UpdatedBy != nonAdmin {
+		t.Errorf("unexpected meta: %+v", created.Meta)
+	}
+
+	// Another member edits the item.
+	created.Amount = 85000
+	created.Meta.CreatedBy = ""
+	itemReq.Values = []interface{}{created}
+	itemRes, code = sync(t, s.URL, itemReq, password)
+	if code != http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, code)
+	}
+	updated := itemRes.Values[0].(*models.ItemData)
+	if updated.Amount != 85000 || updated.Meta.CreatedBy != nonAdmin ||
+		updated.Meta.UpdatedBy != email {
+		t.Errorf("unexpected item: %+v", updated)
+	}
+
+	// The items of the ledger are not the members' own items.
+	personalReq := &models.SyncRequest{Type: "ItemData"}
+	personalRes, code := sync(t, s.URL, personalReq, password)
+	if code != http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, code)
+	}
+	if len(personalRes.Values) != 0 {
+		t.Errorf("expected no values got %+v", personalRes.Values)
+	}
+
+	// A member removed from the ledger can't access it anymore.
+	ledger.Members = ledger.Members[:1]
+	if _, code := putLedger(t, s.URL, email, ledger); code !=
+		http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, code)
+	}
+	_, code = syncAs(t, s.URL, readReq, nonAdmin, password)
+	if code != http.StatusForbidden {
+		t.Errorf("expected %+v got %+v", http.StatusForbidden, code)
+	}
+	if ledgers := getLedgers(t, s.URL, nonAdmin); len(ledgers) != 0 {
+		t.Errorf("expected no ledgers got %+v", ledgers)
+	}
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/hajimehoshi/kakeibo/currency"
+	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/items"
+	"github.com/hajimehoshi/kakeibo/money"
+	"github.com/hajimehoshi/kakeibo/storage"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"net/http"
+	"time"
+)
+
+// MaxReportMonths is the maximum number of months in a monthly report.
+const MaxReportMonths = 120
+
+// reportTypes is the names of the synced types which reports are computed
+// from.
+var reportTypes = []string{"ItemData", "Category", "ExchangeRate", "Settings"}
+
+// ReportTotals is the totals in a report. The amounts are in the minor unit
+// of the report's currency.
+type ReportTotals struct {
+	Income  money.Amount
+	Expense money.Amount
+	Net     money.Amount
+	// Unconverted is the number of the items which are not counted because
+	// there are no exchange rates for their currencies, or because the
+	// totals would overflow.
+	Unconverted int
+}
+
+func newReportTotals(t items.Totals) ReportTotals {
+	return ReportTotals{
+		Income:      t.Income,
+		Expense:     t.Expense,
+		Net:         t.Net(),
+		Unconverted: t.Unconverted,
+	}
+}
+
+// MonthlyReport is the response of a monthly report.
+type MonthlyReport struct {
+	Currency currency.Code
+	Months   []MonthReport
+}
+
+// MonthReport is the totals of a month.
+type MonthReport struct {
+	// Month is like '2006-01'.
+	Month string
+	ReportTotals
+}
+
+// CategoryReport is the response of a category report.
+type CategoryReport struct {
+	Currency   currency.Code
+	Month      string
+	Categories []CategoryReportTotals
+}
+
+// CategoryReportTotals is the totals of a category including its
+// subcategories. ID and Path are omitted for the uncategorized items.
+type CategoryReportTotals struct {
+	ID   uuid.UUID `json:",omitempty"`
+	Path string    `json:",omitempty"`
+	ReportTotals
+}
+
+// parseMonth parses a month like '2006-01'. If value is empty, parseMonth
+// returns the current month.
+func parseMonth(value string) (date.Date, error) {
+	if value == "" {
+		today := date.Today()
+		return date.New(today.Year(), today.Month(), 1), nil
+	}
+	t, err := time.Parse("2006-01", value)
+	if err != nil {
+		return 0, errors.New("server: invalid month: " + value)
+	}
+	return date.New(t.Year(), t.Month(), 1), nil
+}
+
+func formatMonth(ym date.Date) string {
+	return ym.String()[:len("2006-01")]
+}
+
+// loadReport loads the values stored under ns, which is the user's ID or a
+// ledger's namespace, to compute reports. Deleted values are not counted.
+func loadReport(
+	b storage.Backend,
+	ns string,
+	email string) (*items.Report, error) {
+	report := items.NewReport(email)
+	for _, name := range reportTypes {
+		s, err := b.Open(ns, name)
+		if err != nil {
+			return nil, err
+		}
+		values, err := storage.GetAll(s)
+		if err != nil {
+			return nil, err
+		}
+		report.Load(values)
+	}
+	return report, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+// HandleMonthlyReport responds the totals of each month from the 'from'
+// parameter to the 'to' parameter, like '2025-01'. 'to' is the current month
+// by default, and 'from' is 11 months before 'to' by default. email is the
+// user's email address, which identifies the user's settings. The totals are
+// of the ledger in the 'ledger' parameter if it is specified.
+func HandleMonthlyReport(
+	w http.ResponseWriter,
+	r *http.Request,
+	b storage.Backend,
+	userID string,
+	email string) {
+	q := r.URL.Query()
+	to, err := parseMonth(q.Get("to"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	from := to.AddDate(0, -11, 0)
+	if q.Get("from") != "" {
+		if from, err = parseMonth(q.Get("from")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	n := (to.Year()-from.Year())*12 + int(to.Month()-from.Month()) + 1
+	if n <= 0 || MaxReportMonths < n {
+		http.Error(w, "server: invalid range", http.StatusBadRequest)
+		return
+	}
+
+	ns, ok := requestNamespace(w, r, b, userID, email, false)
+	if !ok {
+		return
+	}
+	report, err := loadReport(b, ns, email)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	res := &MonthlyReport{
+		Currency: report.BaseCurrency(),
+		Months:   []MonthReport{},
+	}
+	for _, m := range report.MonthTotals(from, n) {
+		res.Months = append(res.Months, MonthReport{
+			Month:        formatMonth(m.Month),
+			ReportTotals: newReportTotals(m.Totals),
+		})
+	}
+	writeJSON(w, res)
+}
+
+// HandleCategoryReport responds the totals of each category in the month of
+// the 'month' parameter, like '2026-05'. 'month' is the current month by
+// default. email is the user's email address, which identifies the user's
+// settings. The totals are of the ledger in the 'ledger' parameter if it is
+// specified.
+func HandleCategoryReport(
+	w http.ResponseWriter,
+	r *http.Request,
+	b storage.Backend,
+	userID string,
+	email string) {
+	ym, err := parseMonth(r.URL.Query().Get("month"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ns, ok := requestNamespace(w, r, b, userID, email, false)
+	if !ok {
+		return
+	}
+	report, err := loadReport(b, ns, email)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	res := &CategoryReport{
+		Currency:   report.BaseCurrency(),
+		Month:      formatMonth(ym),
+		Categories: []CategoryReportTotals{},
+	}
+	for _, t := range report.CategoryTotals(ym) {
+		res.Categories = append(res.Categories, CategoryReportTotals{
+			ID:           t.ID,
+			Path:         t.Path,
+			ReportTotals: newReportTotals(t.Totals),
+		})
+	}
+	writeJSON(w, res)
+}
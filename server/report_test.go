@@ -0,0 +1,150 @@
+package server_test
+
+import (
+	"encoding/json"
+	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/models"
+	. "github.com/hajimehoshi/kakeibo/server"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"net/http"
+	"testing"
+)
+
+func getReport(t *testing.T, url string, user string, v interface{}) int {
+	res := do(t, "GET", url, user, nil)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return res.StatusCode
+	}
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+	return res.StatusCode
+}
+
+func TestReports(t *testing.T) {
+	s := newServer(t)
+	defer s.Close()
+
+	food := &models.Category{
+		Meta: models.Meta{ID: uuid.Generate()},
+		Name: "Food",
+	}
+	req := &models.SyncRequest{
+		Type:   "Category",
+		Values: []interface{}{food},
+	}
+	if _, code := sync(t, s.URL, req, password); code != http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, code)
+	}
+	deleted := &models.ItemData{
+		Meta:    models.Meta{ID: uuid.Generate()},
+		Date:    date.New(2015, 2, 3),
+		Subject: "Deleted",
+		Amount:  10000,
+	}
+	deleted.Destroy()
+	req = &models.SyncRequest{
+		Type: "ItemData",
+		Values: []interface{}{
+			&models.ItemData{
+				Meta:       models.Meta{ID: uuid.Generate()},
+				Date:       date.New(2015, 1, 5),
+				Subject:    "Lunch",
+				Amount:     800,
+				CategoryID: food.Meta.ID,
+			},
+			&models.ItemData{
+				Meta:       models.Meta{ID: uuid.Generate()},
+				Date:       date.New(2015, 2, 1),
+				Subject:    "Dinner",
+				Amount:     1200,
+				CategoryID: food.Meta.ID,
+			},
+			&models.ItemData{
+				Meta:      models.Meta{ID: uuid.Generate()},
+				Date:      date.New(2015, 2, 25),
+				Subject:   "Salary",
+				Amount:    200000,
+				Direction: models.DirectionIncome,
+			},
+			deleted,
+		},
+	}
+	if _, code := sync(t, s.URL, req, password); code != http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, code)
+	}
+
+	monthly := &MonthlyReport{}
+	url := s.URL + "/api/reports/monthly?from=2014-12&to=2015-02"
+	if code := getReport(t, url, email, monthly); code != http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, code)
+	}
+	if monthly.Currency != "JPY" {
+		t.Errorf("expected %+v got %+v", "JPY", monthly.Currency)
+	}
+	expectedMonths := []MonthReport{
+		{"2014-12", ReportTotals{}},
+		{"2015-01", ReportTotals{Expense: 800, Net: -800}},
+		{"2015-02", ReportTotals{
+			Income:  200000,
+			Expense: 1200,
+			Net:     198800,
+		}},
+	}
+	if len(monthly.Months) != len(expectedMonths) {
+		t.Fatalf("expected %+v got %+v", expectedMonths, monthly.Months)
+	}
+	for i, m := range monthly.Months {
+		if m != expectedMonths[i] {
+			t.Errorf("expected %+v got %+v", expectedMonths[i], m)
+		}
+	}
+
+	categories := &CategoryReport{}
+	url = s.URL + "/api/reports/categories?month=2015-02"
+	if code := getReport(t, url, email, categories); code != http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, code)
+	}
+	expectedCategories := []CategoryReportTotals{
+		{"", "", ReportTotals{Income: 200000, Net: 200000}},
+		{food.Meta.ID, "Food", ReportTotals{Expense: 1200, Net: -1200}},
+	}
+	if len(categories.Categories) != len(expectedCategories) {
+		t.Fatalf("expected %+v got %+v", expectedCategories,
+			categories.Categories)
+	}
+	for i, c := range categories.Categories {
+		if c != expectedCategories[i] {
+			t.Errorf("expected %+v got %+v",
+				expectedCategories[i], c)
+		}
+	}
+
+	// Other users' items are not counted.
+	monthly = &MonthlyReport{}
+	url = s.URL + "/api/reports/monthly?from=2015-01&to=2015-02"
+	code := getReport(t, url, nonAdmin, monthly)
+	if code != http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, code)
+	}
+	for _, m := range monthly.Months {
+		if m.ReportTotals != (ReportTotals{}) {
+			t.Errorf("expected no totals got %+v", m)
+		}
+	}
+
+	for _, query := range []string{
+		"monthly?from=2015-03&to=2015-02",
+		"monthly?from=2000-01&to=2015-02",
+		"monthly?to=2015/02",
+		"categories?month=foo",
+	} {
+		url := s.URL + "/api/reports/" + query
+		code := getReport(t, url, email, &struct{}{})
+		if code != http.StatusBadRequest {
+			t.Errorf("%s: expected %+v got %+v", query,
+				http.StatusBadRequest, code)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"github.com/hajimehoshi/kakeibo/blob"
+	"github.com/hajimehoshi/kakeibo/storage"
+	"html/template"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// Server is a standalone Kakeibo server which serves the same pages as the
+// App Engine application.
+type Server struct {
+	backend storage.Backend
+	blobs   blob.Store
+	auth    Auth
+	tmpl    *template.Template
+	mux     *http.ServeMux
+	// admins is the IDs of the users who can back up and restore any
+	// user's values.
+	admins map[string]struct{}
+}
+
+// New returns a server. blobs stores the contents of attachments. root is the
+// directory which contains 'templates' and 'static' directories, like the
+// 'app' directory.
+func New(
+	backend storage.Backend,
+	blobs blob.Store,
+	auth Auth,
+	root string) (*Server, error) {
+	path := filepath.Join(root, "templates", "index.html")
+	tmpl, err := template.ParseFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		backend: backend,
+		blobs:   blobs,
+		auth:    auth,
+		tmpl:    tmpl,
+		mux:     http.NewServeMux(),
+		admins:  map[string]struct{}{},
+	}
+	static := http.Dir(filepath.Join(root, "static"))
+	s.mux.Handle(
+		"/static/",
+		http.StripPrefix("/static/", http.FileServer(static)))
+	s.mux.HandleFunc("/sync", s.filterUsers(s.handleSync))
+	s.mux.HandleFunc("/ledgers", s.filterUsers(s.handleLedgers))
+	s.mux.HandleFunc(
+		attachmentsPath,
+		s.filterUsers(s.handleAttachment))
+	// The REST API is authenticated by API tokens instead.
+	s.mux.HandleFunc(APIItemsPath, s.handleAPIItems)
+	s.mux.HandleFunc(APIItemsPath+"/", s.handleAPIItems)
+	s.mux.HandleFunc(
+		"/api/reports/monthly",
+		s.filterUsers(s.handleMonthlyReport))
+	s.mux.HandleFunc(
+		"/api/reports/categories",
+		s.filterUsers(s.handleCategoryReport))
+	s.mux.HandleFunc(
+		"/admin/backup",
+		s.filterUsers(s.filterAdmins(s.handleBackup)))
+	s.mux.HandleFunc(
+		"/admin/restore",
+		s.filterUsers(s.filterAdmins(s.handleRestore)))
+	s.mux.HandleFunc("/", s.filterUsers(s.handleIndex))
+	return s, nil
+}
+
+// SetAdmins sets the IDs of the administrators.
+func (s *Server) SetAdmins(ids []string) {
+	s.admins = map[string]struct{}{}
+	for _, id := range ids {
+		s.admins[id] = struct{}{}
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+type userHandlerFunc func(w http.ResponseWriter, r *http.Request, u *User)
+
+func (s *Server) filterUsers(f userHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u := s.auth.User(r)
+		if u == nil {
+			s.auth.Challenge(w, r)
+			return
+		}
+		f(w, r, u)
+	}
+}
+
+func (s *Server) filterAdmins(f userHandlerFunc) userHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, u *User) {
+		if _, ok := s.admins[u.ID]; !ok {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		f(w, r, u)
+	}
+}
+
+// targetUserID returns the ID of the user whose values an administrator
+// handles. The user is specified by the 'user' parameter, or is the
+// administrator.
+func targetUserID(r *http.Request, u *User) string {
+	if id := r.URL.Query().Get("user"); id != "" {
+		return id
+	}
+	return u.ID
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request, u *User) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-type", "text/html; charset=utf-8")
+	s.tmpl.Execute(w, map[string]interface{}{
+		"UserEmail":         u.Email,
+		"UserID":            u.ID,
+		"IsDevelopmentMode": false,
+		"LogoutURL":         "",
+	})
+}
+
+func (s *Server) handleSync(w http.ResponseWriter, r *http.Request, u *User) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	HandleSync(w, r, s.backend, s.blobs, u.ID, u.Email)
+}
+
+func (s *Server) handleLedgers(
+	w http.ResponseWriter,
+	r *http.Request,
+	u *User) {
+	HandleLedgers(w, r, s.backend, u.Email)
+}
+
+func (s *Server) handleAttachment(
+	w http.ResponseWriter,
+	r *http.Request,
+	u *User) {
+	hash := strings.TrimPrefix(r.URL.Path, attachmentsPath)
+	switch r.Method {
+	case "PUT":
+		HandleUpload(w, r, s.backend, s.blobs, u.ID, u.Email, hash)
+	case "GET":
+		HandleDownload(w, r, s.backend, s.blobs, u.ID, u.Email, hash)
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleAPIItems(w http.ResponseWriter, r *http.Request) {
+	HandleAPIItems(w, r, s.backend, s.blobs)
+}
+
+func (s *Server) handleMonthlyReport(
+	w http.ResponseWriter,
+	r *http.Request,
+	u *User) {
+	if r.Method != "GET" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	HandleMonthlyReport(w, r, s.backend, u.ID, u.Email)
+}
+
+func (s *Server) handleCategoryReport(
+	w http.ResponseWriter,
+	r *http.Request,
+	u *User) {
+	if r.Method != "GET" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	HandleCategoryReport(w, r, s.backend, u.ID, u.Email)
+}
+
+func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request, u *User) {
+	if r.Method != "GET" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	HandleBackup(w, r, s.backend, targetUserID(r, u))
+}
+
+func (s *Server) handleRestore(
+	w http.ResponseWriter,
+	r *http.Request,
+	u *User) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	HandleRestore(w, r, s.backend, targetUserID(r, u))
+}
@@ -0,0 +1,235 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/hajimehoshi/kakeibo/blob"
+	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/models"
+	. "github.com/hajimehoshi/kakeibo/server"
+	"github.com/hajimehoshi/kakeibo/storage"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const (
+	email    = "foo@example.com"
+	password = "password"
+	// nonAdmin is a user who is not an administrator.
+	nonAdmin = "bar@example.com"
+)
+
+func newServer(t *testing.T) *httptest.Server {
+	hash, err := HashPassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := &BasicAuth{Users: Users{email: hash, nonAdmin: hash}}
+	s, err := New(storage.NewMemory(), blob.NewMemory(), auth, "../app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetAdmins([]string{email})
+	return httptest.NewServer(s)
+}
+
+func sync(
+	t *testing.T,
+	url string,
+	req *models.SyncRequest,
+	password string) (*models.SyncResponse, int) {
+	return syncAs(t, url, req, email, password)
+}
+
+func syncAs(
+	t *testing.T,
+	url string,
+	req *models.SyncRequest,
+	user string,
+	password string) (*models.SyncResponse, int) {
+	b, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := http.NewRequest("POST", url+"/sync", bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetBasicAuth(user, password)
+	res, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, res.StatusCode
+	}
+	syncRes := &models.SyncResponse{}
+	if err := json.NewDecoder(res.Body).Decode(syncRes); err != nil {
+		t.Fatal(err)
+	}
+	return syncRes, res.StatusCode
+}
+
+func TestSync(t *testing.T) {
+	s := newServer(t)
+	defer s.Close()
+
+	item := &models.ItemData{
+		Meta:    models.Meta{ID: uuid.Generate()},
+		Date:    date.New(2014, 5, 6),
+		Subject: "Lunch",
+		Amount:  800,
+	}
+	req := &models.SyncRequest{
+		Type:   "ItemData",
+		Values: []interface{}{item},
+	}
+	res, status := sync(t, s.URL, req, password)
+	if status != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, status)
+	}
+	if len(res.Values) != 1 {
+		t.Fatalf("expected 1 value got %d", len(res.Values))
+	}
+	got := res.Values[0].(*models.ItemData)
+	if got.Meta.ID != item.Meta.ID || got.Subject != item.Subject {
+		t.Errorf("expected %+v got %+v", item, got)
+	}
+
+	req = &models.SyncRequest{
+		Type:        "ItemData",
+		LastUpdated: res.LastUpdated,
+	}
+	res, status = sync(t, s.URL, req, password)
+	if status != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, status)
+	}
+	if len(res.Values) != 0 {
+		t.Errorf("expected no values got %+v", res.Values)
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	s := newServer(t)
+	defer s.Close()
+
+	req := &models.SyncRequest{Type: "ItemData"}
+	_, status := sync(t, s.URL, req, "wrong")
+	if status != http.StatusUnauthorized {
+		t.Errorf("expected %d got %d", http.StatusUnauthorized, status)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	s := newServer(t)
+	defer s.Close()
+
+	r, err := http.NewRequest("GET", s.URL+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetBasicAuth(email, password)
+	res, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, res.StatusCode)
+	}
+}
+
+func do(
+	t *testing.T,
+	method string,
+	url string,
+	user string,
+	body []byte) *http.Response {
+	r, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetBasicAuth(user, password)
+	res, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	s := newServer(t)
+	defer s.Close()
+
+	item := &models.ItemData{
+		Meta:    models.Meta{ID: uuid.Generate()},
+		Date:    date.New(2015, 1, 5),
+		Subject: "Coffee",
+		Amount:  350,
+	}
+	req := &models.SyncRequest{
+		Type:   "ItemData",
+		Values: []interface{}{item},
+	}
+	if _, code := sync(t, s.URL, req, password); code != http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, code)
+	}
+
+	res := do(t, "GET", s.URL+"/admin/backup", email, nil)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected %+v got %+v", http.StatusOK, res.StatusCode)
+	}
+	buf := &bytes.Buffer{}
+	if _, err := buf.ReadFrom(res.Body); err != nil {
+		t.Fatal(err)
+	}
+	backup := &models.Backup{}
+	if err := json.Unmarshal(buf.Bytes(), backup); err != nil {
+		t.Fatal(err)
+	}
+	if len(backup.Values["ItemData"]) != 1 {
+		t.Fatalf("expected 1 item got %+v", backup.Values)
+	}
+
+	// Restoring to the same server doesn't change anything, while restoring
+	// to an empty server restores the item only once.
+	s2 := newServer(t)
+	defer s2.Close()
+	urls := []string{s.URL, s2.URL, s2.URL}
+	for i, expected := range []int{0, 1, 0} {
+		url := urls[i] + "/admin/restore"
+		res := do(t, "POST", url, email, buf.Bytes())
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			msg, _ := ioutil.ReadAll(res.Body)
+			t.Fatalf("expected %+v got %+v: %s", http.StatusOK,
+				res.StatusCode, msg)
+		}
+		result := struct{ Restored int }{}
+		if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+		if result.Restored != expected {
+			t.Errorf("expected %+v got %+v", expected, result.Restored)
+		}
+	}
+}
+
+func TestBackupForbidden(t *testing.T) {
+	s := newServer(t)
+	defer s.Close()
+
+	res := do(t, "GET", s.URL+"/admin/backup", nonAdmin, nil)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf(
+			"expected %+v got %+v",
+			http.StatusForbidden,
+			res.StatusCode)
+	}
+}
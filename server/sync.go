@@ -0,0 +1,127 @@
+// Package server provides the HTTP handlers of Kakeibo which don't depend on
+// App Engine.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/hajimehoshi/kakeibo/blob"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/storage"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"io/ioutil"
+	"net/http"
+)
+
+const (
+	// MaxPageSize is the maximum number of values in a sync response.
+	MaxPageSize = 500
+
+	// MaxRequestValues is the maximum number of values in a sync request.
+	MaxRequestValues = 500
+)
+
+func parseRequest(r *http.Request) (req *models.SyncRequest, err error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return
+	}
+
+	req = &models.SyncRequest{}
+	if err = json.Unmarshal(body, &req); err != nil {
+		return
+	}
+	if MaxRequestValues < len(req.Values) {
+		err = errors.New("server: too many values")
+		return
+	}
+	return
+}
+
+// HandleSync handles a sync request of the user. The contents in blobs are
+// deleted when no attachments refer to them anymore. email is the user's email
+// address, which identifies the user as a member of a ledger and is recorded
+// as the creator and the modifier of the values.
+func HandleSync(
+	w http.ResponseWriter,
+	r *http.Request,
+	b storage.Backend,
+	blobs blob.Store,
+	userID string,
+	email string) {
+	req, err := parseRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ns, err := authorizeSync(b, userID, email, req)
+	if err == ErrForbidden {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	stampUser(req.Values, email)
+	d, err := b.Open(ns, req.Type)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	now, rejected, err := d.Put(req.LastUpdated, req.Values)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	isRejected := map[uuid.UUID]struct{}{}
+	for _, v := range rejected {
+		isRejected[models.MetaOf(v).ID] = struct{}{}
+	}
+	accepted := []interface{}{}
+	for _, v := range req.Values {
+		if _, ok := isRejected[models.MetaOf(v).ID]; !ok {
+			accepted = append(accepted, v)
+		}
+	}
+	err = followTombstones(b, blobs, ns, req.Type, accepted)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if req.Type == ledgerType {
+		if err := indexLedgers(b, accepted); err != nil {
+			http.Error(w, err.Error(),
+				http.StatusInternalServerError)
+			return
+		}
+	}
+	limit := req.Limit
+	if limit <= 0 || MaxPageSize < limit {
+		limit = MaxPageSize
+	}
+	values, cursor, err := d.Get(req.LastUpdated, req.Cursor, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res := &models.SyncResponse{
+		Type:        req.Type,
+		LastUpdated: now,
+		Values:      values,
+		Rejected:    rejected,
+		Cursor:      cursor,
+	}
+	resBytes, err := json.Marshal(res)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, err = w.Write(resBytes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
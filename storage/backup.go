@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"github.com/hajimehoshi/kakeibo/models"
+	"time"
+)
+
+// backupPageSize is the number of values read or written at once on backup
+// and restore.
+const backupPageSize = 500
+
+// GetAll returns all the values in the storage including deleted ones.
+func GetAll(s Storage) ([]interface{}, error) {
+	all := []interface{}{}
+	cursor := ""
+	for {
+		values, next, err := s.Get(time.Time{}, cursor, backupPageSize)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, values...)
+		if next == "" {
+			return all, nil
+		}
+		cursor = next
+	}
+}
+
+// Dump returns the backup of all the values stored under userID. The values
+// of other IDs, like the shared ledgers of the user, are not included.
+func Dump(b Backend, userID string, now time.Time) (*models.Backup, error) {
+	backup := models.NewBackup(now)
+	for _, t := range models.SyncedTypes() {
+		s, err := b.Open(userID, t.Name)
+		if err != nil {
+			return nil, err
+		}
+		values, err := GetAll(s)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 0 {
+			continue
+		}
+		backup.Values[t.Name] = values
+	}
+	return backup, nil
+}
+
+// Restore stores the values in the backup which models.ShouldRestore accepts,
+// and returns the number of the restored values. The restored values get new
+// revisions and last-updated times so that clients receive them on their next
+// sync. Restore modifies the Meta of the values in the backup.
+func Restore(b Backend, userID string, backup *models.Backup) (int, error) {
+	restored := 0
+	for name, values := range backup.Values {
+		s, err := b.Open(userID, name)
+		if err != nil {
+			return restored, err
+		}
+		current, err := GetAll(s)
+		if err != nil {
+			return restored, err
+		}
+		metas := map[string]*models.Meta{}
+		for _, v := range current {
+			meta := models.MetaOf(v)
+			metas[meta.ID.String()] = meta
+		}
+		toPut := []interface{}{}
+		for _, v := range values {
+			meta := models.MetaOf(v)
+			c := metas[meta.ID.String()]
+			if !models.ShouldRestore(meta, c) {
+				continue
+			}
+			// Overwrite the current value regardless of the revision.
+			meta.Revision = 0
+			if c != nil {
+				meta.Revision = c.Revision
+			}
+			toPut = append(toPut, v)
+		}
+		for 0 < len(toPut) {
+			n := len(toPut)
+			if backupPageSize < n {
+				n = backupPageSize
+			}
+			_, rejected, err := s.Put(time.Time{}, toPut[:n])
+			if err != nil {
+				return restored, err
+			}
+			// Values updated during the restore are rejected and
+			// kept.
+			restored += n - len(rejected)
+			toPut = toPut[n:]
+		}
+	}
+	return restored, nil
+}
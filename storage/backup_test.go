@@ -0,0 +1,117 @@
+package storage_test
+
+import (
+	"encoding/json"
+	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/models"
+	. "github.com/hajimehoshi/kakeibo/storage"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	src := NewMemory()
+	items, err := src.Open("user1", "ItemData")
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := &models.ItemData{
+		Meta:    models.Meta{ID: uuid.Generate()},
+		Date:    date.New(2015, 1, 5),
+		Subject: "Coffee",
+		Amount:  350,
+	}
+	deleted := &models.ItemData{Meta: models.Meta{ID: uuid.Generate()}}
+	deleted.Destroy()
+	_, _, err = items.Put(time.Time{}, []interface{}{item, deleted})
+	if err != nil {
+		t.Fatal(err)
+	}
+	categories, err := src.Open("user1", "Category")
+	if err != nil {
+		t.Fatal(err)
+	}
+	category := &models.Category{
+		Meta: models.Meta{ID: uuid.Generate()},
+		Name: "Food",
+	}
+	_, _, err = categories.Put(time.Time{}, []interface{}{category})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := Dump(src, "user1", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The backup must survive a JSON round trip with the full Meta.
+	j, err := json.Marshal(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &models.Backup{}
+	if err := json.Unmarshal(j, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Values["ItemData"]) != 2 {
+		t.Fatalf("expected 2 items got %+v", decoded.Values["ItemData"])
+	}
+	for _, v := range decoded.Values["ItemData"] {
+		meta := models.MetaOf(v)
+		if meta.LastUpdated.IsZero() || meta.Revision != 1 {
+			t.Errorf("expected a synced Meta got %+v", meta)
+		}
+	}
+
+	dst := NewMemory()
+	n, err := Restore(dst, "user2", decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("expected %+v got %+v", 3, n)
+	}
+	s, err := dst.Open("user2", "ItemData")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, _, err := s.Get(time.Time{}, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := map[uuid.UUID]bool{}
+	for _, v := range values {
+		meta := models.MetaOf(v)
+		ids[meta.ID] = meta.IsDeleted
+	}
+	expected := map[uuid.UUID]bool{
+		item.Meta.ID:    false,
+		deleted.Meta.ID: true,
+	}
+	if !reflect.DeepEqual(expected, ids) {
+		t.Errorf("expected %+v got %+v", expected, ids)
+	}
+
+	// Restoring the same backup again doesn't change anything.
+	decoded = &models.Backup{}
+	if err := json.Unmarshal(j, decoded); err != nil {
+		t.Fatal(err)
+	}
+	n, err = Restore(dst, "user2", decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("expected %+v got %+v", 0, n)
+	}
+}
+
+func TestBackupVersion(t *testing.T) {
+	b := &models.Backup{}
+	j := []byte(`{"Version":999,"Values":{}}`)
+	if err := json.Unmarshal(j, b); err == nil {
+		t.Errorf("expected an error for an unsupported version")
+	}
+}
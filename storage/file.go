@@ -0,0 +1,257 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/hajimehoshi/kakeibo/models"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const fileVersion = 1
+
+// minRecordsToCompact is the number of records in a file under which the file
+// is not compacted.
+const minRecordsToCompact = 1000
+
+// File is a Backend which keeps values in memory and appends the values put
+// to a log file. The file starts with a header line, and each following line
+// is a record of a value. A later record of the same value replaces the
+// earlier ones. The file is compacted into the latest records when it has
+// more than twice as many records as the values.
+type File struct {
+	path   string
+	memory *Memory
+	// size is the size of the valid records in the file.
+	size int64
+	// records is the number of the records in the file.
+	records int
+}
+
+type fileHeader struct {
+	Version int
+}
+
+type fileRecord struct {
+	Type   string
+	UserID string
+	Value  json.RawMessage
+}
+
+// OpenFile opens the file at path. The file is created at the first put if
+// it doesn't exist.
+func OpenFile(path string) (*File, error) {
+	f := &File{
+		path:   path,
+		memory: NewMemory(),
+	}
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+	f.memory.afterPut = f.append
+	return f, nil
+}
+
+func (f *File) Open(userID string, typeName string) (Storage, error) {
+	return f.memory.Open(userID, typeName)
+}
+
+func (f *File) load() error {
+	file, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	r := bufio.NewReader(file)
+	line, err := r.ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if len(line) == 0 {
+		return nil
+	}
+	header := fileHeader{}
+	if err := json.Unmarshal(line, &header); err != nil {
+		return err
+	}
+	if header.Version != fileVersion {
+		return errors.New("storage: unsupported file version")
+	}
+	f.size = int64(len(line))
+	for {
+		line, err := r.ReadBytes('\n')
+		// The last line without a newline is a record which failed to
+		// be appended, and is overwritten by the next put.
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		record := fileRecord{}
+		if err := json.Unmarshal(line, &record); err != nil {
+			return err
+		}
+		if err := f.loadRecord(record); err != nil {
+			return err
+		}
+		f.size += int64(len(line))
+		f.records++
+	}
+	return nil
+}
+
+func (f *File) loadRecord(r fileRecord) error {
+	t, err := models.LookupSyncedType(r.Type)
+	if err != nil {
+		return err
+	}
+	v, err := t.Decode(r.Value)
+	if err != nil {
+		return err
+	}
+	meta := models.MetaOf(v)
+	meta.UserID = r.UserID
+	f.memory.values[memoryKey{t.Name, meta.ID}] = v
+	if f.memory.last.Before(meta.LastUpdated) {
+		f.memory.last = meta.LastUpdated
+	}
+	return nil
+}
+
+// encodeRecord returns the line of the record of the value.
+func encodeRecord(key memoryKey, v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	r := fileRecord{
+		Type:   key.typeName,
+		UserID: models.MetaOf(v).UserID,
+		Value:  b,
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+func encodeHeader() ([]byte, error) {
+	line, err := json.Marshal(fileHeader{Version: fileVersion})
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// append appends the records of the values of the keys to the file. append
+// must be called with the lock of the memory held.
+func (f *File) append(keys []memoryKey) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	buf := &bytes.Buffer{}
+	if f.size == 0 {
+		header, err := encodeHeader()
+		if err != nil {
+			return err
+		}
+		buf.Write(header)
+	}
+	for _, key := range keys {
+		line, err := encodeRecord(key, f.memory.values[key])
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+	}
+
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	// Records which failed to be appended before are overwritten.
+	if _, err := file.Seek(f.size, io.SeekStart); err != nil {
+		file.Close()
+		return err
+	}
+	n, err := file.Write(buf.Bytes())
+	if err == nil {
+		err = file.Truncate(f.size + int64(n))
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	f.size += int64(n)
+	f.records += len(keys)
+
+	if f.records < minRecordsToCompact {
+		return nil
+	}
+	if f.records <= 2*len(f.memory.values) {
+		return nil
+	}
+	// The values are already in the file, and compaction is tried again at
+	// the next put if it fails.
+	f.compact()
+	return nil
+}
+
+// compact rewrites the file with only the latest records. compact must be
+// called with the lock of the memory held.
+func (f *File) compact() error {
+	header, err := encodeHeader()
+	if err != nil {
+		return err
+	}
+	buf := bytes.NewBuffer(header)
+	for key, v := range f.memory.values {
+		line, err := encodeRecord(key, v)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+	}
+
+	// Write to a temporary file and rename it so that the file is never
+	// broken.
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), ".kakeibo")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	f.size = int64(buf.Len())
+	f.records = len(f.memory.values)
+	return nil
+}
@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryKey struct {
+	typeName string
+	id       uuid.UUID
+}
+
+// Memory is a Backend which keeps values in memory.
+type Memory struct {
+	m      sync.Mutex
+	values map[memoryKey]interface{}
+	last   time.Time
+	// afterPut is called with the keys of the values after they are put,
+	// while the lock is held.
+	afterPut func(keys []memoryKey) error
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		values: map[memoryKey]interface{}{},
+	}
+}
+
+type memoryStorage struct {
+	memory *Memory
+	userID string
+	t      *models.SyncedType
+}
+
+func (m *Memory) Open(userID string, typeName string) (Storage, error) {
+	t, err := models.LookupSyncedType(typeName)
+	if err != nil {
+		return nil, err
+	}
+	return &memoryStorage{m, userID, t}, nil
+}
+
+// now returns the current time. The result is always after the previous
+// result so that a client never misses values put at the same time as its
+// last sync.
+func (m *Memory) now() time.Time {
+	now := time.Now().UTC()
+	if !now.After(m.last) {
+		now = m.last.Add(time.Nanosecond)
+	}
+	m.last = now
+	return now
+}
+
+// clone returns a deep copy of the value.
+func clone(t *models.SyncedType, v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	c, err := t.Decode(b)
+	if err != nil {
+		return nil, err
+	}
+	// UserID is not encoded in JSON.
+	models.MetaOf(c).UserID = models.MetaOf(v).UserID
+	return c, nil
+}
+
+func (s *memoryStorage) Put(
+	lastUpdated time.Time,
+	values []interface{}) (
+	now time.Time,
+	rejected []interface{},
+	err error) {
+	m := s.memory
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	now = time.Now().UTC()
+	if now.Before(lastUpdated) {
+		err = ErrTooNew
+		return
+	}
+	now = m.now()
+
+	rejected = []interface{}{}
+	valuesToPut := map[memoryKey]interface{}{}
+	for _, v := range values {
+		if reflect.TypeOf(v) != reflect.PtrTo(s.t.Type) {
+			err = errors.New("storage: invalid type")
+			return
+		}
+		if !s.t.Validate(v) {
+			err = errors.New("storage: invalid value")
+			return
+		}
+		meta := models.MetaOf(v)
+		key := memoryKey{s.t.Name, meta.ID}
+		var existing *models.Meta
+		e, ok := m.values[key]
+		if ok {
+			existing = models.MetaOf(e)
+		}
+		ok, err = Accept(s.userID, meta, existing)
+		if err != nil {
+			return
+		}
+		var c interface{}
+		if !ok {
+			if c, err = clone(s.t, e); err != nil {
+				return
+			}
+			rejected = append(rejected, c)
+			continue
+		}
+		Stamp(s.userID, now, meta, existing)
+		c, err = clone(s.t, v)
+		if err != nil {
+			return
+		}
+		valuesToPut[key] = c
+	}
+	// Values are put after all of them are checked so that Put is atomic.
+	oldValues := map[memoryKey]interface{}{}
+	keys := make([]memoryKey, 0, len(valuesToPut))
+	for key, v := range valuesToPut {
+		if old, ok := m.values[key]; ok {
+			oldValues[key] = old
+		}
+		m.values[key] = v
+		keys = append(keys, key)
+	}
+	if m.afterPut == nil {
+		return
+	}
+	if err = m.afterPut(keys); err != nil {
+		rejected = nil
+		for key := range valuesToPut {
+			if old, ok := oldValues[key]; ok {
+				m.values[key] = old
+				continue
+			}
+			delete(m.values, key)
+		}
+	}
+	return
+}
+
+type sortByLastUpdated []interface{}
+
+func (s sortByLastUpdated) Len() int {
+	return len(s)
+}
+
+func (s sortByLastUpdated) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s sortByLastUpdated) Less(i, j int) bool {
+	return metaLess(models.MetaOf(s[i]), models.MetaOf(s[j]))
+}
+
+func metaLess(m1, m2 *models.Meta) bool {
+	if !m1.LastUpdated.Equal(m2.LastUpdated) {
+		return m1.LastUpdated.Before(m2.LastUpdated)
+	}
+	return m1.ID < m2.ID
+}
+
+// encodeCursor returns a cursor which points the position just after the
+// value.
+func encodeCursor(meta *models.Meta) string {
+	t := meta.LastUpdated.UTC().Format(time.RFC3339Nano)
+	return t + " " + meta.ID.String()
+}
+
+func decodeCursor(cursor string) (*models.Meta, error) {
+	tokens := strings.SplitN(cursor, " ", 2)
+	if len(tokens) != 2 {
+		return nil, errors.New("storage: invalid cursor")
+	}
+	t, err := time.Parse(time.RFC3339Nano, tokens[0])
+	if err != nil {
+		return nil, errors.New("storage: invalid cursor")
+	}
+	return &models.Meta{ID: uuid.UUID(tokens[1]), LastUpdated: t}, nil
+}
+
+func (s *memoryStorage) Get(
+	lastUpdated time.Time,
+	cursor string,
+	limit int) (values []interface{}, next string, err error) {
+	m := s.memory
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	var after *models.Meta
+	if cursor != "" {
+		if after, err = decodeCursor(cursor); err != nil {
+			return
+		}
+	}
+
+	found := []interface{}{}
+	for key, v := range m.values {
+		if key.typeName != s.t.Name {
+			continue
+		}
+		meta := models.MetaOf(v)
+		if meta.UserID != s.userID {
+			continue
+		}
+		if !meta.LastUpdated.After(lastUpdated) {
+			continue
+		}
+		if after != nil && !metaLess(after, meta) {
+			continue
+		}
+		found = append(found, v)
+	}
+	sort.Sort(sortByLastUpdated(found))
+	if 0 < limit && limit < len(found) {
+		found = found[:limit]
+		next = encodeCursor(models.MetaOf(found[limit-1]))
+	}
+
+	values = make([]interface{}, len(found))
+	for i, v := range found {
+		if values[i], err = clone(s.t, v); err != nil {
+			return
+		}
+	}
+	return
+}
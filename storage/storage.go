@@ -0,0 +1,80 @@
+// Package storage provides server-side storages of synced values.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hajimehoshi/kakeibo/models"
+	"time"
+)
+
+// Storage stores synced values of one type for one user.
+type Storage interface {
+	// Put stores the values sent by a client whose last-updated time is
+	// lastUpdated. A value is rejected if its revision is different from
+	// the stored value's, and the stored value is returned in rejected
+	// instead. Put increments the revisions of the stored values and
+	// returns the new last-updated time of the server.
+	Put(lastUpdated time.Time, values []interface{}) (
+		now time.Time,
+		rejected []interface{},
+		err error)
+
+	// Get returns the values updated after lastUpdated in order of their
+	// last-updated times. cursor is the continuation token returned by the
+	// previous call, or empty for the first page. Get returns at most limit
+	// values if limit is positive, and next is the continuation token for
+	// the next page, or empty if there are no more values.
+	Get(lastUpdated time.Time, cursor string, limit int) (
+		values []interface{},
+		next string,
+		err error)
+}
+
+// Backend opens storages.
+type Backend interface {
+	Open(userID string, typeName string) (Storage, error)
+}
+
+var ErrTooNew = errors.New("storage: last-updated is too new")
+
+// Accept reports whether a value sent by a client can overwrite the stored
+// value. incoming is the Meta of the sent value, and existing is the Meta of
+// the stored value, or nil if no value is stored. Accept returns an error if
+// the stored value belongs to another user.
+func Accept(
+	userID string,
+	incoming *models.Meta,
+	existing *models.Meta) (bool, error) {
+	if existing == nil {
+		return true, nil
+	}
+	if userID != existing.UserID {
+		e := fmt.Sprintf(
+			"storage: invalid UUID: %s",
+			existing.ID.String())
+		return false, errors.New(e)
+	}
+	// The client's edit is not based on the stored value.
+	if incoming.Revision != existing.Revision {
+		return false, nil
+	}
+	return true, nil
+}
+
+// Stamp updates the Meta of a value to be stored. The creator of the stored
+// value is kept.
+func Stamp(
+	userID string,
+	now time.Time,
+	meta *models.Meta,
+	existing *models.Meta) {
+	meta.LastUpdated = now
+	meta.UserID = userID
+	meta.Revision = 0
+	if existing != nil {
+		meta.Revision = existing.Revision
+		meta.CreatedBy = existing.CreatedBy
+	}
+	meta.Revision++
+}
@@ -0,0 +1,201 @@
+package storage_test
+
+import (
+	"bytes"
+	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/money"
+	. "github.com/hajimehoshi/kakeibo/storage"
+	"github.com/hajimehoshi/kakeibo/storage/storagetest"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) Backend {
+		return NewMemory()
+	})
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "kakeibo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestFile(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	n := 0
+	storagetest.Run(t, func(t *testing.T) Backend {
+		n++
+		path := filepath.Join(dir, string('a'+rune(n))+".json")
+		f, err := OpenFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	})
+}
+
+func TestFileReopen(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "kakeibo.json")
+
+	f, err := OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := f.Open("user1", "ItemData")
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := &models.ItemData{
+		Meta:    models.Meta{ID: uuid.Generate()},
+		Date:    date.New(2014, 5, 6),
+		Subject: "Lunch",
+		Amount:  800,
+	}
+	now, _, err := s.Put(time.Time{}, []interface{}{item})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = f.Open("user1", "ItemData")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, _, err := s.Get(time.Time{}, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 1 {
+		t.Fatalf("expected 1 value got %d", len(values))
+	}
+	got := values[0].(*models.ItemData)
+	if got.Meta.ID != item.Meta.ID || got.Subject != "Lunch" {
+		t.Errorf("expected %+v got %+v", item, got)
+	}
+	if got.Meta.UserID != "user1" {
+		t.Errorf("expected user1 got %s", got.Meta.UserID)
+	}
+
+	// The next put must be after the stored values even if the clock goes
+	// backward.
+	now2, _, err := s.Put(now, []interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !now.Before(now2) {
+		t.Errorf("expected %v < %v", now, now2)
+	}
+}
+
+func countLines(t *testing.T, path string) int {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Count(b, []byte("\n"))
+}
+
+func TestFileCompaction(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "kakeibo.json")
+
+	f, err := OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := f.Open("user1", "ItemData")
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := &models.ItemData{
+		Meta:    models.Meta{ID: uuid.Generate()},
+		Date:    date.New(2014, 5, 6),
+		Subject: "Lunch",
+		Amount:  800,
+	}
+	// Each put appends a record of the item, and stamps the item with the
+	// stored revision.
+	const n = 2000
+	for i := 0; i < n; i++ {
+		item.Amount = money.Amount(i + 1)
+		values := []interface{}{item}
+		if _, _, err := s.Put(time.Time{}, values); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if lines := countLines(t, path); lines >= n {
+		t.Errorf("expected compacted got %d lines", lines)
+	}
+
+	// A record which failed to be appended is ignored.
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.Write([]byte(`{"Type":"ItemData","Us`)); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	f, err = OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = f.Open("user1", "ItemData")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, _, err := s.Get(time.Time{}, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 1 {
+		t.Fatalf("expected 1 value got %d", len(values))
+	}
+	if got := values[0].(*models.ItemData); got.Amount != n {
+		t.Errorf("expected %d got %d", n, got.Amount)
+	}
+	item.Subject = "Dinner"
+	if _, _, err := s.Put(time.Time{}, []interface{}{item}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenFile(path); err != nil {
+		t.Errorf("expected the broken record overwritten: %v", err)
+	}
+}
+
+func TestFileUnsupportedVersion(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "kakeibo.json")
+
+	for _, content := range []string{
+		`{"Version":0}` + "\n",
+		`{"Version":2}` + "\n",
+	} {
+		err := ioutil.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := OpenFile(path); err == nil {
+			t.Errorf("expected an error for %q", content)
+		}
+	}
+}
@@ -0,0 +1,398 @@
+// Package storagetest provides a conformance test suite for storage
+// backends.
+package storagetest
+
+import (
+	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/money"
+	"github.com/hajimehoshi/kakeibo/storage"
+	"github.com/hajimehoshi/kakeibo/uuid"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	userID      = "user1"
+	otherUserID = "user2"
+	itemType    = "ItemData"
+)
+
+func newItem(subject string, amount money.Amount) *models.ItemData {
+	return &models.ItemData{
+		Meta:    models.Meta{ID: uuid.Generate()},
+		Date:    date.New(2014, 5, 6),
+		Subject: subject,
+		Amount:  amount,
+	}
+}
+
+func open(
+	t *testing.T,
+	b storage.Backend,
+	userID, typeName string) storage.Storage {
+	s, err := b.Open(userID, typeName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func put(
+	t *testing.T,
+	s storage.Storage,
+	lastUpdated time.Time,
+	values ...interface{}) time.Time {
+	now, rejected, err := s.Put(lastUpdated, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rejected) != 0 {
+		t.Fatalf("expected no rejected values got %+v", rejected)
+	}
+	return now
+}
+
+func get(
+	t *testing.T,
+	s storage.Storage,
+	lastUpdated time.Time) []*models.ItemData {
+	values, next, err := s.Get(lastUpdated, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != "" {
+		t.Fatalf("expected no next cursor got %s", next)
+	}
+	items := make([]*models.ItemData, len(values))
+	for i, v := range values {
+		item, ok := v.(*models.ItemData)
+		if !ok {
+			t.Fatalf("expected *models.ItemData got %T", v)
+		}
+		items[i] = item
+	}
+	return items
+}
+
+// Run runs the conformance tests. newBackend must return a new empty backend
+// for each call.
+func Run(t *testing.T, newBackend func(t *testing.T) storage.Backend) {
+	tests := []struct {
+		name string
+		f    func(t *testing.T, b storage.Backend)
+	}{
+		{"PutAndGet", testPutAndGet},
+		{"GetAfterLastUpdated", testGetAfterLastUpdated},
+		{"Conflict", testConflict},
+		{"Revision", testRevision},
+		{"Creator", testCreator},
+		{"UpdateAfterSync", testUpdateAfterSync},
+		{"OtherUsersValue", testOtherUsersValue},
+		{"UserScope", testUserScope},
+		{"TypeScope", testTypeScope},
+		{"TooNew", testTooNew},
+		{"InvalidValue", testInvalidValue},
+		{"Tombstone", testTombstone},
+		{"Pagination", testPagination},
+		{"UpdateWhilePaging", testUpdateWhilePaging},
+		{"UnknownType", testUnknownType},
+	}
+	for _, test := range tests {
+		b := newBackend(t)
+		test.f(t, b)
+		if t.Failed() {
+			t.Fatalf("%s failed", test.name)
+		}
+	}
+}
+
+func testPutAndGet(t *testing.T, b storage.Backend) {
+	s := open(t, b, userID, itemType)
+	item := newItem("Lunch", 800)
+	now := put(t, s, time.Time{}, item)
+	items := get(t, s, time.Time{})
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item got %d", len(items))
+	}
+	got := items[0]
+	if got.Meta.ID != item.Meta.ID ||
+		got.Subject != "Lunch" ||
+		got.Amount != 800 {
+		t.Errorf("expected %+v got %+v", item, got)
+	}
+	if !got.Meta.LastUpdated.Equal(now) {
+		t.Errorf("expected %v got %v", now, got.Meta.LastUpdated)
+	}
+}
+
+func testGetAfterLastUpdated(t *testing.T, b storage.Backend) {
+	s := open(t, b, userID, itemType)
+	now1 := put(t, s, time.Time{}, newItem("Lunch", 800))
+	if items := get(t, s, now1); len(items) != 0 {
+		t.Errorf("expected no items got %d", len(items))
+	}
+	item := newItem("Dinner", 1200)
+	now2 := put(t, s, now1, item)
+	if !now1.Before(now2) {
+		t.Errorf("expected %v < %v", now1, now2)
+	}
+	items := get(t, s, now1)
+	if len(items) != 1 || items[0].Meta.ID != item.Meta.ID {
+		t.Errorf("expected [%+v] got %+v", item, items)
+	}
+}
+
+func testConflict(t *testing.T, b storage.Backend) {
+	s := open(t, b, userID, itemType)
+	item := newItem("Lunch", 800)
+	now1 := put(t, s, time.Time{}, item)
+
+	// Two clients edit the same revision.
+	item2 := *item
+	item2.Amount = 900
+	item3 := *item
+	item3.Amount = 1000
+
+	put(t, s, now1, &item2)
+
+	// The second edit is rejected, and the stored value is returned.
+	_, rejected, err := s.Put(now1, []interface{}{&item3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rejected) != 1 {
+		t.Fatalf("expected 1 rejected value got %+v", rejected)
+	}
+	r, ok := rejected[0].(*models.ItemData)
+	if !ok || r.Meta.ID != item.Meta.ID || r.Amount != 900 {
+		t.Errorf("expected the amount 900 got %+v", rejected[0])
+	}
+
+	items := get(t, s, now1)
+	if len(items) != 1 || items[0].Amount != 900 {
+		t.Errorf("expected the amount 900 got %+v", items)
+	}
+
+	// The edit based on the stored revision is accepted.
+	item3.Meta.Revision = r.Meta.Revision
+	put(t, s, now1, &item3)
+	items = get(t, s, now1)
+	if len(items) != 1 || items[0].Amount != 1000 {
+		t.Errorf("expected the amount 1000 got %+v", items)
+	}
+}
+
+func testRevision(t *testing.T, b storage.Backend) {
+	s := open(t, b, userID, itemType)
+	item := newItem("Lunch", 800)
+	now := put(t, s, time.Time{}, item)
+	for i := 1; i <= 3; i++ {
+		items := get(t, s, time.Time{})
+		if len(items) != 1 || items[0].Meta.Revision != i {
+			t.Fatalf("expected the revision %d got %+v", i, items)
+		}
+		now = put(t, s, now, items[0])
+	}
+}
+
+func testCreator(t *testing.T, b storage.Backend) {
+	s := open(t, b, userID, itemType)
+	item := newItem("Lunch", 800)
+	item.Meta.CreatedBy = "foo@example.com"
+	item.Meta.UpdatedBy = "foo@example.com"
+	now := put(t, s, time.Time{}, item)
+
+	// The creator of the stored value is kept.
+	item2 := *item
+	item2.Meta.CreatedBy = "bar@example.com"
+	item2.Meta.UpdatedBy = "bar@example.com"
+	put(t, s, now, &item2)
+	items := get(t, s, time.Time{})
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item got %+v", items)
+	}
+	meta := items[0].Meta
+	if meta.CreatedBy != "foo@example.com" ||
+		meta.UpdatedBy != "bar@example.com" {
+		t.Errorf("unexpected creator or updater: %+v", meta)
+	}
+}
+
+func testUpdateAfterSync(t *testing.T, b storage.Backend) {
+	s := open(t, b, userID, itemType)
+	item := newItem("Lunch", 800)
+	now1 := put(t, s, time.Time{}, item)
+
+	item2 := *item
+	item2.Amount = 900
+	now2 := put(t, s, now1, &item2)
+
+	items := get(t, s, time.Time{})
+	if len(items) != 1 || items[0].Amount != 900 {
+		t.Errorf("expected the amount 900 got %+v", items)
+	}
+	if !items[0].Meta.LastUpdated.Equal(now2) {
+		t.Errorf("expected %v got %v", now2, items[0].Meta.LastUpdated)
+	}
+}
+
+func testOtherUsersValue(t *testing.T, b storage.Backend) {
+	s := open(t, b, userID, itemType)
+	item := newItem("Lunch", 800)
+	now := put(t, s, time.Time{}, item)
+
+	other := open(t, b, otherUserID, itemType)
+	item2 := *item
+	if _, _, err := other.Put(now, []interface{}{&item2}); err == nil {
+		t.Errorf("expected an error")
+	}
+	items := get(t, s, time.Time{})
+	if len(items) != 1 || items[0].Amount != 800 {
+		t.Errorf("expected the amount 800 got %+v", items)
+	}
+}
+
+func testUserScope(t *testing.T, b storage.Backend) {
+	s := open(t, b, userID, itemType)
+	put(t, s, time.Time{}, newItem("Lunch", 800))
+	other := open(t, b, otherUserID, itemType)
+	if items := get(t, other, time.Time{}); len(items) != 0 {
+		t.Errorf("expected no items got %+v", items)
+	}
+}
+
+func testTypeScope(t *testing.T, b storage.Backend) {
+	s := open(t, b, userID, itemType)
+	put(t, s, time.Time{}, newItem("Lunch", 800))
+	categories := open(t, b, userID, "Category")
+	values, _, err := categories.Get(time.Time{}, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 0 {
+		t.Errorf("expected no values got %+v", values)
+	}
+	if _, _, err := categories.Put(time.Time{}, []interface{}{
+		newItem("Lunch", 800),
+	}); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func testTooNew(t *testing.T, b storage.Backend) {
+	s := open(t, b, userID, itemType)
+	future := time.Now().Add(time.Hour)
+	if _, _, err := s.Put(future, []interface{}{}); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func testInvalidValue(t *testing.T, b storage.Backend) {
+	s := open(t, b, userID, itemType)
+	valid := newItem("Lunch", 800)
+	invalid := newItem("", 800)
+	if _, _, err := s.Put(time.Time{}, []interface{}{
+		valid,
+		invalid,
+	}); err == nil {
+		t.Errorf("expected an error")
+	}
+	if items := get(t, s, time.Time{}); len(items) != 0 {
+		t.Errorf("expected no items got %+v", items)
+	}
+}
+
+func testTombstone(t *testing.T, b storage.Backend) {
+	s := open(t, b, userID, itemType)
+	item := newItem("Lunch", 800)
+	now := put(t, s, time.Time{}, item)
+	item2 := *item
+	item2.Destroy()
+	put(t, s, now, &item2)
+
+	items := get(t, s, now)
+	if len(items) != 1 || !items[0].Meta.IsDeleted {
+		t.Errorf("expected a deleted item got %+v", items)
+	}
+}
+
+func testUnknownType(t *testing.T, b storage.Backend) {
+	if _, err := b.Open(userID, "Unknown"); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+// getAll gets all the pages and returns the amounts of the items.
+func getAll(
+	t *testing.T,
+	s storage.Storage,
+	lastUpdated time.Time,
+	limit int,
+	f func(page int)) []money.Amount {
+	amounts := []money.Amount{}
+	cursor := ""
+	for page := 0; ; page++ {
+		values, next, err := s.Get(lastUpdated, cursor, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if limit < len(values) {
+			t.Fatalf("expected at most %d values got %d",
+				limit, len(values))
+		}
+		for _, v := range values {
+			amounts = append(amounts, v.(*models.ItemData).Amount)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+		if f != nil {
+			f(page)
+		}
+	}
+	return amounts
+}
+
+func testPagination(t *testing.T, b storage.Backend) {
+	s := open(t, b, userID, itemType)
+	now := time.Time{}
+	for i := 0; i < 5; i++ {
+		now = put(t, s, now, newItem("Lunch", money.Amount(i)))
+	}
+	for limit := 1; limit <= 6; limit++ {
+		amounts := getAll(t, s, time.Time{}, limit, nil)
+		expected := []money.Amount{0, 1, 2, 3, 4}
+		if !reflect.DeepEqual(expected, amounts) {
+			t.Errorf("limit %d: expected %v got %v",
+				limit, expected, amounts)
+		}
+	}
+}
+
+func testUpdateWhilePaging(t *testing.T, b storage.Backend) {
+	s := open(t, b, userID, itemType)
+	now := time.Time{}
+	items := []*models.ItemData{}
+	for i := 0; i < 4; i++ {
+		item := newItem("Lunch", money.Amount(i))
+		now = put(t, s, now, item)
+		items = append(items, item)
+	}
+	// An item already returned is updated while paging. The updated item
+	// must be returned again at the end.
+	amounts := getAll(t, s, time.Time{}, 2, func(page int) {
+		if page != 0 {
+			return
+		}
+		item := *items[0]
+		item.Amount = 10
+		put(t, s, now, &item)
+	})
+	expected := []money.Amount{0, 1, 2, 3, 10}
+	if !reflect.DeepEqual(expected, amounts) {
+		t.Errorf("expected %v got %v", expected, amounts)
+	}
+}
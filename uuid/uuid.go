@@ -1,6 +1,7 @@
 package uuid
 
 import (
+	"crypto/sha1"
 	"errors"
 	"fmt"
 	"regexp"
@@ -49,9 +50,21 @@ func (i UUID) IsValid() bool {
 }
 
 func Generate() UUID {
-	id := [16]byte{}
 	r := rand()
-	copy(id[:], r[:])
+	return fromBytes(r[:])
+}
+
+// Derive returns a UUID determined by base and name. Clients deriving an ID
+// from the same values get the same ID without communicating. The version
+// bits are the same as Generate's so that the result is accepted as a v4 UUID.
+func Derive(base UUID, name string) UUID {
+	h := sha1.Sum([]byte(string(base) + " " + name))
+	return fromBytes(h[:])
+}
+
+func fromBytes(b []byte) UUID {
+	id := [16]byte{}
+	copy(id[:], b)
 	id[6] = (id[6] & 0x0f) | 0x40
 	id[8] = (id[8] & 0x3f) | 0x80
 	str := fmt.Sprintf(
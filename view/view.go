@@ -1,14 +1,22 @@
 package view
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/gopherjs/gopherjs/js"
+	"github.com/hajimehoshi/kakeibo/currency"
 	"github.com/hajimehoshi/kakeibo/date"
+	"github.com/hajimehoshi/kakeibo/idb"
 	"github.com/hajimehoshi/kakeibo/items"
+	"github.com/hajimehoshi/kakeibo/journal"
 	"github.com/hajimehoshi/kakeibo/models"
+	"github.com/hajimehoshi/kakeibo/money"
 	"github.com/hajimehoshi/kakeibo/uuid"
 	"html"
+	"net/url"
 	"reflect"
 	"strconv"
 	"strings"
@@ -17,11 +25,107 @@ import (
 type Items interface {
 	UpdateDate(id uuid.UUID, date date.Date) error
 	UpdateSubject(id uuid.UUID, subject string) error
-	UpdateAmount(id uuid.UUID, amount int32) error
+	UpdateAmount(id uuid.UUID, amount money.Amount) error
+	UpdateCurrency(id uuid.UUID, code currency.Code) error
+	UpdateDirection(id uuid.UUID, direction models.Direction) error
+	UpdateCategory(id uuid.UUID, categoryID uuid.UUID) error
+	UpdateSplits(id uuid.UUID, splits []models.Split) error
+	UpdateTags(id uuid.UUID, tags []string) error
+	UpdateNote(id uuid.UUID, note string) error
+	UpdateAccount(id uuid.UUID, accountID uuid.UUID) error
+	UpdateToAccount(id uuid.UUID, accountID uuid.UUID) error
+	Attach(
+		id uuid.UUID,
+		name string,
+		contentType string,
+		size int64,
+		hash string) error
 	Save(id uuid.UUID) error
+	Edit(id uuid.UUID) error
 	Destroy(id uuid.UUID) error
 	UpdateMode(mode items.Mode, ym date.Date)
+	UpdateTagMode(tag string)
+	UpdateSearchMode(query string) error
 	DownloadCSV() error
+	DownloadJournal(format journal.Format) error
+	PreviewCSV(data []byte, mapping items.CSVMapping) error
+	PreviewOFX(data []byte, encoding string, accountID uuid.UUID) error
+	Import(includeDuplicates bool) error
+	CancelImport()
+}
+
+type Categories interface {
+	Create(name string, parentID uuid.UUID) error
+	Destroy(id uuid.UUID) error
+}
+
+type Accounts interface {
+	Create(name string, openingBalance money.Amount) error
+	Destroy(id uuid.UUID) error
+}
+
+type RecurringItems interface {
+	Create(template models.RecurringItem) error
+	Destroy(id uuid.UUID) error
+}
+
+type Budgets interface {
+	Create(
+		categoryID uuid.UUID,
+		subjectPattern string,
+		month date.Date,
+		limit money.Amount) error
+	Destroy(id uuid.UUID) error
+}
+
+type ExchangeRates interface {
+	Create(d date.Date, from, to currency.Code, rate string) error
+	Destroy(id uuid.UUID) error
+	ImportCSV(data []byte) (int, error)
+}
+
+type Settings interface {
+	SetBaseCurrency(code currency.Code) error
+}
+
+type Attachments interface {
+	Destroy(id uuid.UUID) error
+}
+
+// Ledger is the shared ledger which is shown.
+type Ledger interface {
+	SetMember(email string, role models.Role) error
+	RemoveMember(email string) error
+}
+
+// Ledgers is the shared ledgers which the user is a member of.
+type Ledgers interface {
+	// Ledger returns the ledger which is shown, or an empty UUID for the
+	// user's own values.
+	Ledger() uuid.UUID
+	Ledgers() ([]models.LedgerSummary, error)
+	CreateLedger(name string) (*models.LedgerSummary, error)
+	// SwitchLedger shows the ledger, or the user's own values if id is
+	// empty.
+	SwitchLedger(id uuid.UUID)
+}
+
+type APITokens interface {
+	// Create returns the created token.
+	Create(name string) (string, error)
+	Destroy(id uuid.UUID) error
+}
+
+// Blobs stores the contents of attachments on the client.
+type Blobs interface {
+	SaveBlob(hash string, blob js.Object) error
+	// LoadBlob returns nil if the content is not stored on the client.
+	LoadBlob(hash string) (js.Object, error)
+}
+
+type Backup interface {
+	Backup() (*models.Backup, error)
+	Restore(b *models.Backup) (int, error)
 }
 
 // TODO: Rename this to html_view
@@ -112,6 +216,12 @@ func getIDFromElement(e js.Object) (uuid.UUID, error) {
 }
 
 func printValueAt(e js.Object, name string, value string) {
+	printValueAndTextAt(e, name, value, value)
+}
+
+// printValueAndTextAt is like printValueAt, but prints text instead of value
+// at elements which show the value as their content.
+func printValueAndTextAt(e js.Object, name string, value string, text string) {
 	targets := []js.Object{}
 	if e.Get("name").Str() == name {
 		targets = append(targets, e)
@@ -135,17 +245,38 @@ func printValueAt(e js.Object, name string, value string) {
 	}
 
 	for _, e := range targets {
-		if e.Call("hasAttribute", "value").Bool() {
+		tagName := e.Get("tagName").Str()
+		if e.Call("hasAttribute", "value").Bool() ||
+			tagName == "SELECT" || tagName == "TEXTAREA" {
 			e.Set("value", value)
 		} else {
-			e.Set("textContent", value)
+			e.Set("textContent", text)
 		}
 	}
 }
 
 type HTMLView struct {
-	items       Items
-	onErrorFunc func(error)
+	items         Items
+	categories    Categories
+	categoryPaths map[uuid.UUID]string
+	accounts      Accounts
+	accountNames  map[uuid.UUID]string
+	budgets       Budgets
+	recurring     RecurringItems
+	rates         ExchangeRates
+	settings      Settings
+	attachments   Attachments
+	apiTokens     APITokens
+	ledger        Ledger
+	ledgers       Ledgers
+	blobs         Blobs
+	backup        Backup
+	baseCurrency  currency.Code
+	// locale is the separators of amounts for the user's language.
+	locale money.Locale
+	// recurringNames is the descriptions of the recurring items.
+	recurringNames map[uuid.UUID]string
+	onErrorFunc    func(error)
 }
 
 func empty(e js.Object) {
@@ -157,9 +288,19 @@ func empty(e js.Object) {
 func NewHTMLView(onErrorFunc func(error)) *HTMLView {
 	ch := make(chan js.Object)
 	v := &HTMLView{
-		onErrorFunc: onErrorFunc,
+		categoryPaths:  map[uuid.UUID]string{},
+		accountNames:   map[uuid.UUID]string{},
+		recurringNames: map[uuid.UUID]string{},
+		baseCurrency:   currency.Default,
+		locale:         money.DefaultLocale,
+		onErrorFunc:    onErrorFunc,
+	}
+	language := js.Global.Get("navigator").Get("language")
+	if !language.IsUndefined() && !language.IsNull() {
+		v.locale = money.LookupLocale(language.Str())
 	}
 	document := js.Global.Get("document")
+	printCurrencyOptions()
 	form := document.Call("getElementById", "form_item")
 	form.Set("onsubmit", async(func(e js.Object) {
 		// TODO: Remove this goroutine?
@@ -168,9 +309,41 @@ func NewHTMLView(onErrorFunc func(error)) *HTMLView {
 		}()
 	}))
 
+	form = document.Call("getElementById", "form_search")
+	form.Set("onsubmit", async(v.onSubmitSearch))
+
 	a := document.Call("getElementById", "link_export_as_csv")
 	a.Set("onclick", async(v.onClickExportAsCSV))
 
+	for id, format := range map[string]journal.Format{
+		"link_export_as_ledger":    journal.FormatLedger,
+		"link_export_as_hledger":   journal.FormatHledger,
+		"link_export_as_beancount": journal.FormatBeancount,
+	} {
+		format := format
+		a := document.Call("getElementById", id)
+		a.Set("onclick", async(func(e js.Object) {
+			if err := v.items.DownloadJournal(format); err != nil {
+				v.onErrorFunc(err)
+			}
+		}))
+	}
+
+	a = document.Call("getElementById", "link_dismiss_conflicts")
+	a.Set("onclick", async(v.onClickDismissConflicts))
+
+	form = document.Call("getElementById", "form_import")
+	form.Set("onsubmit", async(v.onSubmitImport))
+	// Files are likely to use the user's decimal separator.
+	sel := form.Call("querySelector", "select[name=Decimal]")
+	sel.Set("value", v.locale.Decimal)
+	form = document.Call("getElementById", "form_import_ofx")
+	form.Set("onsubmit", async(v.onSubmitImportOFX))
+	a = document.Call("getElementById", "link_import")
+	a.Set("onclick", async(v.onClickImport))
+	a = document.Call("getElementById", "link_cancel_import")
+	a.Set("onclick", async(v.onClickCancelImport))
+
 	go func() {
 		for e := range ch {
 			switch e.Get("type").Str() {
@@ -216,174 +389,1912 @@ func (v *HTMLView) addEventListeners(items Items, form js.Object) {
 		}
 	})
 	inputMoneyAmount := form.Call("querySelector", "input[name=Amount]")
+	selectCurrency := form.Call("querySelector", "select[name=Currency]")
+	// updateAmount parses the amount in the selected currency.
+	updateAmount := func(id uuid.UUID) error {
+		str := inputMoneyAmount.Get("value").Str()
+		if str == "" {
+			return nil
+		}
+		code := currency.Code(selectCurrency.Get("value").Str())
+		amount, err := v.parseAmount(str, code)
+		if err != nil {
+			return err
+		}
+		return items.UpdateAmount(id, amount)
+	}
 	inputMoneyAmount.Set("onchange", func(e js.Object) {
 		id, err := getIDFromElement(e.Get("target"))
 		if err != nil {
 			v.onErrorFunc(err)
 			return
 		}
-		amount := int32(e.Get("target").Get("value").Int())
-		if err := items.UpdateAmount(id, amount); err != nil {
+		if err := updateAmount(id); err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+	})
+	selectCurrency.Set("onchange", func(e js.Object) {
+		id, err := getIDFromElement(e.Get("target"))
+		if err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+		code := currency.Code(e.Get("target").Get("value").Str())
+		if err := items.UpdateCurrency(id, code); err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+		// The same input means a different amount in the minor unit.
+		if err := updateAmount(id); err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+		if err := v.updateSplits(items, form); err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+	})
+	selectDirection := form.Call("querySelector", "select[name=Direction]")
+	selectDirection.Set("onchange", func(e js.Object) {
+		id, err := getIDFromElement(e.Get("target"))
+		if err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+		var direction models.Direction
+		str := e.Get("target").Get("value").Str()
+		if err := direction.UnmarshalText([]byte(str)); err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+		if err := items.UpdateDirection(id, direction); err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+	})
+	v.addIDSelectListener(form, "CategoryID", items.UpdateCategory)
+	v.addIDSelectListener(form, "AccountID", items.UpdateAccount)
+	v.addIDSelectListener(form, "ToAccountID", items.UpdateToAccount)
+	inputTags := form.Call("querySelector", "input[name=Tags]")
+	inputTags.Set("onchange", func(e js.Object) {
+		id, err := getIDFromElement(e.Get("target"))
+		if err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+		tags := models.ParseTags(e.Get("target").Get("value").Str())
+		if err := items.UpdateTags(id, tags); err != nil {
 			v.onErrorFunc(err)
 			return
 		}
 	})
+	textareaNote := form.Call("querySelector", "textarea[name=Note]")
+	textareaNote.Set("onchange", func(e js.Object) {
+		id, err := getIDFromElement(e.Get("target"))
+		if err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+		note := e.Get("target").Get("value").Str()
+		if err := items.UpdateNote(id, note); err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+	})
+	inputAttachment := form.Call("querySelector", "input[name=Attachment]")
+	inputAttachment.Set("onchange", func(e js.Object) {
+		go v.onChangeAttachment(items, e.Get("target"))
+	})
+	aAddSplit := form.Call("querySelector", "a.add_split")
+	aAddSplit.Set("onclick", func(e js.Object) {
+		e.Call("preventDefault")
+		v.addSplitLine(items, form, nil)
+	})
 }
 
-func (v *HTMLView) SetItems(items Items) {
-	v.items = items
-	document := js.Global.Get("document")
-	form := document.Call("getElementById", "form_item")
-	v.addEventListeners(items, form)
+func formCurrency(form js.Object) currency.Code {
+	sel := form.Call("querySelector", "select[name=Currency]")
+	return currency.Code(sel.Get("value").Str())
 }
 
-func removeSingleHash() {
-	href := js.Global.Get("location").Get("href").Str()
-	if 0 < len(href) && href[len(href)-1] == '#' {
-		href = href[:len(href)-2]
-		js.Global.Get("history").Call(
-			"replaceState", "", "", href)
-	}
-}
+// addSplitLine adds a line to edit a split to the item form. split is nil for
+// a new line.
+func (v *HTMLView) addSplitLine(
+	items Items,
+	form js.Object,
+	split *models.Split) {
+	document := js.Global.Get("document")
+	line := document.Call("createElement", "span")
+	line.Get("classList").Call("add", "split_line")
 
-func (v *HTMLView) OnHashChange(e js.Object) {
-	hash := js.Global.Get("location").Get("hash").Str()
-	// Remove the initial '#'
-	if 1 <= len(hash) {
-		hash = hash[1:]
+	// The options of the categories are copied from the item's category.
+	query := "select[name=CategoryID]"
+	sel := form.Call("querySelector", query).Call("cloneNode", true)
+	sel.Set("name", "SplitCategoryID")
+	sel.Set("disabled", false)
+	line.Call("appendChild", sel)
+
+	inputAmount := document.Call("createElement", "input")
+	inputAmount.Set("name", "SplitAmount")
+	inputAmount.Set("type", "text")
+	inputAmount.Call("setAttribute", "inputmode", "decimal")
+	inputAmount.Set("placeholder", "Split amount")
+	line.Call("appendChild", inputAmount)
+
+	inputMemo := document.Call("createElement", "input")
+	inputMemo.Set("name", "SplitMemo")
+	inputMemo.Set("type", "text")
+	inputMemo.Set("placeholder", "Memo")
+	line.Call("appendChild", inputMemo)
+
+	if split != nil {
+		sel.Set("value", split.CategoryID.String())
+		amount := split.Amount.FormatLocale(formCurrency(form), v.locale)
+		inputAmount.Set("value", amount)
+		inputMemo.Set("value", split.Memo)
 	}
-	switch hash {
-	case "":
-		removeSingleHash()
-		v.updateMode(items.ModeTop, date.Date(0))
-	default:
-		ym, err := date.ParseISO8601(hash + "-01")
-		if err != nil {
+	onChange := func(e js.Object) {
+		if err := v.updateSplits(items, form); err != nil {
 			v.onErrorFunc(err)
 			return
 		}
-		v.updateMode(items.ModeYearMonth, ym)
 	}
+	for _, e := range []js.Object{sel, inputAmount, inputMemo} {
+		e.Set("onchange", onChange)
+	}
+
+	a := document.Call("createElement", "a")
+	a.Set("textContent", "Remove")
+	a.Call("setAttribute", "href", "")
+	a.Set("onclick", func(e js.Object) {
+		e.Call("preventDefault")
+		line.Get("parentNode").Call("removeChild", line)
+		onChange(e)
+	})
+	line.Call("appendChild", a)
+
+	form.Call("querySelector", ".split_lines").Call("appendChild", line)
 }
 
-func (v *HTMLView) onSubmit(e js.Object) {
-	form := e.Get("target")
+// formSplits parses the splits in the item form. Lines without amounts are
+// ignored.
+func (v *HTMLView) formSplits(form js.Object) ([]models.Split, error) {
+	code := formCurrency(form)
+	splits := []models.Split{}
+	lines := form.Call("querySelectorAll", ".split_line")
+	for i := 0; i < lines.Length(); i++ {
+		line := lines.Index(i)
+		value := func(name string) string {
+			query := fmt.Sprintf("*[name=\"%s\"]", name)
+			e := line.Call("querySelector", query)
+			return e.Get("value").Str()
+		}
+		str := value("SplitAmount")
+		if str == "" {
+			continue
+		}
+		amount, err := v.parseAmount(str, code)
+		if err != nil {
+			return nil, err
+		}
+		categoryID, err := parseOptionalID(value("SplitCategoryID"))
+		if err != nil {
+			return nil, err
+		}
+		splits = append(splits, models.Split{
+			CategoryID: categoryID,
+			Amount:     amount,
+			Memo:       value("SplitMemo"),
+		})
+	}
+	return splits, nil
+}
+
+func (v *HTMLView) updateSplits(items Items, form js.Object) error {
 	id, err := getIDFromElement(form)
 	if err != nil {
-		v.onErrorFunc(err)
-		return
+		return err
 	}
-	err = v.items.Save(id) //gopherjs:blocking
+	splits, err := v.formSplits(form)
 	if err != nil {
-		v.onErrorFunc(err)
-		return
+		return err
 	}
+	return items.UpdateSplits(id, splits)
 }
 
-func (v *HTMLView) onClickExportAsCSV(e js.Object) {
-	if err := v.items.DownloadCSV(); err != nil {
-		v.onErrorFunc(err)
-		return
+// printSplitLines prints the lines to edit the splits in the item form. The
+// lines are kept if they already represent the splits so that editing them is
+// not interrupted.
+func (v *HTMLView) printSplitLines(form js.Object, data *models.ItemData) {
+	sel := form.Call("querySelector", "select[name=CategoryID]")
+	sel.Set("disabled", len(data.Splits) != 0)
+	splits, err := v.formSplits(form)
+	if err == nil {
+		if len(splits) == 0 && len(data.Splits) == 0 {
+			return
+		}
+		if reflect.DeepEqual(splits, data.Splits) {
+			return
+		}
+	}
+	empty(form.Call("querySelector", ".split_lines"))
+	for n := range data.Splits {
+		v.addSplitLine(v.items, form, &data.Splits[n])
 	}
 }
 
-func (v *HTMLView) SetEditingItem(id uuid.UUID) {
+// printSplitRows prints the rows of the splits under the row of the item.
+func (v *HTMLView) printSplitRows(tr js.Object, data *models.ItemData) {
 	document := js.Global.Get("document")
-	form := document.Call("getElementById", "form_item")
-	form.Get("dataset").Set(toDatasetProp(datasetAttrID), id.String())
+	for {
+		next := tr.Get("nextElementSibling")
+		if next.IsNull() {
+			break
+		}
+		if !next.Get("classList").Call("contains", "split").Bool() {
+			break
+		}
+		next.Get("parentNode").Call("removeChild", next)
+	}
+	code := data.CurrencyCode()
+	next := tr.Get("nextElementSibling")
+	for _, s := range data.Splits {
+		texts := map[string]string{
+			"Subject":    s.Memo,
+			"Amount":     s.Amount.FormatLocale(code, v.locale),
+			"Currency":   code.String(),
+			"CategoryID": v.categoryPaths[s.CategoryID],
+		}
+		row := document.Call("createElement", "tr")
+		row.Get("classList").Call("add", "split")
+		// The cells are aligned with the cells of the item's row.
+		tds := tr.Get("children")
+		for i := 0; i < tds.Length(); i++ {
+			td := document.Call("createElement", "td")
+			td.Set("className", tds.Index(i).Get("className"))
+			key := tds.Index(i).Get("dataset").Get(
+				toDatasetProp(datasetAttrKey))
+			if !key.IsUndefined() {
+				td.Set("textContent", texts[key.Str()])
+			}
+			row.Call("appendChild", td)
+		}
+		tr.Get("parentNode").Call("insertBefore", row, next)
+	}
 }
 
-func (v *HTMLView) updateMode(mode items.Mode, ym date.Date) {
-	v.items.UpdateMode(mode, ym)
+// addIDSelectListener adds a listener to the select element whose options'
+// values are UUIDs or empty.
+func (v *HTMLView) addIDSelectListener(
+	form js.Object,
+	name string,
+	update func(id uuid.UUID, value uuid.UUID) error) {
+	query := fmt.Sprintf("select[name=%s]", name)
+	sel := form.Call("querySelector", query)
+	sel.Set("onchange", func(e js.Object) {
+		id, err := getIDFromElement(e.Get("target"))
+		if err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+		str := e.Get("target").Get("value").Str()
+		value, err := parseOptionalID(str)
+		if err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+		if err := update(id, value); err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+	})
 }
 
-func (v *HTMLView) PrintTitle(title string) {
+func (v *HTMLView) SetItems(items Items) {
+	v.items = items
 	document := js.Global.Get("document")
-	h1 := document.Call("querySelector", "body > main h1")
-	h1.Set("textContent", title)
+	form := document.Call("getElementById", "form_item")
+	v.addEventListeners(items, form)
 }
 
-func (v *HTMLView) PrintItems(ids []uuid.UUID) {
+func (v *HTMLView) SetCategories(categories Categories) {
+	v.categories = categories
 	document := js.Global.Get("document")
-	table := document.Call("getElementById", "table_items")
-	tbody := table.Call("getElementsByTagName", "tbody").Index(0)
-	empty(tbody)
-	for _, id := range ids {
-		v.addIDToItemTable(id)
-	}
-	display := "table"
-	if len(ids) == 0 {
-		display = "none"
-	}
-	table.Get("style").Set("display", display)
+	form := document.Call("getElementById", "form_category")
+	form.Set("onsubmit", async(v.onSubmitCategory))
 }
 
-func (v *HTMLView) PrintItemsAndTotal(ids []uuid.UUID, total int) {
-	v.PrintItems(ids)
-
+func (v *HTMLView) SetAccounts(accounts Accounts) {
+	v.accounts = accounts
 	document := js.Global.Get("document")
-	table := document.Call("getElementById", "table_items")
-	tbody := table.Call("getElementsByTagName", "tbody").Index(0)
+	form := document.Call("getElementById", "form_account")
+	form.Set("onsubmit", async(v.onSubmitAccount))
+}
 
-	tr := document.Call("createElement", "tr")
+func (v *HTMLView) SetRecurringItems(recurring RecurringItems) {
+	v.recurring = recurring
+	document := js.Global.Get("document")
+	form := document.Call("getElementById", "form_recurring")
+	form.Set("onsubmit", async(v.onSubmitRecurringItem))
+}
 
-	td := document.Call("createElement", "td")
-	td.Set("textContent", "")
-	tr.Call("appendChild", td)
+func (v *HTMLView) SetBudgets(budgets Budgets) {
+	v.budgets = budgets
+	document := js.Global.Get("document")
+	form := document.Call("getElementById", "form_budget")
+	form.Set("onsubmit", async(v.onSubmitBudget))
+}
 
-	td = document.Call("createElement", "td")
-	td.Set("textContent", "(Total)")
-	tr.Call("appendChild", td)
+func (v *HTMLView) SetAttachments(attachments Attachments) {
+	v.attachments = attachments
+}
 
-	td = document.Call("createElement", "td")
-	td.Set("textContent", strconv.Itoa(int(total)))
-	td.Get("classList").Call("add", "number")
-	tr.Call("appendChild", td)
+func (v *HTMLView) SetBlobs(blobs Blobs) {
+	v.blobs = blobs
+}
 
-	td = document.Call("createElement", "td")
-	td.Set("textContent", "")
-	tr.Call("appendChild", td)
+func (v *HTMLView) SetBackup(backup Backup) {
+	v.backup = backup
+	document := js.Global.Get("document")
+	a := document.Call("getElementById", "link_backup")
+	a.Set("onclick", async(v.onClickBackup))
+	form := document.Call("getElementById", "form_restore")
+	form.Set("onsubmit", async(v.onSubmitRestore))
+}
 
-	tbody.Call("appendChild", tr)
+func (v *HTMLView) SetAPITokens(tokens APITokens) {
+	v.apiTokens = tokens
+	document := js.Global.Get("document")
+	form := document.Call("getElementById", "form_api_token")
+	form.Set("onsubmit", async(v.onSubmitAPIToken))
 }
 
-func (v *HTMLView) PrintYearMonths(yms []date.Date) {
+func (v *HTMLView) SetLedger(ledger Ledger) {
+	v.ledger = ledger
 	document := js.Global.Get("document")
-	ul := document.Call("getElementById", "year_months")
-	empty(ul)
-	for _, ym := range yms {
-		a := document.Call("createElement", "a")
-		date := fmt.Sprintf("%04d-%02d", ym.Year(), ym.Month())
-		a.Set("textContent", date)
-		a.Set("href", "#"+date)
-		li := document.Call("createElement", "li")
-		li.Call("appendChild", a)
-		ul.Call("appendChild", li)
-	}
+	form := document.Call("getElementById", "form_member")
+	form.Set("onsubmit", async(v.onSubmitMember))
 }
 
-func (v *HTMLView) PrintItem(data models.ItemData) {
+func (v *HTMLView) SetLedgers(ledgers Ledgers) {
+	v.ledgers = ledgers
 	document := js.Global.Get("document")
-	id := data.Meta.ID
-	query := fmt.Sprintf(
-		"*[data-%s=\"%s\"]",
-		html.EscapeString(datasetAttrID),
-		html.EscapeString(id.String()))
-	elements := document.Call("querySelectorAll", query)
-	for i := 0; i < elements.Length(); i++ {
-		e := elements.Index(i)
-		printValueAt(e, "Date", data.Date.String())
-		printValueAt(e, "Subject", data.Subject)
-		printValueAt(e, "Amount", strconv.Itoa(int(data.Amount)))
+	form := document.Call("getElementById", "form_ledger")
+	form.Set("onsubmit", async(v.onSubmitLedger))
+	sel := document.Call("getElementById", "select_ledger")
+	sel.Set("onchange", func(e js.Object) {
+		id := uuid.UUID(e.Get("target").Get("value").Str())
+		v.ledgers.SwitchLedger(id)
+	})
+	if ledgers.Ledger() != "" {
+		es := document.Call("querySelectorAll", ".personal")
+		for i := 0; i < es.Length(); i++ {
+			es.Index(i).Get("style").Set("display", "none")
+		}
 	}
+	go v.printLedgers()
 }
 
-func (v *HTMLView) addIDToItemTable(id uuid.UUID) {
-	t := reflect.TypeOf((*models.ItemData)(nil)).Elem()
+func (v *HTMLView) SetExchangeRates(rates ExchangeRates) {
+	v.rates = rates
+	document := js.Global.Get("document")
+	form := document.Call("getElementById", "form_exchange_rate")
+	form.Set("onsubmit", async(v.onSubmitExchangeRate))
+	form = document.Call("getElementById", "form_import_exchange_rates")
+	form.Set("onsubmit", async(v.onSubmitImportExchangeRates))
+}
 
+func (v *HTMLView) SetSettings(settings Settings) {
+	v.settings = settings
 	document := js.Global.Get("document")
-	table := document.Call("getElementById", "table_items")
-	query := fmt.Sprintf("tr[data-%s=\"%s\"]", datasetAttrID, id.String())
+	sel := document.Call("getElementById", "select_base_currency")
+	sel.Set("onchange", func(e js.Object) {
+		code := currency.Code(e.Get("target").Get("value").Str())
+		go func() {
+			if err := v.settings.SetBaseCurrency(code); err != nil {
+				v.onErrorFunc(err)
+				return
+			}
+		}()
+	})
+}
+
+// printCurrencyOptions adds the currencies to the select elements for
+// currencies.
+func printCurrencyOptions() {
+	document := js.Global.Get("document")
+	sels := document.Call("querySelectorAll", "select.currency")
+	for i := 0; i < sels.Length(); i++ {
+		sel := sels.Index(i)
+		for _, c := range currency.Codes() {
+			option := document.Call("createElement", "option")
+			option.Set("value", c.String())
+			option.Set("textContent", c.String())
+			sel.Call("appendChild", option)
+		}
+	}
+	selectDefaultCurrency(currency.Default)
+}
+
+// selectDefaultCurrency makes the currency selected by default at the select
+// elements for currencies. The item form is not changed as it shows the
+// editing item's currency.
+func selectDefaultCurrency(code currency.Code) {
+	document := js.Global.Get("document")
+	sels := document.Call("querySelectorAll", "select.currency")
+	for i := 0; i < sels.Length(); i++ {
+		sel := sels.Index(i)
+		options := sel.Get("options")
+		for j := 0; j < options.Length(); j++ {
+			option := options.Index(j)
+			selected := option.Get("value").Str() == code.String()
+			option.Set("defaultSelected", selected)
+		}
+		if sel.Call("closest", "#form_item").IsNull() {
+			sel.Set("value", code.String())
+		}
+	}
+}
+
+// parseAmount parses str as an amount in the currency, formatted for the
+// user's locale.
+func (v *HTMLView) parseAmount(
+	str string,
+	code currency.Code) (money.Amount, error) {
+	return money.ParseLocale(str, code, v.locale)
+}
+
+// formatAmount formats an amount in the base currency.
+func (v *HTMLView) formatAmount(amount money.Amount) string {
+	return amount.FormatLocale(v.baseCurrency, v.locale)
+}
+
+// itemAmount returns the amount of the item with its currency like
+// '12.50 USD'.
+func (v *HTMLView) itemAmount(data *models.ItemData) string {
+	code := data.CurrencyCode()
+	return data.Amount.FormatLocale(code, v.locale) + " " + code.String()
+}
+
+// parseOptionalID parses str as a UUID. An empty str means no ID.
+func parseOptionalID(str string) (uuid.UUID, error) {
+	if str == "" {
+		return "", nil
+	}
+	return uuid.ParseString(str)
+}
+
+func removeSingleHash() {
+	href := js.Global.Get("location").Get("href").Str()
+	if 0 < len(href) && href[len(href)-1] == '#' {
+		href = href[:len(href)-2]
+		js.Global.Get("history").Call(
+			"replaceState", "", "", href)
+	}
+}
+
+// tagHashPrefix is the prefix of the hashes to list the items with tags like
+// '#tag/reimbursable'.
+const tagHashPrefix = "tag/"
+
+// searchHashPrefix is the prefix of the hashes to search items like
+// '#search/plumber%20date:2025'.
+const searchHashPrefix = "search/"
+
+func (v *HTMLView) OnHashChange(e js.Object) {
+	hash := js.Global.Get("location").Get("hash").Str()
+	// Remove the initial '#'
+	if 1 <= len(hash) {
+		hash = hash[1:]
+	}
+	switch {
+	case hash == "":
+		removeSingleHash()
+		v.updateMode(items.ModeTop, date.Date(0))
+	case strings.HasPrefix(hash, tagHashPrefix):
+		tag, err := url.PathUnescape(hash[len(tagHashPrefix):])
+		if err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+		v.items.UpdateTagMode(tag)
+	case strings.HasPrefix(hash, searchHashPrefix):
+		query, err := url.PathUnescape(hash[len(searchHashPrefix):])
+		if err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+		document := js.Global.Get("document")
+		input := document.Call("querySelector", "#form_search input")
+		input.Set("value", query)
+		if err := v.items.UpdateSearchMode(query); err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+	case isYearHash(hash):
+		y, err := strconv.Atoi(hash)
+		if err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+		v.updateMode(items.ModeYear, date.New(y, 1, 1))
+	default:
+		ym, err := date.ParseISO8601(hash + "-01")
+		if err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+		v.updateMode(items.ModeYearMonth, ym)
+	}
+}
+
+// isYearHash reports whether the hash is a year like '2026'.
+func isYearHash(hash string) bool {
+	if len(hash) != 4 {
+		return false
+	}
+	for _, r := range hash {
+		if r < '0' || '9' < r {
+			return false
+		}
+	}
+	return true
+}
+
+func (v *HTMLView) onSubmitSearch(e js.Object) {
+	input := e.Get("target").Call("querySelector", "input[name=Query]")
+	query := strings.TrimSpace(input.Get("value").Str())
+	if query == "" {
+		js.Global.Get("location").Set("hash", "")
+		return
+	}
+	hash := "#" + searchHashPrefix + url.PathEscape(query)
+	js.Global.Get("location").Set("hash", hash)
+}
+
+func (v *HTMLView) onSubmit(e js.Object) {
+	form := e.Get("target")
+	id, err := getIDFromElement(form)
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	err = v.items.Save(id) //gopherjs:blocking
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+}
+
+func (v *HTMLView) onSubmitCategory(e js.Object) {
+	form := e.Get("target")
+	input := form.Call("querySelector", "input[name=Name]")
+	name := input.Get("value").Str()
+	sel := form.Call("querySelector", "select[name=ParentID]")
+	parentID, err := parseOptionalID(sel.Get("value").Str())
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	if err := v.categories.Create(name, parentID); err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	input.Set("value", "")
+}
+
+func (v *HTMLView) onClickToDeleteCategory(e js.Object) {
+	id, err := getIDFromElement(e.Get("target"))
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	if err := v.categories.Destroy(id); err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+}
+
+func (v *HTMLView) onSubmitAccount(e js.Object) {
+	form := e.Get("target")
+	inputName := form.Call("querySelector", "input[name=Name]")
+	name := inputName.Get("value").Str()
+	query := "input[name=OpeningBalance]"
+	inputBalance := form.Call("querySelector", query)
+	// The opening balance is in the base currency.
+	balance := money.Amount(0)
+	if str := inputBalance.Get("value").Str(); str != "" {
+		var err error
+		balance, err = v.parseAmount(str, v.baseCurrency)
+		if err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+	}
+	if err := v.accounts.Create(name, balance); err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	inputName.Set("value", "")
+	inputBalance.Set("value", "")
+}
+
+func (v *HTMLView) onClickToDeleteAccount(e js.Object) {
+	id, err := getIDFromElement(e.Get("target"))
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	if err := v.accounts.Destroy(id); err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+}
+
+// parseOptionalDate parses str as a date. An empty str means no date.
+func parseOptionalDate(str string) (date.Date, error) {
+	if str == "" {
+		return date.Date(0), nil
+	}
+	return date.ParseISO8601(str)
+}
+
+func (v *HTMLView) onSubmitRecurringItem(e js.Object) {
+	form := e.Get("target")
+	value := func(name string) string {
+		query := fmt.Sprintf("*[name=\"%s\"]", name)
+		return form.Call("querySelector", query).Get("value").Str()
+	}
+	var err error
+	r := models.RecurringItem{}
+	r.Subject = value("Subject")
+	r.Currency = currency.Code(value("Currency"))
+	r.Amount, err = v.parseAmount(value("Amount"), r.Currency)
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	dir := []byte(value("Direction"))
+	if err := r.Direction.UnmarshalText(dir); err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	for _, f := range []struct {
+		name string
+		id   *uuid.UUID
+	}{
+		{"CategoryID", &r.CategoryID},
+		{"AccountID", &r.AccountID},
+		{"ToAccountID", &r.ToAccountID},
+	} {
+		*f.id, err = parseOptionalID(value(f.name))
+		if err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+	}
+	rec := []byte(value("Recurrence"))
+	if err := r.Recurrence.UnmarshalText(rec); err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	r.Start, err = date.ParseISO8601(value("Start"))
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	r.End, err = parseOptionalDate(value("End"))
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	switch r.Recurrence {
+	case models.RecurrenceMonthly:
+		r.Day = r.Start.Day()
+	case models.RecurrenceWeekly:
+		r.Weeks = 1
+		if str := value("Weeks"); str != "" {
+			r.Weeks, err = strconv.Atoi(str)
+			if err != nil {
+				v.onErrorFunc(err)
+				return
+			}
+		}
+	}
+	if err := v.recurring.Create(r); err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	form.Call("reset")
+}
+
+func (v *HTMLView) onClickToDeleteRecurringItem(e js.Object) {
+	id, err := getIDFromElement(e.Get("target"))
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	if err := v.recurring.Destroy(id); err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+}
+
+func (v *HTMLView) onSubmitBudget(e js.Object) {
+	form := e.Get("target")
+	sel := form.Call("querySelector", "select[name=CategoryID]")
+	categoryID, err := parseOptionalID(sel.Get("value").Str())
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	query := "input[name=SubjectPattern]"
+	inputPattern := form.Call("querySelector", query)
+	pattern := inputPattern.Get("value").Str()
+	inputMonth := form.Call("querySelector", "input[name=Month]")
+	month := date.Date(0)
+	// An empty month means the budget recurs every month.
+	if str := inputMonth.Get("value").Str(); str != "" {
+		month, err = date.ParseISO8601(str + "-01")
+		if err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+	}
+	inputLimit := form.Call("querySelector", "input[name=Limit]")
+	// The limit is in the base currency.
+	str := inputLimit.Get("value").Str()
+	limit, err := v.parseAmount(str, v.baseCurrency)
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	err = v.budgets.Create(categoryID, pattern, month, limit)
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	inputPattern.Set("value", "")
+	inputMonth.Set("value", "")
+	inputLimit.Set("value", "")
+}
+
+func (v *HTMLView) onClickToDeleteBudget(e js.Object) {
+	id, err := getIDFromElement(e.Get("target"))
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	if err := v.budgets.Destroy(id); err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+}
+
+func (v *HTMLView) onSubmitExchangeRate(e js.Object) {
+	form := e.Get("target")
+	value := func(name string) string {
+		query := fmt.Sprintf("*[name=\"%s\"]", name)
+		return form.Call("querySelector", query).Get("value").Str()
+	}
+	d, err := date.ParseISO8601(value("Date"))
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	from := currency.Code(value("From"))
+	to := currency.Code(value("To"))
+	if err := v.rates.Create(d, from, to, value("Rate")); err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	form.Call("reset")
+}
+
+func (v *HTMLView) onSubmitImportExchangeRates(e js.Object) {
+	form := e.Get("target")
+	data, err := readFile(form.Call("querySelector", "input[name=File]"))
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	if _, err := v.rates.ImportCSV(data); err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	form.Call("reset")
+}
+
+func (v *HTMLView) onSubmitAPIToken(e js.Object) {
+	form := e.Get("target")
+	input := form.Call("querySelector", "input[name=Name]")
+	token, err := v.apiTokens.Create(input.Get("value").Str())
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	input.Set("value", "")
+	// The token is shown only once.
+	document := js.Global.Get("document")
+	p := document.Call("getElementById", "api_token")
+	p.Set("textContent", token)
+	p.Get("style").Set("display", "block")
+}
+
+func (v *HTMLView) onClickToRevokeAPIToken(e js.Object) {
+	id, err := getIDFromElement(e.Get("target"))
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	if err := v.apiTokens.Destroy(id); err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+}
+
+func (v *HTMLView) onSubmitLedger(e js.Object) {
+	form := e.Get("target")
+	input := form.Call("querySelector", "input[name=Name]")
+	ledger, err := v.ledgers.CreateLedger(input.Get("value").Str())
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	v.ledgers.SwitchLedger(ledger.ID)
+}
+
+func (v *HTMLView) onSubmitMember(e js.Object) {
+	form := e.Get("target")
+	input := form.Call("querySelector", "input[name=Email]")
+	sel := form.Call("querySelector", "select[name=Role]")
+	var role models.Role
+	err := role.UnmarshalText([]byte(sel.Get("value").Str()))
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	email := input.Get("value").Str()
+	if err := v.ledger.SetMember(email, role); err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	form.Call("reset")
+}
+
+func (v *HTMLView) onClickToDeleteExchangeRate(e js.Object) {
+	id, err := getIDFromElement(e.Get("target"))
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	if err := v.rates.Destroy(id); err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+}
+
+func (v *HTMLView) onSubmitImport(e js.Object) {
+	form := e.Get("target")
+	value := func(name string) string {
+		query := fmt.Sprintf("*[name=\"%s\"]", name)
+		return form.Call("querySelector", query).Get("value").Str()
+	}
+	column := func(name string) (int, error) {
+		// Columns are 1-based in the form.
+		n, err := strconv.Atoi(value(name))
+		if err != nil {
+			return 0, err
+		}
+		return n - 1, nil
+	}
+	var err error
+	m := items.CSVMapping{
+		Encoding:   value("Encoding"),
+		DateLayout: value("DateLayout"),
+		Currency:   currency.Code(value("Currency")),
+	}
+	// Amounts like '1.234,50' have ',' as the decimal separator.
+	if value("Decimal") == "," {
+		m.Locale = money.Locale{Decimal: ",", Group: "."}
+	}
+	if str := value("HeaderRows"); str != "" {
+		m.HeaderRows, err = strconv.Atoi(str)
+		if err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+	}
+	for _, c := range []struct {
+		name   string
+		column *int
+	}{
+		{"DateColumn", &m.DateColumn},
+		{"SubjectColumn", &m.SubjectColumn},
+		{"AmountColumn", &m.AmountColumn},
+	} {
+		*c.column, err = column(c.name)
+		if err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+	}
+	switch value("Sign") {
+	case "negative-expense":
+		m.Sign = items.CSVSignNegativeExpense
+	case "positive-expense":
+		m.Sign = items.CSVSignPositiveExpense
+	case "separate-columns":
+		m.Sign = items.CSVSignSeparateColumns
+		m.DepositColumn, err = column("DepositColumn")
+		if err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+	default:
+		v.onErrorFunc(errors.New("view: invalid sign convention"))
+		return
+	}
+	m.AccountID, err = parseOptionalID(value("AccountID"))
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+
+	data, err := readFile(form.Call("querySelector", "input[name=File]"))
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	if err := v.items.PreviewCSV(data, m); err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+}
+
+func (v *HTMLView) onSubmitImportOFX(e js.Object) {
+	form := e.Get("target")
+	sel := form.Call("querySelector", "select[name=Encoding]")
+	encoding := sel.Get("value").Str()
+	sel = form.Call("querySelector", "select[name=AccountID]")
+	accountID, err := parseOptionalID(sel.Get("value").Str())
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	data, err := readFile(form.Call("querySelector", "input[name=File]"))
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	err = v.items.PreviewOFX(data, encoding, accountID)
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+}
+
+// readFile reads the file selected at the input element.
+func readFile(input js.Object) ([]byte, error) {
+	files := input.Get("files")
+	if files.Length() == 0 {
+		return nil, errors.New("view: no file is selected")
+	}
+	ch := make(chan js.Object)
+	reader := js.Global.Get("FileReader").New()
+	reader.Set("onload", func(e js.Object) {
+		go func() {
+			ch <- e.Get("target").Get("result")
+		}()
+	})
+	reader.Call("readAsArrayBuffer", files.Index(0))
+	buf := <-ch
+	array := js.Global.Get("Uint8Array").New(buf)
+	return array.Interface().([]byte), nil
+}
+
+func (v *HTMLView) onClickImport(e js.Object) {
+	document := js.Global.Get("document")
+	input := document.Call("getElementById", "input_import_duplicates")
+	if err := v.items.Import(input.Get("checked").Bool()); err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+}
+
+func (v *HTMLView) onClickCancelImport(e js.Object) {
+	v.items.CancelImport()
+}
+
+func (v *HTMLView) onClickBackup(e js.Object) {
+	b, err := v.backup.Backup()
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	j, err := json.Marshal(b)
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	v.Download(j, "kakeibo-backup.json")
+}
+
+func (v *HTMLView) onSubmitRestore(e js.Object) {
+	form := e.Get("target")
+	data, err := readFile(form.Call("querySelector", "input[name=File]"))
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	b := &models.Backup{}
+	if err := json.Unmarshal(data, b); err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	n, err := v.backup.Restore(b)
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	form.Call("reset")
+	msg := fmt.Sprintf("%d values are restored.", n)
+	js.Global.Call("alert", msg)
+}
+
+func (v *HTMLView) onClickExportAsCSV(e js.Object) {
+	if err := v.items.DownloadCSV(); err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+}
+
+func (v *HTMLView) SetEditingItem(id uuid.UUID) {
+	document := js.Global.Get("document")
+	form := document.Call("getElementById", "form_item")
+	form.Get("dataset").Set(toDatasetProp(datasetAttrID), id.String())
+	empty(form.Call("querySelector", ".split_lines"))
+}
+
+func (v *HTMLView) updateMode(mode items.Mode, ym date.Date) {
+	v.items.UpdateMode(mode, ym)
+}
+
+func (v *HTMLView) PrintTitle(title string) {
+	document := js.Global.Get("document")
+	h1 := document.Call("querySelector", "body > main h1")
+	h1.Set("textContent", title)
+}
+
+func (v *HTMLView) PrintItems(ids []uuid.UUID) {
+	document := js.Global.Get("document")
+	table := document.Call("getElementById", "table_items")
+	tbody := table.Call("getElementsByTagName", "tbody").Index(0)
+	empty(tbody)
+	for _, id := range ids {
+		v.addIDToItemTable(id)
+	}
+	display := "table"
+	if len(ids) == 0 {
+		display = "none"
+	}
+	table.Get("style").Set("display", display)
+}
+
+func (v *HTMLView) PrintItemsAndTotals(ids []uuid.UUID, totals items.Totals) {
+	v.PrintItems(ids)
+
+	document := js.Global.Get("document")
+	table := document.Call("getElementById", "table_items")
+	tbody := table.Call("getElementsByTagName", "tbody").Index(0)
+
+	rows := []struct {
+		label string
+		value string
+	}{
+		{"(Income)", v.formatAmount(totals.Income)},
+		{"(Expense)", v.formatAmount(totals.Expense)},
+		{"(Net)", v.formatAmount(totals.Net())},
+	}
+	if 0 < totals.Unconverted {
+		label := fmt.Sprintf(
+			"(%d items without exchange rates are not counted)",
+			totals.Unconverted)
+		rows = append(rows, struct {
+			label string
+			value string
+		}{label, ""})
+	}
+	for _, row := range rows {
+		tr := document.Call("createElement", "tr")
+
+		td := document.Call("createElement", "td")
+		td.Set("textContent", "")
+		tr.Call("appendChild", td)
+
+		td = document.Call("createElement", "td")
+		td.Set("textContent", row.label)
+		tr.Call("appendChild", td)
+
+		td = document.Call("createElement", "td")
+		td.Set("textContent", row.value)
+		td.Get("classList").Call("add", "number")
+		tr.Call("appendChild", td)
+
+		// The other columns
+		ths := table.Call("querySelectorAll", "thead th")
+		for j := 0; j < ths.Length()-3; j++ {
+			td = document.Call("createElement", "td")
+			td.Set("textContent", "")
+			tr.Call("appendChild", td)
+		}
+
+		tbody.Call("appendChild", tr)
+	}
+}
+
+func (v *HTMLView) PrintYearMonths(yms []date.Date) {
+	document := js.Global.Get("document")
+	ul := document.Call("getElementById", "year_months")
+	empty(ul)
+	year := 0
+	for _, ym := range yms {
+		// yms are sorted in descending order, and each year is
+		// followed by its months.
+		if ym.Year() != year {
+			year = ym.Year()
+			a := document.Call("createElement", "a")
+			y := fmt.Sprintf("%04d", year)
+			a.Set("textContent", y)
+			a.Set("href", "#"+y)
+			li := document.Call("createElement", "li")
+			li.Get("classList").Call("add", "year")
+			li.Call("appendChild", a)
+			ul.Call("appendChild", li)
+		}
+		a := document.Call("createElement", "a")
+		date := fmt.Sprintf("%04d-%02d", ym.Year(), ym.Month())
+		a.Set("textContent", date)
+		a.Set("href", "#"+date)
+		li := document.Call("createElement", "li")
+		li.Call("appendChild", a)
+		ul.Call("appendChild", li)
+	}
+}
+
+func (v *HTMLView) PrintTags(tags []string) {
+	document := js.Global.Get("document")
+	ul := document.Call("getElementById", "tags")
+	empty(ul)
+	for _, t := range tags {
+		a := document.Call("createElement", "a")
+		a.Set("textContent", t)
+		a.Set("href", "#"+tagHashPrefix+url.PathEscape(t))
+		li := document.Call("createElement", "li")
+		li.Call("appendChild", a)
+		ul.Call("appendChild", li)
+	}
+}
+
+func (v *HTMLView) PrintCategories(categories []items.CategoryPath) {
+	v.categoryPaths = map[uuid.UUID]string{}
+	ids := make([]uuid.UUID, len(categories))
+	paths := make([]string, len(categories))
+	for i, c := range categories {
+		v.categoryPaths[c.ID] = c.Path
+		ids[i] = c.ID
+		paths[i] = c.Path
+	}
+
+	document := js.Global.Get("document")
+	for _, query := range []string{
+		"#form_item select[name=CategoryID]",
+		"#form_category select[name=ParentID]",
+		"#form_budget select[name=CategoryID]",
+		"#form_recurring select[name=CategoryID]",
+	} {
+		sel := document.Call("querySelector", query)
+		printOptions(sel, ids, paths)
+	}
+	query := "#form_item select[name=SplitCategoryID]"
+	sels := document.Call("querySelectorAll", query)
+	for i := 0; i < sels.Length(); i++ {
+		printOptions(sels.Index(i), ids, paths)
+	}
+
+	ul := document.Call("getElementById", "categories")
+	empty(ul)
+	for _, c := range categories {
+		li := document.Call("createElement", "li")
+		prop := toDatasetProp(datasetAttrID)
+		li.Get("dataset").Set(prop, c.ID.String())
+		li.Set("textContent", c.Path+" ")
+		a := document.Call("createElement", "a")
+		a.Set("textContent", "Delete")
+		a.Call("setAttribute", "href", "")
+		a.Get("classList").Call("add", "writable")
+		a.Set("onclick", async(v.onClickToDeleteCategory))
+		li.Call("appendChild", a)
+		ul.Call("appendChild", li)
+	}
+}
+
+func (v *HTMLView) PrintCategoryTotals(totals []items.CategoryTotals) {
+	document := js.Global.Get("document")
+	table := document.Call("getElementById", "table_categories")
+	tbody := table.Call("getElementsByTagName", "tbody").Index(0)
+	empty(tbody)
+	for _, t := range totals {
+		tr := document.Call("createElement", "tr")
+
+		td := document.Call("createElement", "td")
+		path := t.Path
+		if t.ID == "" {
+			path = "(Uncategorized)"
+		}
+		td.Set("textContent", path)
+		tr.Call("appendChild", td)
+
+		for _, value := range []money.Amount{
+			t.Totals.Income,
+			t.Totals.Expense,
+			t.Totals.Net(),
+		} {
+			td := document.Call("createElement", "td")
+			td.Set("textContent", v.formatAmount(value))
+			td.Get("classList").Call("add", "number")
+			tr.Call("appendChild", td)
+		}
+
+		tbody.Call("appendChild", tr)
+	}
+	display := "table"
+	if len(totals) == 0 {
+		display = "none"
+	}
+	table.Get("style").Set("display", display)
+}
+
+// PrintMonthTotals prints the totals of each month with the year-to-date net
+// and the change of the net from the previous year.
+func (v *HTMLView) PrintMonthTotals(
+	months []items.MonthTotals,
+	total items.Totals,
+	previousTotal items.Totals) {
+	document := js.Global.Get("document")
+	table := document.Call("getElementById", "table_months")
+	tbody := table.Call("getElementsByTagName", "tbody").Index(0)
+	empty(tbody)
+	addRow := func(label js.Object, values []string) {
+		tr := document.Call("createElement", "tr")
+		td := document.Call("createElement", "td")
+		td.Call("appendChild", label)
+		tr.Call("appendChild", td)
+		for _, value := range values {
+			td := document.Call("createElement", "td")
+			td.Set("textContent", value)
+			td.Get("classList").Call("add", "number")
+			tr.Call("appendChild", td)
+		}
+		tbody.Call("appendChild", tr)
+	}
+	unconverted := 0
+	for _, m := range months {
+		a := document.Call("createElement", "a")
+		ym := fmt.Sprintf("%04d-%02d", m.Month.Year(), m.Month.Month())
+		a.Set("textContent", ym)
+		a.Set("href", "#"+ym)
+		addRow(a, []string{
+			v.formatAmount(m.Totals.Income),
+			v.formatAmount(m.Totals.Expense),
+			v.formatAmount(m.Totals.Net()),
+			v.formatAmount(m.YearToDate.Net()),
+			v.formatAmount(m.PreviousYear.Net()),
+			v.formatChange(m.Totals, m.PreviousYear),
+		})
+		unconverted += m.Totals.Unconverted
+	}
+	if 0 < len(months) {
+		label := document.Call("createTextNode", "(Total)")
+		addRow(label, []string{
+			v.formatAmount(total.Income),
+			v.formatAmount(total.Expense),
+			v.formatAmount(total.Net()),
+			"",
+			v.formatAmount(previousTotal.Net()),
+			v.formatChange(total, previousTotal),
+		})
+	}
+	if 0 < unconverted {
+		text := fmt.Sprintf(
+			"(%d items without exchange rates are not counted)",
+			unconverted)
+		addRow(document.Call("createTextNode", text), []string{})
+	}
+	display := "table"
+	if len(months) == 0 {
+		display = "none"
+	}
+	table.Get("style").Set("display", display)
+}
+
+// formatChange formats the change of the net from the previous totals with a
+// sign, or returns an empty string if the change overflows.
+func (v *HTMLView) formatChange(totals, previous items.Totals) string {
+	change, err := totals.Net().Sub(previous.Net())
+	if err != nil {
+		return ""
+	}
+	str := v.formatAmount(change)
+	if 0 < change {
+		str = "+" + str
+	}
+	return str
+}
+
+// printChart injects the SVG image into the element, or hides the element if
+// svg is empty. The SVG is generated by the chart package, which escapes the
+// texts.
+func printChart(id string, svg string) {
+	div := js.Global.Get("document").Call("getElementById", id)
+	div.Set("innerHTML", svg)
+	display := "inline-block"
+	if svg == "" {
+		display = "none"
+	}
+	div.Get("style").Set("display", display)
+}
+
+func (v *HTMLView) PrintMonthlyExpenseChart(svg string) {
+	printChart("chart_monthly", svg)
+}
+
+func (v *HTMLView) PrintCategoryChart(svg string) {
+	printChart("chart_categories", svg)
+}
+
+func (v *HTMLView) PrintCumulativeChart(svg string) {
+	printChart("chart_cumulative", svg)
+}
+
+// printOptions replaces the options of the select element except for the
+// first one, keeping the selected value.
+func printOptions(sel js.Object, values []uuid.UUID, texts []string) {
+	document := js.Global.Get("document")
+	value := sel.Get("value").Str()
+	for 1 < sel.Get("options").Length() {
+		sel.Call("removeChild", sel.Get("lastChild"))
+	}
+	for i, v := range values {
+		option := document.Call("createElement", "option")
+		option.Set("value", v.String())
+		option.Set("textContent", texts[i])
+		sel.Call("appendChild", option)
+	}
+	sel.Set("value", value)
+}
+
+func (v *HTMLView) PrintAccounts(accounts []models.Account) {
+	v.accountNames = map[uuid.UUID]string{}
+	ids := make([]uuid.UUID, len(accounts))
+	names := make([]string, len(accounts))
+	for i, a := range accounts {
+		v.accountNames[a.Meta.ID] = a.Name
+		ids[i] = a.Meta.ID
+		names[i] = a.Name
+	}
+
+	document := js.Global.Get("document")
+	for _, query := range []string{
+		"#form_item select[name=AccountID]",
+		"#form_item select[name=ToAccountID]",
+		"#form_recurring select[name=AccountID]",
+		"#form_recurring select[name=ToAccountID]",
+		"#form_import select[name=AccountID]",
+		"#form_import_ofx select[name=AccountID]",
+	} {
+		sel := document.Call("querySelector", query)
+		printOptions(sel, ids, names)
+	}
+}
+
+func (v *HTMLView) PrintRecurringItems(recurring []models.RecurringItem) {
+	v.recurringNames = map[uuid.UUID]string{}
+	for _, r := range recurring {
+		v.recurringNames[r.Meta.ID] = r.Description()
+	}
+
+	document := js.Global.Get("document")
+	ul := document.Call("getElementById", "recurring_items")
+	empty(ul)
+	for _, r := range recurring {
+		li := document.Call("createElement", "li")
+		prop := toDatasetProp(datasetAttrID)
+		li.Get("dataset").Set(prop, r.Meta.ID.String())
+		code := r.Currency
+		if code == "" {
+			code = currency.Default
+		}
+		text := fmt.Sprintf(
+			"%s: %s %s (%s) ",
+			r.Subject,
+			r.Amount.FormatLocale(code, v.locale),
+			code,
+			r.Description())
+		li.Set("textContent", text)
+		a := document.Call("createElement", "a")
+		a.Set("textContent", "Delete")
+		a.Call("setAttribute", "href", "")
+		a.Get("classList").Call("add", "writable")
+		a.Set("onclick", async(v.onClickToDeleteRecurringItem))
+		li.Call("appendChild", a)
+		ul.Call("appendChild", li)
+	}
+}
+
+func (v *HTMLView) PrintExchangeRates(rates []models.ExchangeRate) {
+	document := js.Global.Get("document")
+	ul := document.Call("getElementById", "exchange_rates")
+	empty(ul)
+	for _, r := range rates {
+		li := document.Call("createElement", "li")
+		prop := toDatasetProp(datasetAttrID)
+		li.Get("dataset").Set(prop, r.Meta.ID.String())
+		text := fmt.Sprintf("%s: 1 %s = %s %s ", r.Date, r.From, r.Rate, r.To)
+		li.Set("textContent", text)
+		a := document.Call("createElement", "a")
+		a.Set("textContent", "Delete")
+		a.Call("setAttribute", "href", "")
+		a.Get("classList").Call("add", "writable")
+		a.Set("onclick", async(v.onClickToDeleteExchangeRate))
+		li.Call("appendChild", a)
+		ul.Call("appendChild", li)
+	}
+}
+
+func (v *HTMLView) PrintAPITokens(tokens []models.APIToken) {
+	document := js.Global.Get("document")
+	ul := document.Call("getElementById", "api_tokens")
+	empty(ul)
+	for _, t := range tokens {
+		li := document.Call("createElement", "li")
+		prop := toDatasetProp(datasetAttrID)
+		li.Get("dataset").Set(prop, t.Meta.ID.String())
+		li.Set("textContent", t.Name+" ")
+		a := document.Call("createElement", "a")
+		a.Set("textContent", "Revoke")
+		a.Call("setAttribute", "href", "")
+		a.Set("onclick", async(v.onClickToRevokeAPIToken))
+		li.Call("appendChild", a)
+		ul.Call("appendChild", li)
+	}
+}
+
+// printLedgers prints the ledgers which the user can switch to.
+func (v *HTMLView) printLedgers() {
+	ledgers, err := v.ledgers.Ledgers()
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	document := js.Global.Get("document")
+	sel := document.Call("getElementById", "select_ledger")
+	empty(sel)
+	option := document.Call("createElement", "option")
+	option.Set("value", "")
+	option.Set("textContent", "Personal")
+	sel.Call("appendChild", option)
+	for _, l := range ledgers {
+		option := document.Call("createElement", "option")
+		option.Set("value", l.ID.String())
+		text := fmt.Sprintf("%s (%s)", l.Name, l.Role)
+		option.Set("textContent", text)
+		sel.Call("appendChild", option)
+	}
+	sel.Set("value", v.ledgers.Ledger().String())
+}
+
+func (v *HTMLView) PrintLedger(ledger *models.Ledger, role models.Role) {
+	document := js.Global.Get("document")
+	div := document.Call("getElementById", "ledger_members")
+	if ledger == nil {
+		div.Get("style").Set("display", "none")
+		return
+	}
+	div.Get("style").Set("display", "block")
+	isOwner := role == models.RoleOwner
+	form := document.Call("getElementById", "form_member")
+	if isOwner {
+		form.Get("style").Set("display", "block")
+	} else {
+		form.Get("style").Set("display", "none")
+	}
+	// Viewers can't edit any values. The forms and the links to edit values
+	// have the class 'writable'.
+	readOnly := !role.CanWrite()
+	body := document.Get("body")
+	body.Get("classList").Call("toggle", "read_only", readOnly)
+	sel := document.Call("getElementById", "select_base_currency")
+	sel.Set("disabled", readOnly)
+	ul := document.Call("getElementById", "members")
+	empty(ul)
+	for _, m := range ledger.Members {
+		email := m.Email
+		li := document.Call("createElement", "li")
+		li.Set("textContent", fmt.Sprintf("%s (%s) ", email, m.Role))
+		if isOwner {
+			a := document.Call("createElement", "a")
+			a.Set("textContent", "Remove")
+			a.Call("setAttribute", "href", "")
+			a.Set("onclick", async(func(e js.Object) {
+				err := v.ledger.RemoveMember(email)
+				if err != nil {
+					v.onErrorFunc(err)
+				}
+			}))
+			li.Call("appendChild", a)
+		}
+		ul.Call("appendChild", li)
+	}
+}
+
+// editors returns the description of the members who created and last edited
+// the value.
+func editors(meta models.Meta) string {
+	if meta.CreatedBy == "" {
+		return ""
+	}
+	text := "Created by " + meta.CreatedBy
+	if meta.UpdatedBy != "" && meta.UpdatedBy != meta.CreatedBy {
+		text += ", last edited by " + meta.UpdatedBy
+	}
+	return text
+}
+
+// PrintSettings prints the settings. Amounts in the base currency are printed
+// after this.
+func (v *HTMLView) PrintSettings(settings models.Settings) {
+	v.baseCurrency = settings.Base()
+	selectDefaultCurrency(v.baseCurrency)
+}
+
+func (v *HTMLView) PrintAccountBalances(balances []items.AccountBalance) {
+	document := js.Global.Get("document")
+	ul := document.Call("getElementById", "accounts")
+	empty(ul)
+	for _, b := range balances {
+		li := document.Call("createElement", "li")
+		prop := toDatasetProp(datasetAttrID)
+		li.Get("dataset").Set(prop, b.ID.String())
+		text := fmt.Sprintf("%s: %s ", b.Name, v.formatAmount(b.Balance))
+		li.Set("textContent", text)
+		a := document.Call("createElement", "a")
+		a.Set("textContent", "Delete")
+		a.Call("setAttribute", "href", "")
+		a.Get("classList").Call("add", "writable")
+		a.Set("onclick", async(v.onClickToDeleteAccount))
+		li.Call("appendChild", a)
+		ul.Call("appendChild", li)
+	}
+}
+
+func (v *HTMLView) PrintRunningBalances(
+	balances map[uuid.UUID]money.Amount) {
+	document := js.Global.Get("document")
+	table := document.Call("getElementById", "table_items")
+	query := fmt.Sprintf("tr[data-%s]", datasetAttrID)
+	trs := table.Call("querySelectorAll", query)
+	for i := 0; i < trs.Length(); i++ {
+		tr := trs.Index(i)
+		id, err := getIDFromElement(tr)
+		if err != nil {
+			v.onErrorFunc(err)
+			return
+		}
+		text := ""
+		if b, ok := balances[id]; ok {
+			text = v.formatAmount(b)
+		}
+		printValueAt(tr, "Balance", text)
+	}
+}
+
+func (v *HTMLView) PrintBudgets(budgets []items.BudgetStatus) {
+	document := js.Global.Get("document")
+	table := document.Call("getElementById", "table_budgets")
+	tbody := table.Call("getElementsByTagName", "tbody").Index(0)
+	empty(tbody)
+	for _, b := range budgets {
+		tr := document.Call("createElement", "tr")
+		prop := toDatasetProp(datasetAttrID)
+		tr.Get("dataset").Set(prop, b.ID.String())
+		if b.IsOver() {
+			tr.Get("classList").Call("add", "over")
+		}
+
+		td := document.Call("createElement", "td")
+		td.Set("textContent", b.Name)
+		tr.Call("appendChild", td)
+
+		for _, value := range []money.Amount{b.Spent, b.Limit} {
+			td := document.Call("createElement", "td")
+			td.Set("textContent", v.formatAmount(value))
+			td.Get("classList").Call("add", "number")
+			tr.Call("appendChild", td)
+		}
+
+		td = document.Call("createElement", "td")
+		meter := document.Call("createElement", "meter")
+		meter.Set("max", float64(b.Limit))
+		meter.Set("high", float64(b.Limit))
+		meter.Set("value", float64(b.Spent))
+		td.Call("appendChild", meter)
+		tr.Call("appendChild", td)
+
+		td = document.Call("createElement", "td")
+		td.Get("classList").Call("add", "action", "writable")
+		a := document.Call("createElement", "a")
+		a.Set("textContent", "Delete")
+		a.Call("setAttribute", "href", "")
+		a.Set("onclick", async(v.onClickToDeleteBudget))
+		td.Call("appendChild", a)
+		tr.Call("appendChild", td)
+
+		tbody.Call("appendChild", tr)
+	}
+	display := "table"
+	if len(budgets) == 0 {
+		display = "none"
+	}
+	table.Get("style").Set("display", display)
+}
+
+func (v *HTMLView) PrintBudgetWarnings(budgets []items.BudgetStatus) {
+	document := js.Global.Get("document")
+	ul := document.Call("getElementById", "budget_warnings")
+	empty(ul)
+	for _, b := range budgets {
+		li := document.Call("createElement", "li")
+		text := fmt.Sprintf(
+			"Over budget: %s (%s / %s)",
+			b.Name,
+			v.formatAmount(b.Spent),
+			v.formatAmount(b.Limit))
+		li.Set("textContent", text)
+		ul.Call("appendChild", li)
+	}
+	display := "block"
+	if len(budgets) == 0 {
+		display = "none"
+	}
+	ul.Get("parentNode").Get("style").Set("display", display)
+}
+
+func (v *HTMLView) itemSummary(data models.ItemData) string {
+	if data.Meta.IsDeleted {
+		return "(Deleted)"
+	}
+	amount := v.itemAmount(&data)
+	return fmt.Sprintf("%s %s %s", data.Date, data.Subject, amount)
+}
+
+func (v *HTMLView) PrintConflicts(conflicts []items.ItemConflict) {
+	document := js.Global.Get("document")
+	ul := document.Call("getElementById", "conflicts")
+	for _, c := range conflicts {
+		li := document.Call("createElement", "li")
+		text := fmt.Sprintf(
+			"Edited on another device: %s (yours: %s, result: %s)",
+			v.itemSummary(c.Server),
+			v.itemSummary(c.Local),
+			v.itemSummary(c.Result))
+		li.Set("textContent", text)
+		ul.Call("appendChild", li)
+	}
+	ul.Get("parentNode").Get("style").Set("display", "block")
+}
+
+func (v *HTMLView) onClickDismissConflicts(e js.Object) {
+	document := js.Global.Get("document")
+	ul := document.Call("getElementById", "conflicts")
+	empty(ul)
+	ul.Get("parentNode").Get("style").Set("display", "none")
+}
+
+func (v *HTMLView) PrintItem(data models.ItemData) {
+	document := js.Global.Get("document")
+	id := data.Meta.ID
+	query := fmt.Sprintf(
+		"*[data-%s=\"%s\"]",
+		html.EscapeString(datasetAttrID),
+		html.EscapeString(id.String()))
+	elements := document.Call("querySelectorAll", query)
+	for i := 0; i < elements.Length(); i++ {
+		e := elements.Index(i)
+		printValueAt(e, "Date", data.Date.String())
+		printValueAt(e, "Subject", data.Subject)
+		code := data.CurrencyCode()
+		amount := data.Amount.FormatLocale(code, v.locale)
+		printValueAt(e, "Amount", amount)
+		printValueAt(e, "Currency", code.String())
+		printValueAt(e, "Direction", data.Direction.String())
+		printValueAt(e, "Tags", strings.Join(data.Tags, " "))
+		printValueAt(e, "Note", data.Note)
+		category := v.categoryPaths[data.CategoryID]
+		if len(data.Splits) != 0 {
+			category = "(Split)"
+		}
+		printValueAndTextAt(
+			e,
+			"CategoryID",
+			data.CategoryID.String(),
+			category)
+		printValueAndTextAt(
+			e,
+			"AccountID",
+			data.AccountID.String(),
+			v.accountNames[data.AccountID])
+		printValueAndTextAt(
+			e,
+			"ToAccountID",
+			data.ToAccountID.String(),
+			v.accountNames[data.ToAccountID])
+		printValueAndTextAt(
+			e,
+			"RecurringID",
+			data.RecurringID.String(),
+			v.recurringNames[data.RecurringID])
+		// Deleting an occurrence of a recurring item skips it.
+		if a := e.Call("querySelector", "a.delete"); !a.IsNull() {
+			text := "Delete"
+			if data.RecurringID != "" {
+				text = "Skip"
+			}
+			a.Set("textContent", text)
+		}
+		switch e.Get("tagName").Str() {
+		case "FORM":
+			v.printSplitLines(e, &data)
+		case "TR":
+			v.printSplitRows(e, &data)
+			e.Set("title", editors(data.Meta))
+		}
+	}
+}
+
+// onChangeAttachment stores the file selected at the input element and
+// attaches it to the item.
+func (v *HTMLView) onChangeAttachment(items Items, input js.Object) {
+	// The input is cleared so that the same file can be selected again.
+	defer input.Set("value", "")
+	if input.Get("files").Length() == 0 {
+		return
+	}
+	id, err := getIDFromElement(input)
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	file := input.Get("files").Index(0)
+	if models.MaxAttachmentSize < file.Get("size").Int64() {
+		v.onErrorFunc(errors.New("view: the file is too large"))
+		return
+	}
+	data, err := readFile(input)
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	contentType := file.Get("type").Str()
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if err := v.blobs.SaveBlob(hash, file); err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	name := file.Get("name").Str()
+	size := int64(len(data))
+	if err := items.Attach(id, name, contentType, size, hash); err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+}
+
+// PrintAttachments prints the links to the attachments of the item. The links
+// in the form to edit the item open the contents stored on this client if
+// any, so that they can be opened offline.
+func (v *HTMLView) PrintAttachments(
+	itemID uuid.UUID,
+	attachments []models.Attachment) {
+	document := js.Global.Get("document")
+	query := fmt.Sprintf(
+		"*[data-%s=\"%s\"] .attachments",
+		html.EscapeString(datasetAttrID),
+		html.EscapeString(itemID.String()))
+	elements := document.Call("querySelectorAll", query)
+	for i := 0; i < elements.Length(); i++ {
+		e := elements.Index(i)
+		empty(e)
+		inForm := e.Get("tagName").Str() == "SPAN"
+		for _, attachment := range attachments {
+			link := v.attachmentLink(attachment, inForm)
+			e.Call("appendChild", link)
+			space := document.Call("createTextNode", " ")
+			e.Call("appendChild", space)
+		}
+	}
+}
+
+// attachmentLink returns an element which has the link to the attachment, and
+// the link to remove it if removable is true.
+func (v *HTMLView) attachmentLink(
+	attachment models.Attachment,
+	removable bool) js.Object {
+	document := js.Global.Get("document")
+	span := document.Call("createElement", "span")
+	id := attachment.Meta.ID.String()
+	span.Get("dataset").Set(toDatasetProp(datasetAttrID), id)
+	a := document.Call("createElement", "a")
+	name := attachment.Name
+	if name == "" {
+		name = "(Attachment)"
+	}
+	a.Set("textContent", name)
+	ledger := idb.LedgerQuery(v.ledgers.Ledger())
+	href := "attachments/" + attachment.Hash + ledger
+	a.Call("setAttribute", "href", href)
+	a.Call("setAttribute", "target", "_blank")
+	span.Call("appendChild", a)
+	if !removable {
+		return span
+	}
+	go v.linkToBlob(a, attachment.Hash)
+	span.Call("appendChild", document.Call("createTextNode", " "))
+	a = document.Call("createElement", "a")
+	a.Set("textContent", "Remove")
+	a.Call("setAttribute", "href", "")
+	a.Get("classList").Call("add", "writable")
+	a.Set("onclick", async(v.onClickToRemoveAttachment))
+	span.Call("appendChild", a)
+	return span
+}
+
+// linkToBlob makes the link open the content stored on this client.
+func (v *HTMLView) linkToBlob(a js.Object, hash string) {
+	blob, err := v.blobs.LoadBlob(hash)
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	if blob == nil {
+		return
+	}
+	url := js.Global.Get("URL").Call("createObjectURL", blob)
+	a.Call("setAttribute", "href", url)
+}
+
+func (v *HTMLView) onClickToRemoveAttachment(e js.Object) {
+	id, err := getIDFromElement(e.Get("target"))
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	if err := v.attachments.Destroy(id); err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+}
+
+func (v *HTMLView) addIDToItemTable(id uuid.UUID) {
+	t := reflect.TypeOf((*models.ItemData)(nil)).Elem()
+
+	document := js.Global.Get("document")
+	table := document.Call("getElementById", "table_items")
+	query := fmt.Sprintf("tr[data-%s=\"%s\"]", datasetAttrID, id.String())
 	if 1 <= table.Call("querySelectorAll", query).Length() {
 		return
 	}
@@ -395,6 +2306,10 @@ func (v *HTMLView) addIDToItemTable(id uuid.UUID) {
 		if f.Type == reflect.TypeOf((*models.Meta)(nil)).Elem() {
 			continue
 		}
+		// Splits are printed as rows under the item.
+		if f.Type == reflect.TypeOf(([]models.Split)(nil)) {
+			continue
+		}
 		td := document.Call("createElement", "td")
 		td.Get("dataset").Set(toDatasetProp(datasetAttrKey), f.Name)
 		if isNumberType(f.Type) {
@@ -402,20 +2317,47 @@ func (v *HTMLView) addIDToItemTable(id uuid.UUID) {
 		}
 		tr.Call("appendChild", td)
 	}
+	td := document.Call("createElement", "td")
+	td.Get("classList").Call("add", "attachments")
+	tr.Call("appendChild", td)
+
+	td = document.Call("createElement", "td")
+	td.Get("dataset").Set(toDatasetProp(datasetAttrKey), "Balance")
+	td.Get("classList").Call("add", "number")
+	tr.Call("appendChild", td)
 
+	td = document.Call("createElement", "td")
+	td.Get("classList").Call("add", "action", "writable")
 	a := document.Call("createElement", "a")
-	a.Set("textContent", "Delete")
+	a.Set("textContent", "Edit")
 	a.Call("setAttribute", "href", "")
-	td := document.Call("createElement", "td")
+	a.Set("onclick", async(v.onClickToEdit))
 	td.Call("appendChild", a)
-	td.Get("classList").Call("add", "action")
+	td.Call("appendChild", document.Call("createTextNode", " "))
+	a = document.Call("createElement", "a")
+	a.Set("textContent", "Delete")
+	a.Call("setAttribute", "href", "")
+	a.Get("classList").Call("add", "delete")
 	a.Set("onclick", async(v.onClickToDelete))
+	td.Call("appendChild", a)
 	tr.Call("appendChild", td)
 
 	tbody := table.Call("getElementsByTagName", "tbody").Index(0)
 	tbody.Call("appendChild", tr)
 }
 
+func (v *HTMLView) onClickToEdit(e js.Object) {
+	id, err := getIDFromElement(e.Get("target"))
+	if err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+	if err := v.items.Edit(id); err != nil {
+		v.onErrorFunc(err)
+		return
+	}
+}
+
 func (v *HTMLView) onClickToDelete(e js.Object) {
 	id, err := getIDFromElement(e.Get("target"))
 	if err != nil {
@@ -432,6 +2374,65 @@ func (v *HTMLView) onClickToDelete(e js.Object) {
 	}()
 }
 
+func (v *HTMLView) PrintImportPreview(rows []items.ImportRow) {
+	document := js.Global.Get("document")
+	table := document.Call("getElementById", "table_import")
+	tbody := table.Call("getElementsByTagName", "tbody").Index(0)
+	empty(tbody)
+	for _, row := range rows {
+		tr := document.Call("createElement", "tr")
+		texts := []string{strconv.Itoa(row.Line)}
+		switch {
+		case row.Err != nil:
+			tr.Get("classList").Call("add", "error")
+			texts = append(texts, "", "", "", "", row.Err.Error())
+		default:
+			item := row.Item
+			note := ""
+			if row.Duplicate {
+				tr.Get("classList").Call("add", "duplicate")
+				note = "Likely duplicate"
+			}
+			texts = append(
+				texts,
+				item.Date.String(),
+				item.Subject,
+				v.itemAmount(&item),
+				item.Direction.String(),
+				note)
+		}
+		for n, text := range texts {
+			td := document.Call("createElement", "td")
+			td.Set("textContent", text)
+			if n == 0 || n == 3 {
+				td.Get("classList").Call("add", "number")
+			}
+			tr.Call("appendChild", td)
+		}
+		tbody.Call("appendChild", tr)
+	}
+	display := "block"
+	if rows == nil {
+		display = "none"
+	}
+	notice := document.Call("getElementById", "notice_import")
+	notice.Get("style").Set("display", display)
+}
+
+// DecodeText decodes b by the browser's TextDecoder.
+func (v *HTMLView) DecodeText(b []byte, encoding string) (
+	text string,
+	err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("view: decoding %s failed: %v", encoding, r)
+		}
+	}()
+	decoder := js.Global.Get("TextDecoder").New(encoding)
+	array := js.Global.Get("Uint8Array").New(b)
+	return decoder.Call("decode", array).Str(), nil
+}
+
 func (v *HTMLView) Download(b []byte, filename string) {
 	document := js.Global.Get("document")
 	a := document.Call("createElement", "a")